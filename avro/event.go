@@ -28,6 +28,10 @@ func convertEvent(evt event.Envelope) (to *avroEvent, err error) {
 		FIPAddr:        evt.IPAddr(),
 		FDests:         evt.Dests(),
 		FTTL:           evt.TTL(),
+		FCorrelationID: evt.CorrelationID(),
+		FCausationID:   evt.CausationID(),
+		FTraceID:       evt.TraceID(),
+		FMetadata:      evt.Metadata(),
 		fVersion:       evt.Version(),
 		fGlobalVersion: evt.GlobalVersion(),
 	}
@@ -50,11 +54,16 @@ type avroEvent struct {
 	FID               string `avro:"ID"`
 	FType             string `avro:"Type"`
 	fEvent            any
-	FAt               int64         `avro:"At"`
-	FUser             string        `avro:"User"`
-	FIPAddr           string        `avro:"IPAddr"`
-	FDests            []string      `avro:"Dests"`
-	FTTL              time.Duration `avro:"TTL"`
+	FAt               int64             `avro:"At"`
+	FUser             string            `avro:"User"`
+	FIPAddr           string            `avro:"IPAddr"`
+	FDests            []string          `avro:"Dests"`
+	FTTL              time.Duration     `avro:"TTL"`
+	FRev              int               `avro:"Rev"`
+	FCorrelationID    string            `avro:"CorrID"`
+	FCausationID      string            `avro:"CausID"`
+	FTraceID          string            `avro:"TraceID"`
+	FMetadata         map[string]string `avro:"Meta"`
 
 	// make sure to put data as last field for future partial decoding (using a sub-schema)
 	FRawEvent any `avro:"Data" ev:",inject=union"`
@@ -151,6 +160,28 @@ func (e *avroEvent) TTL() time.Duration {
 	return e.FTTL
 }
 
+func (e *avroEvent) CorrelationID() string {
+	return e.FCorrelationID
+}
+
+func (e *avroEvent) CausationID() string {
+	return e.FCausationID
+}
+
+func (e *avroEvent) TraceID() string {
+	return e.FTraceID
+}
+
+func (e *avroEvent) Metadata() map[string]string {
+	return e.FMetadata
+}
+
+func (e *avroEvent) SetVersion(v event.Version) event.Envelope {
+	e.fVersion = v
+	e.FRawVersion = e.fVersion.String()
+	return e
+}
+
 func (e *avroEvent) SetGlobalVersion(v event.Version) event.Envelope {
 	e.fGlobalVersion = v
 	e.FRawGlobalVersion = e.fGlobalVersion.String()
@@ -46,6 +46,7 @@ func TestEvent(t *testing.T) {
 			event.WithNameSpace("service1"),
 			event.WithVersionIncr(ver, 1, event.VersionSeqDiffPart),
 			event.WithGlobalVersionIncr(gVer, 1, event.VersionSeqDiffPart),
+			event.WithMetadata(map[string]string{"source": "service1"}),
 		)[0]
 
 		avroEvt, err := convertEvent(evt)
@@ -2,12 +2,15 @@ package avro
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/ln80/avro/v2"
 	"github.com/ln80/event-store/avro/registry"
 	"github.com/ln80/event-store/event"
+	event_errors "github.com/ln80/event-store/event/errors"
 	"github.com/ln80/event-store/logger"
 )
 
@@ -27,6 +30,8 @@ type EventSerializerConfig struct {
 	// SkipCurrentSchema disables the generation of the current schema from registered event.
 	SkipCurrentSchema    bool
 	PersistCurrentSchema bool
+	// Upcasters are applied on unmarshal to migrate legacy events to their current shape.
+	Upcasters *event.Upcasters
 }
 
 func NewEventSerializer(ctx context.Context, r *registry.Registry, opts ...func(*EventSerializerConfig)) *EventSerializer {
@@ -74,6 +79,7 @@ func NewEventSerializer(ctx context.Context, r *registry.Registry, opts ...func(
 }
 
 var _ event.Serializer = &EventSerializer{}
+var _ event.EventsUnmarshaler = &EventSerializer{}
 
 // MarshalEvent implements event.Serializer.
 func (s *EventSerializer) MarshalEvent(ctx context.Context, evt event.Envelope) (b []byte, err error) {
@@ -97,7 +103,7 @@ func (s *EventSerializer) MarshalEvent(ctx context.Context, evt event.Envelope)
 		avroEvt *avroEvent
 	)
 
-	avroEvt, err = convertEvent(evt)
+	avroEvt, err = s.convert(evt)
 	if err != nil {
 		return
 	}
@@ -145,13 +151,10 @@ func (s *EventSerializer) MarshalEventBatch(ctx context.Context, events []event.
 	for i, evt := range events {
 		var (
 			avroEvt *avroEvent
-			ok      bool
 		)
-		if avroEvt, ok = evt.(*avroEvent); !ok {
-			avroEvt, err = convertEvent(evt)
-			if err != nil {
-				return
-			}
+		avroEvt, err = s.convert(evt)
+		if err != nil {
+			return
 		}
 		avroEvents[i] = *avroEvt
 	}
@@ -176,6 +179,23 @@ func (s *EventSerializer) MarshalEventBatch(ctx context.Context, events []event.
 
 // UnmarshalEvent implements event.Serializer.
 func (s *EventSerializer) UnmarshalEvent(ctx context.Context, b []byte) (event.Envelope, error) {
+	envs, err := s.UnmarshalEvents(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	// a single envelope can't present the derived events
+	if l := len(envs); l != 1 {
+		streamID := ""
+		if l > 0 {
+			streamID = envs[0].StreamID()
+		}
+		return nil, event_errors.Err(event.ErrUpcastEventFailed, streamID, "splitting or dropping an event requires UnmarshalEvents or a batch unmarshal")
+	}
+	return envs[0], nil
+}
+
+// UnmarshalEvents implements event.EventsUnmarshaler.
+func (s *EventSerializer) UnmarshalEvents(ctx context.Context, b []byte) ([]event.Envelope, error) {
 	avroEvt := avroEvent{}
 
 	id, b, err := s.registry.ExtractSchemaID(b)
@@ -191,9 +211,28 @@ func (s *EventSerializer) UnmarshalEvent(ctx context.Context, b []byte) (event.E
 	}
 
 	avroEvt.SetAVROSchemaID(id)
-	avroEvt.checkType(s.cfg.Namespace)
+	if !s.cfg.Upcasters.Match(avroEvt.FType, avroEvt.FRev) {
+		avroEvt.checkType(s.cfg.Namespace)
+		return []event.Envelope{&avroEvt}, nil
+	}
 
-	return &avroEvt, nil
+	// upcasters expect the record as written, before resolving it to the current event struct
+	var raw any
+	if err := rawAPI.Unmarshal(schema, b, &raw); err != nil {
+		return nil, event_errors.Err(event.ErrUpcastEventFailed, avroEvt.FStreamID, err)
+	}
+
+	avroEvents, err := s.upcast(ctx, &avroEvt, rawData(schema, raw))
+	if err != nil {
+		return nil, err
+	}
+
+	envs := make([]event.Envelope, len(avroEvents))
+	for i, avroEvt := range avroEvents {
+		avroEvt.checkType(s.cfg.Namespace)
+		envs[i] = avroEvt
+	}
+	return envs, nil
 }
 
 // UnmarshalEventBatch implements event.Serializer.
@@ -212,13 +251,153 @@ func (s *EventSerializer) UnmarshalEventBatch(ctx context.Context, b []byte) ([]
 		return nil, err
 	}
 
-	envs := make([]event.Envelope, len(avroEvents))
+	// the batch is decoded as written only if upcasters match some of its events
+	var raws []any
+
+	upcasted := false
+	envs := make([]event.Envelope, 0, len(avroEvents))
 	for i, avroEvt := range avroEvents {
 		avroEvt := avroEvt
 		avroEvt.SetAVROSchemaID(id)
-		avroEvt.checkType(s.cfg.Namespace)
-		envs[i] = &avroEvt
+		if !s.cfg.Upcasters.Match(avroEvt.FType, avroEvt.FRev) {
+			avroEvt.checkType(s.cfg.Namespace)
+			envs = append(envs, &avroEvt)
+			continue
+		}
+
+		if raws == nil {
+			if err := rawAPI.Unmarshal(batchSchema, b, &raws); err != nil {
+				return nil, event_errors.Err(event.ErrUpcastEventFailed, avroEvt.FStreamID, err)
+			}
+		}
+		if i >= len(raws) {
+			return nil, event_errors.Err(event.ErrUpcastEventFailed, avroEvt.FStreamID, "invalid raw batch")
+		}
 
+		evts, err := s.upcast(ctx, &avroEvt, rawData(batchSchema, raws[i]))
+		if err != nil {
+			return nil, err
+		}
+		upcasted = upcasted || len(evts) != 1
+		for _, evt := range evts {
+			evt.checkType(s.cfg.Namespace)
+			envs = append(envs, evt)
+		}
 	}
+	if upcasted {
+		event.RenumberEvents(envs)
+	}
+
 	return envs, nil
 }
+
+// convert returns the AVRO version of the given envelope along with the current revision of its event.
+func (s *EventSerializer) convert(evt event.Envelope) (*avroEvent, error) {
+	if avroEvt, ok := evt.(*avroEvent); ok {
+		return avroEvt, nil
+	}
+
+	avroEvt, err := convertEvent(evt)
+	if err != nil {
+		return nil, err
+	}
+	avroEvt.FRev = event.NewRegister(s.cfg.Namespace).Revision(evt.Type())
+
+	return avroEvt, nil
+}
+
+// upcast applies the matching upcasters to the given event, using the data as written.
+// It may return several events in the case of a split, the derived events share the original event version.
+func (s *EventSerializer) upcast(ctx context.Context, avroEvt *avroEvent, data map[string]any) ([]*avroEvent, error) {
+	raws, err := s.cfg.Upcasters.Upcast(ctx, event.RawEvent{
+		Type:     avroEvt.FType,
+		Revision: avroEvt.FRev,
+		Data:     data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reg := event.NewRegister(s.cfg.Namespace)
+
+	result := make([]*avroEvent, len(raws))
+	for i, raw := range raws {
+		e := *avroEvt
+		e.FType = raw.Type
+		e.FRev = raw.Revision
+		e.FRawEvent, err = fromRawData(reg, raw)
+		if err != nil {
+			return nil, event_errors.Err(event.ErrUpcastEventFailed, avroEvt.FStreamID, err)
+		}
+		e.fEvent = nil
+		// the upcasted event no longer fits the writer schema
+		e.avroSchemaID = ""
+		// derived events must have unique and deterministic IDs
+		if i > 0 {
+			e.FID = avroEvt.FID + "-" + strconv.Itoa(i)
+		}
+		result[i] = &e
+	}
+
+	return result, nil
+}
+
+// rawAPI decodes records as written, without resolving event data to the registered types.
+var rawAPI = avro.Config{}.Freeze()
+
+// rawData returns the event data of the given envelope, decoded using rawAPI and the envelope (or batch) schema.
+// The data union is decoded as a map keyed by the name of the written type, it's unwrapped accordingly.
+func rawData(schema avro.Schema, envelope any) map[string]any {
+	env, _ := envelope.(map[string]any)
+	data, _ := env["Data"].(map[string]any)
+	if data == nil {
+		return map[string]any{}
+	}
+
+	if arr, ok := schema.(*avro.ArraySchema); ok {
+		schema = arr.Items()
+	}
+	rec, ok := schema.(*avro.RecordSchema)
+	if !ok {
+		return data
+	}
+	for _, f := range rec.Fields() {
+		if f.Name() != "Data" {
+			continue
+		}
+		union, ok := f.Type().(*avro.UnionSchema)
+		if !ok {
+			break
+		}
+		for _, t := range union.Types() {
+			named, ok := t.(avro.NamedSchema)
+			if !ok {
+				continue
+			}
+			if v, ok := data[named.FullName()].(map[string]any); ok && len(data) == 1 {
+				return v
+			}
+		}
+	}
+
+	return data
+}
+
+// fromRawData converts the upcasted data to the registered event type.
+// It keeps the generic form if the event type is not registered.
+func fromRawData(reg event.Register, raw event.RawEvent) (any, error) {
+	ptr, err := reg.Get(raw.Type)
+	if err != nil {
+		return raw.Data, nil
+	}
+
+	b, err := json.Marshal(raw.Data)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, ptr); err != nil {
+		return nil, err
+	}
+
+	return ptr, nil
+}
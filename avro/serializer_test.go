@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"os"
+	"strconv"
 	"testing"
 
 	"github.com/ln80/event-store/event"
@@ -132,3 +133,77 @@ func TestSerializer_WithError(t *testing.T) {
 		}
 	})
 }
+
+func TestSerializer_Upcast(t *testing.T) {
+	event.NewRegister("upcast").Set(&eventtest.Event1{})
+
+	ctx := context.WithValue(context.Background(), event.ContextNamespaceKey, "upcast")
+
+	typ := event.TypeOfWithNamespace("upcast", &eventtest.Event1{})
+
+	// split the event into two ones, based on the record as written
+	upcasters := event.NewUpcasters().
+		Set(typ, 0, func(ctx context.Context, evt event.RawEvent) ([]event.RawEvent, error) {
+			val, _ := evt.Data["Val"].(string)
+			return []event.RawEvent{
+				{Type: typ, Revision: 1, Data: map[string]any{"Val": val + "-1"}},
+				{Type: typ, Revision: 1, Data: map[string]any{"Val": val + "-2"}},
+			}, nil
+		})
+
+	dir := t.TempDir()
+	registry := NewFSRegistry(os.DirFS(dir), func(fc *FSRegistryConfig) { fc.PersistDir = dir })
+
+	ser := NewEventSerializer(ctx, registry, func(esc *EventSerializerConfig) {
+		esc.Namespace = "upcast"
+		esc.PersistCurrentSchema = true
+		esc.Upcasters = upcasters
+	})
+
+	envs := event.Wrap(ctx, event.NewStreamID("tenantID"),
+		[]any{&eventtest.Event1{Val: "foo"}},
+		event.WithVersionIncr(event.VersionMin, 1, event.VersionSeqDiffFracPart),
+	)
+
+	b, err := ser.MarshalEvent(ctx, envs[0])
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// single event unmarshal does not support split
+	_, err = ser.UnmarshalEvent(ctx, b)
+	if want, got := event.ErrUpcastEventFailed, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	renvs, err := event.UnmarshalEvents(ctx, ser, b)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 2, len(renvs); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	for i, renv := range renvs {
+		if want, got := "foo-"+strconv.Itoa(i+1), renv.Event().(*eventtest.Event1).Val; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := envs[0].Version(), renv.Version(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	b, err = ser.MarshalEventBatch(ctx, envs)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	renvs, err = ser.UnmarshalEventBatch(ctx, b)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 2, len(renvs); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if err := event.Stream(renvs).Validate(); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+}
@@ -2,36 +2,75 @@ package control
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	es "github.com/ln80/event-store"
 	"github.com/ln80/event-store/event"
 	"github.com/ln80/event-store/event/errors"
 	"github.com/ln80/event-store/event/sourcing"
+	"github.com/ln80/event-store/logger"
 )
 
+// Decorator enforces feature toggles on top of an event store:
+//
+// - APPEND: appends to disabled global streams fail;
+//
+// - INDEX: events appended to disabled global streams skip secondary indexing. If the store implements
+// event.StreamIndexer, the global stream is reindexed once the toggle flips back on;
+//
+// - FORWARD: the events are appended as is, along with their destinations. The forwarding is deferred by Publisher,
+// which queues the publication of events until the toggle flips back on;
+//
+// - LOAD, REPLAY and QUERY: reads from disabled global streams fail, using respectively Load and LoadStream,
+// Replay, and Query methods.
+//
+// Global streams in read-only maintenance mode reject appends. Disabled features result in FeatureDisabledError.
 type Decorator struct {
 	feature FeatureToggler
 	es.EventStore
+
+	mu        sync.Mutex
+	reindexes map[string]struct{} // global streams waiting for reindex
+	ctx       context.Context     // scheduled reindexes lifecycle
+	stop      context.CancelFunc
+	wg        sync.WaitGroup
 }
 
 func NewDecorator(store es.EventStore, feature FeatureToggler) *Decorator {
+	ctx, stop := context.WithCancel(context.Background())
 	return &Decorator{
 		feature:    feature,
 		EventStore: store,
+		reindexes:  make(map[string]struct{}),
+		ctx:        ctx,
+		stop:       stop,
 	}
 }
 
+// Close cancels the scheduled reindexes, and waits for the running ones to return.
+// Appends to global streams where INDEX is disabled no longer schedule a reindex once the decorator is closed.
+func (d *Decorator) Close() error {
+	d.mu.Lock()
+	d.stop()
+	d.mu.Unlock()
+
+	d.wg.Wait()
+
+	return nil
+}
+
 func (d *Decorator) Append(ctx context.Context, id event.StreamID, events []event.Envelope, optFns ...func(*event.AppendConfig)) error {
 	toggles, err := d.feature.Get(ctx, id.GlobalID())
 	if err != nil {
 		return err
 	}
 
-	if err := toggles.Enabled(APPEND); err != nil {
+	if err := enabled(toggles, APPEND, id); err != nil {
 		return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
 	}
 
-	return d.EventStore.Append(ctx, id, events, optFns...)
+	return d.EventStore.Append(ctx, id, events, d.index(id, toggles, optFns)...)
 }
 
 func (d *Decorator) AppendToStream(ctx context.Context, chunk sourcing.Stream, optFns ...func(opt *event.AppendConfig)) error {
@@ -40,9 +79,124 @@ func (d *Decorator) AppendToStream(ctx context.Context, chunk sourcing.Stream, o
 		return err
 	}
 
-	if err := toggles.Enabled(APPEND); err != nil {
+	if err := enabled(toggles, APPEND, chunk.ID()); err != nil {
 		return errors.Err(event.ErrAppendEventsFailed, chunk.ID().String(), err)
 	}
 
-	return d.EventStore.AppendToStream(ctx, chunk, optFns...)
+	return d.EventStore.AppendToStream(ctx, chunk, d.index(chunk.ID(), toggles, optFns)...)
+}
+
+func (d *Decorator) Load(ctx context.Context, id event.StreamID, trange ...time.Time) ([]event.Envelope, error) {
+	if err := d.enabled(ctx, LOAD, id); err != nil {
+		return nil, errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+
+	return d.EventStore.Load(ctx, id, trange...)
+}
+
+func (d *Decorator) LoadStream(ctx context.Context, id event.StreamID, vrange ...event.Version) (*sourcing.Stream, error) {
+	if err := d.enabled(ctx, LOAD, id); err != nil {
+		return nil, errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+
+	return d.EventStore.LoadStream(ctx, id, vrange...)
+}
+
+func (d *Decorator) Replay(ctx context.Context, id event.StreamID, q event.StreamReplayQuery, h event.StreamReplayHandler) error {
+	if err := d.enabled(ctx, REPLAY, id); err != nil {
+		return err
+	}
+
+	return d.EventStore.Replay(ctx, id, q, h)
+}
+
+func (d *Decorator) Query(ctx context.Context, id event.StreamID, q event.StreamQuery) (*event.StreamQueryResult, error) {
+	if err := d.enabled(ctx, QUERY, id); err != nil {
+		return nil, err
+	}
+
+	return d.EventStore.Query(ctx, id, q)
+}
+
+// enabled returns an error if the given feature is disabled for the global stream.
+func (d *Decorator) enabled(ctx context.Context, f Feature, id event.StreamID) error {
+	toggles, err := d.feature.Get(ctx, id.GlobalID())
+	if err != nil {
+		return err
+	}
+	return enabled(toggles, f, id)
+}
+
+// enabled returns a FeatureDisabledError which refers to the given stream if the feature is disabled.
+func enabled(toggles Toggles, f Feature, id event.StreamID) error {
+	err := toggles.Enabled(f)
+	if fe, ok := err.(FeatureDisabledError); ok {
+		fe.StreamID = id.String()
+		return fe
+	}
+	return err
+}
+
+// index returns the append options, extended with event.WithoutIndex if INDEX is disabled.
+// In such a case, it schedules the reindex of the global stream once the toggle flips back on.
+func (d *Decorator) index(id event.StreamID, toggles Toggles, optFns []func(*event.AppendConfig)) []func(*event.AppendConfig) {
+	if toggles.Enabled(INDEX) == nil {
+		return optFns
+	}
+
+	if indexer, ok := d.EventStore.(event.StreamIndexer); ok {
+		d.scheduleReindex(indexer, id.GlobalID())
+	}
+
+	return append(append([]func(*event.AppendConfig){}, optFns...), event.WithoutIndex())
+}
+
+// scheduleReindex subscribes to the global stream toggles, and reindexes the stream once INDEX is enabled.
+// The reindex is scheduled once per global stream, and it's canceled if the decorator is closed in the meantime.
+func (d *Decorator) scheduleReindex(indexer event.StreamIndexer, globalID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.ctx.Err() != nil {
+		return
+	}
+	if _, ok := d.reindexes[globalID]; ok {
+		return
+	}
+	d.reindexes[globalID] = struct{}{}
+
+	ch, cancel := d.feature.Subscribe(globalID)
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer cancel()
+
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case toggles, ok := <-ch:
+				if !ok {
+					// the subscription is canceled by the toggler, the next append schedules the reindex again
+					d.mu.Lock()
+					delete(d.reindexes, globalID)
+					d.mu.Unlock()
+					return
+				}
+				if toggles.Enabled(INDEX) != nil {
+					continue
+				}
+
+				// events appended in the meantime are reindexed as well
+				d.mu.Lock()
+				delete(d.reindexes, globalID)
+				d.mu.Unlock()
+
+				if err := indexer.Reindex(d.ctx, event.NewStreamID(globalID)); err != nil {
+					logger.FromContext(d.ctx).WithName("control").Error(err, "Reindex global stream failed", "stream", globalID)
+				}
+				return
+			}
+		}
+	}()
 }
@@ -10,6 +10,7 @@ import (
 
 	es "github.com/ln80/event-store"
 	"github.com/ln80/event-store/event"
+	event_errors "github.com/ln80/event-store/event/errors"
 	"github.com/ln80/event-store/eventtest"
 	"github.com/ln80/event-store/memory"
 )
@@ -67,3 +68,221 @@ func TestDecorator(t *testing.T) {
 		t.Fatal("expect err be nil, got", err)
 	}
 }
+
+// newTestToggler returns a feature toggler whose default toggles are read from the given pointer.
+func newTestToggler(t *testing.T, toggles *atomic.Pointer[Toggles]) *FeatureToggle {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	loader := &MockLoader{LoadFunc: func(ctx context.Context) ([]byte, error) {
+		return json.Marshal(Configuration{Default: toggles.Load()})
+	}}
+	f, err := NewFeatureToggler(ctx, loader, func(ftc *FeatureToggleConfig) {
+		ftc.CacheMaxAge = time.Millisecond
+	})
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	return f
+}
+
+// eventually fails the test if the given condition is not met within a second.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("expect condition be met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDecorator_Index(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	var toggles atomic.Pointer[Toggles]
+	toggles.Store(&Toggles{Append: true, Forward: true})
+
+	store := NewDecorator(memory.NewEventStore(), newTestToggler(t, &toggles))
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, eventtest.GenEvents(4))
+	if err := store.Append(ctx, streamID, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	query := func(q event.StreamQuery) int {
+		t.Helper()
+		result, err := store.Query(ctx, event.NewStreamID(streamID.GlobalID()), q)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		return len(result.Events)
+	}
+
+	// events skip secondary indexing, while they remain queryable without filters
+	if want, got := 0, query(event.StreamQuery{Types: []string{envs[0].Type()}}); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := len(envs), query(event.StreamQuery{}); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// the global stream is reindexed once the toggle flips back on
+	toggles.Store(&Toggles{Append: true, Forward: true, Index: true})
+	eventually(t, func() bool {
+		return query(event.StreamQuery{Types: []string{envs[0].Type()}}) > 0
+	})
+}
+
+func TestDecorator_Forward(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	var toggles atomic.Pointer[Toggles]
+	toggles.Store(&Toggles{Append: true, Index: true})
+
+	feature := newTestToggler(t, &toggles)
+	store := NewDecorator(memory.NewEventStore(), feature)
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, eventtest.GenEvents(4))
+	if err := store.Append(ctx, streamID, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// publication destinations are kept as is
+	renvs, err := store.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 1, len(renvs[0].Dests()); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// forwarding is deferred until the toggle flips back on
+	mock := &mockPublisher{}
+	pub := NewPublisher(mock, feature)
+	t.Cleanup(func() { _ = pub.Close() })
+
+	if err := pub.Publish(ctx, renvs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 0, len(mock.Published()); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	toggles.Store(&Toggles{Append: true, Index: true, Forward: true})
+	eventually(t, func() bool {
+		return len(mock.Published()) == len(renvs)
+	})
+	if want, got := renvs[0].Dests(), mock.Published()[0].Dests(); len(want) != len(got) || want[0] != got[0] {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestDecorator_Close(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	var toggles atomic.Pointer[Toggles]
+	toggles.Store(&Toggles{Append: true, Forward: true})
+
+	store := NewDecorator(memory.NewEventStore(), newTestToggler(t, &toggles))
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, eventtest.GenEvents(4))
+	if err := store.Append(ctx, streamID, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// the scheduled reindex is canceled
+	if err := store.Close(); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	toggles.Store(&Toggles{Append: true, Forward: true, Index: true})
+	time.Sleep(20 * time.Millisecond)
+
+	result, err := store.Query(ctx, event.NewStreamID(streamID.GlobalID()), event.StreamQuery{Types: []string{envs[0].Type()}})
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 0, len(result.Events); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestDecorator_Read(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	var toggles atomic.Pointer[Toggles]
+	toggles.Store(&Toggles{Append: true, Index: true, Forward: true, DisableLoad: true, DisableReplay: true, DisableQuery: true})
+
+	f := newTestToggler(t, &toggles)
+	store := NewDecorator(memory.NewEventStore(), f)
+
+	streamID := event.NewStreamID(event.UID().String())
+	if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(4))); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	assertDisabled := func(err error, feature Feature, readOnly bool) {
+		t.Helper()
+		ok, fe := event_errors.ErrAs[FeatureDisabledError](err)
+		if !ok || !errors.Is(err, ErrFeatureDisabled) {
+			t.Fatalf("expect %v error to occur, got: %v", ErrFeatureDisabled, err)
+		}
+		if want, got := (FeatureDisabledError{Feature: feature, StreamID: streamID.String(), ReadOnly: readOnly}), fe; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	_, err := store.Load(ctx, streamID)
+	if !errors.Is(err, event.ErrLoadEventFailed) {
+		t.Fatalf("expect %v error to occur, got: %v", event.ErrLoadEventFailed, err)
+	}
+	assertDisabled(err, LOAD, false)
+
+	_, err = store.LoadStream(ctx, streamID)
+	assertDisabled(err, LOAD, false)
+
+	err = store.Replay(ctx, streamID, event.StreamReplayQuery{}, func(ctx context.Context, data event.StreamData) error { return nil })
+	assertDisabled(err, REPLAY, false)
+
+	_, err = store.Query(ctx, streamID, event.StreamQuery{})
+	assertDisabled(err, QUERY, false)
+
+	// read-only maintenance mode
+	toggles.Store(&Toggles{Append: true, Index: true, Forward: true, ReadOnly: true})
+	eventually(t, func() bool {
+		tg, _ := f.Get(ctx, streamID.GlobalID())
+		return tg.ReadOnly
+	})
+
+	err = store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(1)))
+	if !errors.Is(err, event.ErrAppendEventsFailed) {
+		t.Fatalf("expect %v error to occur, got: %v", event.ErrAppendEventsFailed, err)
+	}
+	assertDisabled(err, APPEND, true)
+
+	envs, err := store.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 4, len(envs); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if _, err := store.Query(ctx, event.NewStreamID(streamID.GlobalID()), event.StreamQuery{}); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+}
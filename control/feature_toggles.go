@@ -3,16 +3,20 @@ package control
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/ln80/event-store/event"
 	"github.com/ln80/event-store/event/errors"
 )
 
 var (
 	ErrLoadConfigurationFailed = errors.New("load configuration failed")
 	ErrFeatureDisabled         = errors.New("feature disabled")
+	ErrInvalidConfiguration    = errors.New("invalid configuration")
 )
 
 type Feature string
@@ -21,12 +25,51 @@ const (
 	APPEND  Feature = "append"
 	INDEX   Feature = "index"
 	FORWARD Feature = "forward"
+	LOAD    Feature = "load"
+	REPLAY  Feature = "replay"
+	QUERY   Feature = "query"
 )
 
+// FeatureDisabledError describes a disabled feature, it wraps ErrFeatureDisabled.
+type FeatureDisabledError struct {
+	Feature  Feature
+	StreamID string
+	// ReadOnly is true if the feature is disabled by the read-only maintenance mode.
+	ReadOnly bool
+}
+
+// Error implements the error interface.
+func (e FeatureDisabledError) Error() string {
+	str := ErrFeatureDisabled.Error() + ": " + string(e.Feature)
+	if e.StreamID != "" {
+		str += ", stream: " + e.StreamID
+	}
+	if e.ReadOnly {
+		str += " (read-only mode)"
+	}
+	return str
+}
+
+func (e FeatureDisabledError) Unwrap() error { return ErrFeatureDisabled }
+
 type Toggles struct {
 	Index, Forward, Append bool
+
+	// DisableLoad, DisableReplay and DisableQuery toggle off the read side, which is enabled by default
+	// to keep existing configurations working as expected.
+	DisableLoad, DisableReplay, DisableQuery bool
+
+	// ReadOnly puts the stream in maintenance mode: appends are rejected regardless of the Append toggle.
+	ReadOnly bool
+
+	// Limits are enforced by Limiter.
+	Limits Limits
 }
 
+// StreamToggles presents the toggles of the streams matching StreamID.
+//
+// StreamID is either a global stream ID, a stream ID prefix made of event.StreamID parts (ex: "tenant1#orders"),
+// or a pattern whose parts are globs (ex: "tenant-*#orders"). It matches the given stream and all its sub-streams.
 type StreamToggles struct {
 	StreamID string
 	Toggles
@@ -37,12 +80,30 @@ type Configuration struct {
 	Streams []StreamToggles
 }
 
+// Get returns the toggles of the most specific rule matching the given stream ID, otherwise the default toggles.
+// See rule.compare for precedence details.
 func (c Configuration) Get(streamID string, def ...*Toggles) Toggles {
+	parts := strings.Split(streamID, event.StreamIDPartsDelimiter)
+
+	var (
+		found bool
+		best  rule
+		tgs   Toggles
+	)
 	for _, f := range c.Streams {
-		if f.StreamID == streamID {
-			return f.Toggles
+		r, err := parseRule(f.StreamID)
+		if err != nil || !r.match(parts) {
+			continue
 		}
+		// the first rule wins among equally specific ones, such a case is rejected by Validate though
+		if !found || r.compare(best) > 0 {
+			found, best, tgs = true, r, f.Toggles
+		}
+	}
+	if found {
+		return tgs
 	}
+
 	if c.Default == nil && len(def) > 0 {
 		return *def[0]
 	}
@@ -50,6 +111,33 @@ func (c Configuration) Get(streamID string, def ...*Toggles) Toggles {
 	return *c.Default
 }
 
+// Validate checks the stream patterns, and rejects ambiguous rules: equally specific rules with different toggles
+// which might match the same stream.
+func (c Configuration) Validate() error {
+	rules := make([]rule, len(c.Streams))
+	for i, f := range c.Streams {
+		r, err := parseRule(f.StreamID)
+		if err != nil {
+			return errors.Err(ErrInvalidConfiguration, "", err)
+		}
+		rules[i] = r
+	}
+
+	for i := range rules {
+		for j := i + 1; j < len(rules); j++ {
+			if c.Streams[i].Toggles == c.Streams[j].Toggles {
+				continue
+			}
+			if rules[i].compare(rules[j]) == 0 && rules[i].overlaps(rules[j]) {
+				return errors.Err(ErrInvalidConfiguration, "",
+					fmt.Errorf("ambiguous stream patterns %q and %q", c.Streams[i].StreamID, c.Streams[j].StreamID))
+			}
+		}
+	}
+
+	return nil
+}
+
 type configurationCache struct {
 	Configuration
 	At time.Time
@@ -58,19 +146,30 @@ type configurationCache struct {
 func (t Toggles) IsZero() bool {
 	return t == Toggles{}
 }
+
+// Enabled returns a FeatureDisabledError if the given feature is disabled.
 func (t Toggles) Enabled(f Feature) error {
 	toggle := false
 	switch f {
 	case APPEND:
+		if t.ReadOnly {
+			return FeatureDisabledError{Feature: f, ReadOnly: true}
+		}
 		toggle = t.Append
 	case INDEX:
 		toggle = t.Index
 	case FORWARD:
 		toggle = t.Forward
+	case LOAD:
+		toggle = !t.DisableLoad
+	case REPLAY:
+		toggle = !t.DisableReplay
+	case QUERY:
+		toggle = !t.DisableQuery
 	}
 
 	if !toggle {
-		return ErrFeatureDisabled
+		return FeatureDisabledError{Feature: f}
 	}
 
 	return nil
@@ -234,11 +333,16 @@ func (e *FeatureToggle) load(ctx context.Context) (*configurationCache, error) {
 	if err := json.Unmarshal(b, &config); err != nil {
 		return nil, errors.Err(ErrLoadConfigurationFailed, "", err)
 	}
+	if err := config.Validate(); err != nil {
+		return nil, errors.Err(ErrLoadConfigurationFailed, "", err)
+	}
 
 	return &configurationCache{Configuration: config, At: time.Now()}, nil
 }
 
 func (e *FeatureToggle) watch(ctx context.Context) error {
+	// changes are detected against the last watched configuration, rather than the cache which is also refreshed by Get.
+	cache := e.cache.Load().(*configurationCache)
 	for {
 		select {
 		case <-ctx.Done():
@@ -253,25 +357,23 @@ func (e *FeatureToggle) watch(ctx context.Context) error {
 			return err
 		}
 
-		cache := e.cache.Load().(*configurationCache)
-		seen := make(map[string]struct{}, 0)
-		for _, cur := range cache.Configuration.Streams {
-			newToggles := newConfig.Get(cur.StreamID, newConfig.Default)
-			if newToggles != cur.Toggles {
-				e.notify(cur.StreamID, newToggles)
-			}
-			seen[cur.StreamID] = struct{}{}
+		// notify subscribers whose toggles have changed, including the ones relying on the default toggles.
+		e.mu.RLock()
+		streamIDs := make([]string, 0, len(e.subscribers))
+		for streamID := range e.subscribers {
+			streamIDs = append(streamIDs, streamID)
 		}
-		for _, n := range newConfig.Configuration.Streams {
-			if _, ok := seen[n.StreamID]; ok {
-				continue
-			}
-			if n.Toggles != *cache.Default && n.Toggles != e.cfg.Default {
-				e.notify(n.StreamID, n.Toggles)
+		e.mu.RUnlock()
+
+		for _, streamID := range streamIDs {
+			newToggles := newConfig.Get(streamID, &e.cfg.Default)
+			if newToggles != cache.Get(streamID, &e.cfg.Default) {
+				e.notify(streamID, newToggles)
 			}
 		}
 
 		e.cache.Store(newConfig)
+		cache = newConfig
 	}
 }
 
@@ -164,3 +164,126 @@ func TestFeatureToggle_WithLoadError(t *testing.T) {
 		t.Fatalf("expect %v,%v be equals", want, got)
 	}
 }
+
+func TestConfiguration_Get(t *testing.T) {
+	def := Toggles{Append: true, Index: true, Forward: true}
+	c := Configuration{
+		Streams: []StreamToggles{
+			{StreamID: "*", Toggles: Toggles{Append: true}},
+			{StreamID: "tenant-*", Toggles: Toggles{Index: true}},
+			{StreamID: "tenant-1", Toggles: Toggles{Forward: true}},
+			{StreamID: "tenant-*#orders", Toggles: Toggles{Append: true, Index: true}},
+			{StreamID: "tenant-1#orders", Toggles: Toggles{Append: true, Forward: true}},
+			{StreamID: "tenant-1#*", Toggles: Toggles{Index: true, Forward: true}},
+			{StreamID: "legacy", Toggles: Toggles{}},
+		},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	tcs := []struct {
+		streamID string
+		want     Toggles
+	}{
+		{streamID: "other", want: Toggles{Append: true}},
+		{streamID: "tenant-2", want: Toggles{Index: true}},
+		{streamID: "tenant-2#invoices", want: Toggles{Index: true}},
+		{streamID: "tenant-1", want: Toggles{Forward: true}},
+		{streamID: "tenant-2#orders#123", want: Toggles{Append: true, Index: true}},
+		{streamID: "tenant-1#orders#123", want: Toggles{Append: true, Forward: true}},
+		{streamID: "tenant-1#invoices", want: Toggles{Index: true, Forward: true}},
+		{streamID: "legacy", want: Toggles{}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.streamID, func(t *testing.T) {
+			// the rules order must not matter
+			for _, c := range []Configuration{c, reversed(c)} {
+				if want, got := tc.want, c.Get(tc.streamID, &def); want != got {
+					t.Fatalf("expect %v, %v be equals", want, got)
+				}
+			}
+		})
+	}
+
+	c = Configuration{Streams: []StreamToggles{{StreamID: "tenant-1#orders"}}}
+	if want, got := def, c.Get("tenant-1", &def); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func reversed(c Configuration) Configuration {
+	streams := make([]StreamToggles, len(c.Streams))
+	for i, s := range c.Streams {
+		streams[len(streams)-1-i] = s
+	}
+	return Configuration{Default: c.Default, Streams: streams}
+}
+
+func TestConfiguration_Validate(t *testing.T) {
+	on, off := Toggles{Append: true}, Toggles{}
+
+	tcs := []struct {
+		name    string
+		streams []StreamToggles
+		ok      bool
+	}{
+		{
+			name:    "duplicate with same toggles",
+			streams: []StreamToggles{{StreamID: "tenant-1", Toggles: on}, {StreamID: "tenant-1", Toggles: on}},
+			ok:      true,
+		},
+		{
+			name:    "duplicate with different toggles",
+			streams: []StreamToggles{{StreamID: "tenant-1", Toggles: on}, {StreamID: "tenant-1", Toggles: off}},
+		},
+		{
+			name:    "disjoint globs",
+			streams: []StreamToggles{{StreamID: "a-*", Toggles: on}, {StreamID: "b-*", Toggles: off}, {StreamID: "*-xx", Toggles: on}, {StreamID: "*-yy", Toggles: off}},
+			ok:      true,
+		},
+		{
+			name:    "overlapping globs",
+			streams: []StreamToggles{{StreamID: "a*", Toggles: on}, {StreamID: "*b", Toggles: off}},
+		},
+		{
+			name:    "overlapping nested globs",
+			streams: []StreamToggles{{StreamID: "tenant-1#a?", Toggles: on}, {StreamID: "tenant-1#?b", Toggles: off}},
+		},
+		{
+			name:    "different depths",
+			streams: []StreamToggles{{StreamID: "*", Toggles: on}, {StreamID: "*#*", Toggles: off}},
+			ok:      true,
+		},
+		{
+			name:    "bad pattern",
+			streams: []StreamToggles{{StreamID: "tenant-[", Toggles: on}},
+		},
+		{
+			name:    "empty part",
+			streams: []StreamToggles{{StreamID: "tenant-1##orders", Toggles: on}},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Configuration{Streams: tc.streams}.Validate()
+			if tc.ok {
+				if err != nil {
+					t.Fatal("expect err be nil, got", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidConfiguration) {
+				t.Fatalf("expect %v error to occur, got: %v", ErrInvalidConfiguration, err)
+			}
+		})
+	}
+
+	// ambiguous configurations are rejected at load time
+	loader := &MockLoader{LoadFunc: func(ctx context.Context) ([]byte, error) {
+		return json.Marshal(Configuration{Streams: []StreamToggles{{StreamID: "a*", Toggles: on}, {StreamID: "*b", Toggles: off}}})
+	}}
+	if _, err := NewFeatureToggler(context.Background(), loader); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Fatalf("expect %v error to occur, got: %v", ErrInvalidConfiguration, err)
+	}
+}
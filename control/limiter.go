@@ -0,0 +1,299 @@
+package control
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	es "github.com/ln80/event-store"
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/event/sourcing"
+	"github.com/ln80/event-store/json"
+)
+
+var (
+	ErrThrottled = errors.New("throttled")
+)
+
+// Limit presents a kind of per global stream limit.
+type Limit string
+
+const (
+	APPENDS_PER_SECOND Limit = "appends per second"
+	EVENTS_PER_RECORD  Limit = "events per record"
+	BYTES_PER_DAY      Limit = "bytes per day"
+)
+
+// Limits defines the per global stream limits enforced by Limiter. A zero value means no limit.
+type Limits struct {
+	// AppendsPerSecond is the sustained rate of appended records.
+	AppendsPerSecond float64
+	// AppendBurst is the count of records that can be appended at once, it defaults to AppendsPerSecond rounded up.
+	AppendBurst int
+	// EventsPerRecord is the maximum count of events per record.
+	EventsPerRecord int
+	// BytesPerDay is the quota of appended bytes, refilled continuously over a sliding day.
+	BytesPerDay int64
+}
+
+// ThrottleError describes an append rejected by Limiter, it wraps ErrThrottled.
+type ThrottleError struct {
+	Limit    Limit
+	StreamID string
+	// RetryAfter is the delay after which the append might succeed.
+	// It's zero if the append exceeds the limit regardless of the delay, ex: too many events per record.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e ThrottleError) Error() string {
+	str := ErrThrottled.Error() + ": " + string(e.Limit) + " limit exceeded, stream: " + e.StreamID
+	if e.RetryAfter > 0 {
+		str += ", retry after: " + e.RetryAfter.String()
+	}
+	return str
+}
+
+func (e ThrottleError) Unwrap() error { return ErrThrottled }
+
+// limiterEvictInterval is the minimum delay between two evictions of idle buckets.
+const limiterEvictInterval = time.Minute
+
+// LimiterConfig presents the limiter configuration.
+type LimiterConfig struct {
+	// Serializer is used to measure the size of appended records, JSON is used by default.
+	//
+	// The decorated store's serializer isn't exposed by the es.EventStore interface, thus it must be set explicitly
+	// to the one of the store, otherwise the measured sizes might differ from the persisted ones.
+	Serializer event.Serializer
+}
+
+// Limiter enforces per global stream limits on top of an event store, using token buckets.
+//
+// Limits are defined by the Limits of the global stream toggles, thus they're sourced from the same Configuration.
+// Appends exceeding a limit fail with a ThrottleError, and don't consume tokens. Tokens are reserved before appending
+// and refunded if the append fails.
+//
+// Buckets of idle global streams are evicted once refilled, which is equivalent to keeping them.
+type Limiter struct {
+	feature FeatureToggler
+	es.EventStore
+
+	mu      sync.Mutex
+	buckets map[string]*limitBuckets // per global stream
+	evicted time.Time
+	now     func() time.Time
+
+	cfg *LimiterConfig
+}
+
+// NewLimiter returns an event store decorator that enforces the global stream limits.
+func NewLimiter(store es.EventStore, feature FeatureToggler, opts ...func(*LimiterConfig)) *Limiter {
+	cfg := &LimiterConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	if cfg.Serializer == nil {
+		cfg.Serializer = json.NewEventSerializer("")
+	}
+
+	return &Limiter{
+		feature:    feature,
+		EventStore: store,
+		buckets:    make(map[string]*limitBuckets),
+		now:        time.Now,
+		cfg:        cfg,
+	}
+}
+
+func (l *Limiter) Append(ctx context.Context, id event.StreamID, events []event.Envelope, optFns ...func(*event.AppendConfig)) error {
+	refund, err := l.limit(ctx, id, events)
+	if err != nil {
+		return err
+	}
+
+	if err := l.EventStore.Append(ctx, id, events, optFns...); err != nil {
+		refund()
+		return err
+	}
+	return nil
+}
+
+func (l *Limiter) AppendToStream(ctx context.Context, chunk sourcing.Stream, optFns ...func(opt *event.AppendConfig)) error {
+	refund, err := l.limit(ctx, chunk.ID(), chunk.Unwrap())
+	if err != nil {
+		return err
+	}
+
+	if err := l.EventStore.AppendToStream(ctx, chunk, optFns...); err != nil {
+		refund()
+		return err
+	}
+	return nil
+}
+
+// limit reserves the tokens required by the given record, or returns a ThrottleError if the limits are exceeded.
+// The returned function gives the reserved tokens back, it's called if the append fails.
+func (l *Limiter) limit(ctx context.Context, id event.StreamID, events []event.Envelope) (refund func(), err error) {
+	refund = func() {}
+
+	toggles, err := l.feature.Get(ctx, id.GlobalID())
+	if err != nil {
+		return refund, err
+	}
+	limits := toggles.Limits
+	if limits == (Limits{}) || len(events) == 0 {
+		return refund, nil
+	}
+
+	throttled := func(limit Limit, retryAfter time.Duration) error {
+		return errors.Err(event.ErrAppendEventsFailed, id.String(),
+			ThrottleError{Limit: limit, StreamID: id.String(), RetryAfter: retryAfter})
+	}
+
+	if limits.EventsPerRecord > 0 && len(events) > limits.EventsPerRecord {
+		return refund, throttled(EVENTS_PER_RECORD, 0)
+	}
+
+	size := 0
+	if limits.BytesPerDay > 0 {
+		b, err := l.cfg.Serializer.MarshalEventBatch(ctx, events)
+		if err != nil {
+			return refund, errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+		}
+		size = len(b)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evict(now)
+
+	b, ok := l.buckets[id.GlobalID()]
+	if !ok {
+		b = &limitBuckets{}
+		l.buckets[id.GlobalID()] = b
+	}
+	b.configure(limits, now)
+
+	// tokens are consumed only if all limits are met
+	if limits.AppendsPerSecond > 0 {
+		if wait, ok := b.appends.wait(1); !ok || wait > 0 {
+			return refund, throttled(APPENDS_PER_SECOND, wait)
+		}
+	}
+	if limits.BytesPerDay > 0 {
+		if wait, ok := b.bytes.wait(float64(size)); !ok || wait > 0 {
+			return refund, throttled(BYTES_PER_DAY, wait)
+		}
+	}
+	appends := 0.0
+	if limits.AppendsPerSecond > 0 {
+		appends = 1
+	}
+	b.appends.take(appends)
+	b.bytes.take(float64(size))
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		b.appends.give(appends)
+		b.bytes.give(float64(size))
+	}, nil
+}
+
+// evict removes the buckets that are full once refilled, the lock must be held by the caller.
+// A full bucket behaves as a new one, thus evicted buckets are transparently created again if needed.
+func (l *Limiter) evict(now time.Time) {
+	if now.Sub(l.evicted) < limiterEvictInterval {
+		return
+	}
+	l.evicted = now
+
+	for id, b := range l.buckets {
+		b.configure(b.limits, now)
+		if b.appends.full() && b.bytes.full() {
+			delete(l.buckets, id)
+		}
+	}
+}
+
+// limitBuckets holds the token buckets of a global stream.
+type limitBuckets struct {
+	limits         Limits
+	appends, bytes bucket
+}
+
+// configure refills the buckets, and applies limits changes if any.
+func (b *limitBuckets) configure(limits Limits, now time.Time) {
+	b.appends.refill(now)
+	b.bytes.refill(now)
+
+	if limits != b.limits {
+		burst := float64(limits.AppendBurst)
+		if burst <= 0 {
+			burst = math.Max(1, math.Ceil(limits.AppendsPerSecond))
+		}
+		b.appends.configure(limits.AppendsPerSecond, burst, now)
+		b.bytes.configure(float64(limits.BytesPerDay)/(24*time.Hour).Seconds(), float64(limits.BytesPerDay), now)
+		b.limits = limits
+	}
+}
+
+// bucket is a token bucket refilled at a constant rate, up to its burst capacity.
+type bucket struct {
+	rate, burst float64 // rate per second
+	tokens      float64
+	at          time.Time
+}
+
+// configure updates the bucket rate and capacity, a new bucket is created full.
+func (b *bucket) configure(rate, burst float64, now time.Time) {
+	if b.at.IsZero() {
+		b.tokens = burst
+		b.at = now
+	}
+	b.rate, b.burst = rate, burst
+	b.tokens = math.Min(b.tokens, burst)
+}
+
+func (b *bucket) refill(now time.Time) {
+	if b.at.IsZero() {
+		return
+	}
+	if elapsed := now.Sub(b.at).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.at = now
+	}
+}
+
+// wait returns the delay until n tokens are available. It returns false if n exceeds the bucket capacity.
+func (b *bucket) wait(n float64) (time.Duration, bool) {
+	if n > b.burst {
+		return 0, false
+	}
+	if n <= b.tokens {
+		return 0, true
+	}
+	return time.Duration(math.Ceil((n - b.tokens) / b.rate * float64(time.Second))), true
+}
+
+func (b *bucket) take(n float64) {
+	b.tokens -= n
+}
+
+// give returns n tokens to the bucket, up to its burst capacity.
+func (b *bucket) give(n float64) {
+	b.tokens = math.Min(b.burst, b.tokens+n)
+}
+
+func (b *bucket) full() bool {
+	return b.tokens >= b.burst
+}
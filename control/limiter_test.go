@@ -0,0 +1,190 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ln80/event-store/event"
+	event_errors "github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/event/sourcing"
+	"github.com/ln80/event-store/eventtest"
+	"github.com/ln80/event-store/json"
+	"github.com/ln80/event-store/memory"
+)
+
+func TestLimiter(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	setup := func(t *testing.T, limits Limits) (*Limiter, *time.Time) {
+		t.Helper()
+
+		var toggles atomic.Pointer[Toggles]
+		toggles.Store(&Toggles{Append: true, Index: true, Forward: true, Limits: limits})
+
+		now := time.Now()
+		l := NewLimiter(memory.NewEventStore(), newTestToggler(t, &toggles))
+		l.now = func() time.Time { return now }
+		return l, &now
+	}
+
+	assertThrottled := func(t *testing.T, err error, limit Limit, retryAfter time.Duration) {
+		t.Helper()
+		if !errors.Is(err, event.ErrAppendEventsFailed) || !errors.Is(err, ErrThrottled) {
+			t.Fatalf("expect %v error to occur, got: %v", ErrThrottled, err)
+		}
+		ok, terr := event_errors.ErrAs[ThrottleError](err)
+		if !ok {
+			t.Fatalf("expect %v error to occur, got: %v", ErrThrottled, err)
+		}
+		if want, got := limit, terr.Limit; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := retryAfter, terr.RetryAfter; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	t.Run("events per record", func(t *testing.T) {
+		l, _ := setup(t, Limits{EventsPerRecord: 2})
+
+		streamID := event.NewStreamID(event.UID().String())
+		err := l.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(3)))
+		assertThrottled(t, err, EVENTS_PER_RECORD, 0)
+
+		if err := l.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(2))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	})
+
+	t.Run("appends per second", func(t *testing.T) {
+		l, now := setup(t, Limits{AppendsPerSecond: 2})
+
+		streamID := event.NewStreamID(event.UID().String(), "service")
+		appendToStream := func(ver event.Version) (event.Version, error) {
+			stm := sourcing.Wrap(ctx, streamID, ver, eventtest.GenEvents(1))
+			return stm.Version(), l.AppendToStream(ctx, stm)
+		}
+
+		ver := event.VersionZero
+		for i := 0; i < 2; i++ {
+			v, err := appendToStream(ver)
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			ver = v
+		}
+		_, err := appendToStream(ver)
+		assertThrottled(t, err, APPENDS_PER_SECOND, 500*time.Millisecond)
+
+		// tokens are shared by the streams of the same global stream
+		globalID := event.NewStreamID(streamID.GlobalID())
+		err = l.Append(ctx, globalID, event.Wrap(ctx, globalID, eventtest.GenEvents(1)))
+		assertThrottled(t, err, APPENDS_PER_SECOND, 500*time.Millisecond)
+
+		*now = now.Add(500 * time.Millisecond)
+		if _, err := appendToStream(ver); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	})
+
+	t.Run("bytes per day", func(t *testing.T) {
+		streamID := event.NewStreamID(event.UID().String())
+		envs := func() []event.Envelope {
+			return event.Wrap(ctx, streamID, []any{&eventtest.Event1{Val: "test"}})
+		}
+		b, err := json.NewEventSerializer("").MarshalEventBatch(ctx, envs())
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		size := int64(len(b))
+
+		l, now := setup(t, Limits{BytesPerDay: 2 * size, AppendsPerSecond: 1})
+
+		if err := l.Append(ctx, streamID, envs()); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		*now = now.Add(time.Second)
+		if err := l.Append(ctx, streamID, envs()); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		// the quota is refilled continuously, i.e. half of it in about 12 hours,
+		// note that record sizes slightly vary depending on the event timestamp.
+		*now = now.Add(time.Second)
+		err = l.Append(ctx, streamID, envs())
+		ok, terr := event_errors.ErrAs[ThrottleError](err)
+		if !ok || terr.Limit != BYTES_PER_DAY {
+			t.Fatalf("expect %v error to occur, got: %v", ErrThrottled, err)
+		}
+		if terr.RetryAfter < 11*time.Hour || terr.RetryAfter > 13*time.Hour {
+			t.Fatalf("expect retry after be about 12h, got: %v", terr.RetryAfter)
+		}
+
+		// records exceeding the quota can't be appended regardless of the delay, and don't consume tokens
+		*now = now.Add(24 * time.Hour)
+		err = l.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(10)))
+		assertThrottled(t, err, BYTES_PER_DAY, 0)
+
+		if err := l.Append(ctx, streamID, envs()); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	})
+
+	t.Run("refund on failure", func(t *testing.T) {
+		l, now := setup(t, Limits{AppendsPerSecond: 1})
+
+		streamID := event.NewStreamID(event.UID().String())
+		stm := sourcing.Wrap(ctx, streamID, event.VersionZero, eventtest.GenEvents(1))
+		if err := l.AppendToStream(ctx, stm); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		// the conflicting append doesn't consume the refilled token
+		*now = now.Add(time.Second)
+		err := l.AppendToStream(ctx, sourcing.Wrap(ctx, streamID, event.VersionZero, eventtest.GenEvents(1)))
+		if !errors.Is(err, event.ErrAppendEventsConflict) {
+			t.Fatalf("expect %v error to occur, got: %v", event.ErrAppendEventsConflict, err)
+		}
+		if err := l.AppendToStream(ctx, sourcing.Wrap(ctx, streamID, stm.Version(), eventtest.GenEvents(1))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	})
+
+	t.Run("evict idle buckets", func(t *testing.T) {
+		l, now := setup(t, Limits{AppendsPerSecond: 1})
+
+		streamID := event.NewStreamID(event.UID().String())
+		if err := l.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(1))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		// the bucket is refilled in the meantime, thus it's evicted
+		*now = now.Add(limiterEvictInterval)
+		streamID2 := event.NewStreamID(event.UID().String())
+		if err := l.Append(ctx, streamID2, event.Wrap(ctx, streamID2, eventtest.GenEvents(1))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := 1, len(l.buckets); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if _, ok := l.buckets[streamID2.GlobalID()]; !ok {
+			t.Fatalf("expect %s bucket be found", streamID2.GlobalID())
+		}
+	})
+
+	t.Run("no limits", func(t *testing.T) {
+		l, _ := setup(t, Limits{})
+
+		streamID := event.NewStreamID(event.UID().String())
+		for i := 0; i < 10; i++ {
+			if err := l.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(10))); err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+		}
+	})
+}
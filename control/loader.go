@@ -0,0 +1,210 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ln80/event-store/event/errors"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnexpectedResponse = errors.New("unexpected configuration response")
+)
+
+// Format presents the encoding format of the configuration.
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// toJSON converts the given configuration to JSON, as expected by FeatureToggle.
+func toJSON(b []byte, format Format) ([]byte, error) {
+	if format != YAML || len(b) == 0 {
+		return b, nil
+	}
+
+	var v any
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// FileLoaderConfig presents the file loader configuration.
+type FileLoaderConfig struct {
+	// Format of the configuration file. By default, it's inferred from the file extension:
+	// ".yaml" and ".yml" files are YAML-encoded, others are JSON-encoded.
+	Format Format
+}
+
+// FileLoader implements Loader on top of a JSON or YAML configuration file.
+//
+// Changes are detected using the file modification time and size;
+// an empty result is returned if the file hasn't changed since the last load.
+type FileLoader struct {
+	path string
+
+	mu      sync.Mutex
+	loaded  bool
+	modTime time.Time
+	size    int64
+
+	cfg *FileLoaderConfig
+}
+
+var _ Loader = &FileLoader{}
+
+// NewFileLoader returns a loader of the given configuration file.
+func NewFileLoader(path string, opts ...func(*FileLoaderConfig)) *FileLoader {
+	cfg := &FileLoaderConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	if cfg.Format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			cfg.Format = YAML
+		default:
+			cfg.Format = JSON
+		}
+	}
+
+	return &FileLoader{
+		path: path,
+		cfg:  cfg,
+	}
+}
+
+// Load implements Loader interface.
+func (l *FileLoader) Load(ctx context.Context) ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return nil, err
+	}
+	if l.loaded && info.ModTime().Equal(l.modTime) && info.Size() == l.size {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(l.path)
+	if err != nil {
+		return nil, err
+	}
+	b, err = toJSON(b, l.cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	l.loaded, l.modTime, l.size = true, info.ModTime(), info.Size()
+
+	return b, nil
+}
+
+// HTTPLoaderConfig presents the HTTP loader configuration.
+type HTTPLoaderConfig struct {
+	// Client is used to send requests, http.DefaultClient is used by default.
+	Client *http.Client
+
+	// Header is added to the requests, ex: authorization header.
+	Header http.Header
+}
+
+// HTTPLoader implements Loader on top of an HTTP endpoint which serves a JSON or YAML configuration.
+//
+// It relies on ETag and If-None-Match headers to detect changes;
+// an empty result is returned if the server responds with 304 Not Modified.
+// YAML responses are identified by their content type (ex: "application/yaml").
+type HTTPLoader struct {
+	url string
+
+	mu   sync.Mutex
+	etag string
+
+	cfg *HTTPLoaderConfig
+}
+
+var _ Loader = &HTTPLoader{}
+
+// NewHTTPLoader returns a loader of the configuration served by the given URL.
+func NewHTTPLoader(url string, opts ...func(*HTTPLoaderConfig)) *HTTPLoader {
+	cfg := &HTTPLoaderConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	return &HTTPLoader{
+		url: url,
+		cfg: cfg,
+	}
+}
+
+// Load implements Loader interface.
+func (l *HTTPLoader) Load(ctx context.Context) ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range l.cfg.Header {
+		req.Header[k] = v
+	}
+	if l.etag != "" {
+		req.Header.Set("If-None-Match", l.etag)
+	}
+
+	resp, err := l.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		return nil, errors.Err(ErrUnexpectedResponse, "", resp.Status)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	format := JSON
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); strings.Contains(mt, "yaml") {
+		format = YAML
+	}
+	b, err = toJSON(b, format)
+	if err != nil {
+		return nil, err
+	}
+
+	l.etag = resp.Header.Get("ETag")
+
+	return b, nil
+}
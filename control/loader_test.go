@@ -0,0 +1,201 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileLoader(t *testing.T) {
+	ctx := context.Background()
+
+	tcs := []struct {
+		name    string
+		file    string
+		content []string
+	}{
+		{
+			name: "json",
+			file: "toggles.json",
+			content: []string{
+				`{"Default": {"Append": true}, "Streams": [{"StreamID": "stm_1", "Index": true}]}`,
+				`{"Default": {"Append": true, "Forward": true}}`,
+			},
+		},
+		{
+			name: "yaml",
+			file: "toggles.yaml",
+			content: []string{
+				"default:\n  append: true\nstreams:\n  - streamID: stm_1\n    index: true\n",
+				"default:\n  append: true\n  forward: true\n",
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, []byte(tc.content[0]), 0o644); err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+
+			loader := NewFileLoader(path)
+
+			b, err := loader.Load(ctx)
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			c := Configuration{}
+			if err := json.Unmarshal(b, &c); err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if want, got := (Toggles{Index: true}), c.Get("stm_1"); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+			if want, got := (Toggles{Append: true}), c.Get("stm_2"); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+
+			// unchanged file
+			b, err = loader.Load(ctx)
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if want, got := 0, len(b); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+
+			// changed file
+			if err := os.WriteFile(path, []byte(tc.content[1]), 0o644); err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			at := time.Now().Add(time.Second)
+			if err := os.Chtimes(path, at, at); err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			b, err = loader.Load(ctx)
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			c = Configuration{}
+			if err := json.Unmarshal(b, &c); err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if want, got := (Toggles{Append: true, Forward: true}), c.Get("stm_1"); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		})
+	}
+
+	t.Run("feature toggler", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "toggles.json")
+		if err := os.WriteFile(path, []byte(`{"Default": {"Append": true}}`), 0o644); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		f, err := NewFeatureToggler(ctx, NewFileLoader(path), func(ftc *FeatureToggleConfig) {
+			ftc.CacheMaxAge = 0
+		})
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		// the cached configuration is kept as long as the file is unchanged
+		for i := 0; i < 2; i++ {
+			tg, err := f.Get(ctx, "stm_1")
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if want, got := (Toggles{Append: true}), tg; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := NewFileLoader(filepath.Join(t.TempDir(), "missing.json")).Load(ctx); err == nil {
+			t.Fatal("expect err be not nil")
+		}
+	})
+}
+
+func TestHTTPLoader(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		version  atomic.Int32
+		requests atomic.Int32
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Authorization") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		etag := `"v` + string(rune('0'+version.Load())) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		if version.Load() == 0 {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"Default": {"Append": true}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		_, _ = w.Write([]byte("default:\n  append: true\n  index: true\n"))
+	}))
+	defer srv.Close()
+
+	loader := NewHTTPLoader(srv.URL, func(cfg *HTTPLoaderConfig) {
+		cfg.Header = http.Header{"Authorization": []string{"token"}}
+	})
+
+	load := func() []byte {
+		t.Helper()
+		b, err := loader.Load(ctx)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		return b
+	}
+
+	c := Configuration{}
+	if err := json.Unmarshal(load(), &c); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := (Toggles{Append: true}), c.Get("stm_1"); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// not modified
+	if want, got := 0, len(load()); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// modified, YAML-encoded
+	version.Add(1)
+	c = Configuration{}
+	if err := json.Unmarshal(load(), &c); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := (Toggles{Append: true, Index: true}), c.Get("stm_1"); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := int32(3), requests.Load(); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// unexpected response
+	if _, err := NewHTTPLoader(srv.URL).Load(ctx); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Fatalf("expect %v error to occur, got: %v", ErrUnexpectedResponse, err)
+	}
+}
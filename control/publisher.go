@@ -0,0 +1,174 @@
+package control
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/logger"
+)
+
+// PublisherConfig presents the feature toggles publisher configuration.
+type PublisherConfig struct {
+	// Discard drops the events of global streams where FORWARD is disabled, instead of queuing them.
+	Discard bool
+}
+
+// Publisher enforces the FORWARD feature toggle on top of an event publisher.
+//
+// Events of global streams where FORWARD is disabled are queued in memory, and published once the toggle flips back on.
+// The order of events is preserved per global stream.
+type Publisher struct {
+	feature FeatureToggler
+	event.Publisher
+
+	mu     sync.Mutex
+	queues map[string][]event.Envelope // per global stream
+	ctx    context.Context             // scheduled drains lifecycle
+	stop   context.CancelFunc
+	wg     sync.WaitGroup
+
+	cfg *PublisherConfig
+}
+
+var _ event.Publisher = &Publisher{}
+
+// NewPublisher returns a publisher decorator that enforces the FORWARD feature toggle.
+func NewPublisher(publisher event.Publisher, feature FeatureToggler, opts ...func(*PublisherConfig)) *Publisher {
+	cfg := &PublisherConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	return &Publisher{
+		feature:   feature,
+		Publisher: publisher,
+		queues:    make(map[string][]event.Envelope),
+		ctx:       ctx,
+		stop:      stop,
+		cfg:       cfg,
+	}
+}
+
+// Close cancels the scheduled drains, and waits for the running ones to return.
+// Queued events are kept, they're published on the next successful publish to their global stream.
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	p.stop()
+	p.mu.Unlock()
+
+	p.wg.Wait()
+
+	return nil
+}
+
+// Publish implements event.Publisher interface.
+func (p *Publisher) Publish(ctx context.Context, events []event.Envelope) error {
+	// group events per global stream while preserving their order
+	globalIDs := make([]string, 0)
+	groups := make(map[string][]event.Envelope)
+	for _, env := range events {
+		if _, ok := groups[env.GlobalStreamID()]; !ok {
+			globalIDs = append(globalIDs, env.GlobalStreamID())
+		}
+		groups[env.GlobalStreamID()] = append(groups[env.GlobalStreamID()], env)
+	}
+
+	for _, globalID := range globalIDs {
+		toggles, err := p.feature.Get(ctx, globalID)
+		if err != nil {
+			return err
+		}
+
+		if toggles.Enabled(FORWARD) != nil {
+			if !p.cfg.Discard {
+				p.enqueue(globalID, groups[globalID])
+			}
+			continue
+		}
+
+		// queued events are published first
+		if err := p.drain(ctx, globalID); err != nil {
+			return err
+		}
+		if err := p.Publisher.Publish(ctx, groups[globalID]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Queued returns the count of events queued for the given global stream.
+func (p *Publisher) Queued(globalID string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.queues[globalID])
+}
+
+// enqueue queues the given events, and subscribes to the global stream toggles to drain the queue
+// once FORWARD is enabled.
+func (p *Publisher) enqueue(globalID string, events []event.Envelope) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	queue, ok := p.queues[globalID]
+	p.queues[globalID] = append(queue, events...)
+	if ok {
+		return
+	}
+
+	if p.ctx.Err() != nil {
+		return
+	}
+
+	ch, cancel := p.feature.Subscribe(globalID)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer cancel()
+
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case toggles, ok := <-ch:
+				if !ok {
+					return
+				}
+				if toggles.Enabled(FORWARD) != nil {
+					continue
+				}
+
+				if err := p.drain(p.ctx, globalID); err != nil {
+					// the remaining events are published on the next successful publish
+					logger.FromContext(p.ctx).WithName("control").Error(err, "Drain queued events failed", "stream", globalID)
+				}
+				return
+			}
+		}
+	}()
+}
+
+// drain publishes the queued events of the given global stream.
+// Failed events remain queued.
+func (p *Publisher) drain(ctx context.Context, globalID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	queue, ok := p.queues[globalID]
+	if !ok {
+		return nil
+	}
+	if err := p.Publisher.Publish(ctx, queue); err != nil {
+		return err
+	}
+	delete(p.queues, globalID)
+
+	return nil
+}
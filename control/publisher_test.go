@@ -0,0 +1,123 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/eventtest"
+)
+
+// mockPublisher records the published events.
+type mockPublisher struct {
+	mu     sync.Mutex
+	events []event.Envelope
+	err    error
+}
+
+func (p *mockPublisher) Publish(ctx context.Context, events []event.Envelope) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, events...)
+	return nil
+}
+
+func (p *mockPublisher) Published() []event.Envelope {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]event.Envelope{}, p.events...)
+}
+
+func TestPublisher(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	var toggles atomic.Pointer[Toggles]
+	toggles.Store(&Toggles{Append: true, Index: true})
+
+	mock := &mockPublisher{}
+	pub := NewPublisher(mock, newTestToggler(t, &toggles))
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, eventtest.GenEvents(2))
+	envs = append(envs, event.Wrap(ctx, streamID, eventtest.GenEvents(2))...)
+
+	if err := pub.Publish(ctx, envs[:2]); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := pub.Publish(ctx, envs[2:]); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// events are queued while forward is disabled
+	if want, got := 0, len(mock.Published()); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := len(envs), pub.Queued(streamID.GlobalID()); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// queued events are drained in order once the toggle flips back on
+	toggles.Store(&Toggles{Append: true, Index: true, Forward: true})
+	eventually(t, func() bool {
+		return len(mock.Published()) == len(envs)
+	})
+	for i, env := range mock.Published() {
+		if want, got := envs[i].ID(), env.ID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+	if want, got := 0, pub.Queued(streamID.GlobalID()); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestPublisher_Discard(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	var toggles atomic.Pointer[Toggles]
+	toggles.Store(&Toggles{Append: true, Index: true})
+
+	mock := &mockPublisher{}
+	pub := NewPublisher(mock, newTestToggler(t, &toggles), func(pc *PublisherConfig) {
+		pc.Discard = true
+	})
+
+	streamID := event.NewStreamID(event.UID().String())
+	if err := pub.Publish(ctx, event.Wrap(ctx, streamID, eventtest.GenEvents(2))); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 0, pub.Queued(streamID.GlobalID()); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// queued events remain queued if the drain fails
+	f := newTestToggler(t, &toggles)
+	pub = NewPublisher(mock, f)
+	mock.err = errors.New("publish mock error")
+	if err := pub.Publish(ctx, event.Wrap(ctx, streamID, eventtest.GenEvents(2))); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	toggles.Store(&Toggles{Append: true, Index: true, Forward: true})
+	eventually(t, func() bool {
+		tg, _ := f.Get(ctx, streamID.GlobalID())
+		return tg.Forward
+	})
+	if err := pub.Publish(ctx, event.Wrap(ctx, streamID, eventtest.GenEvents(1))); !errors.Is(err, mock.err) {
+		t.Fatalf("expect %v error to occur, got: %v", mock.err, err)
+	}
+	if want, got := 2, pub.Queued(streamID.GlobalID()); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
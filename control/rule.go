@@ -0,0 +1,127 @@
+package control
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ln80/event-store/event"
+)
+
+// rule presents a parsed StreamToggles.StreamID pattern.
+//
+// A pattern is made of parts separated by event.StreamIDPartsDelimiter, each part is either a literal or a glob
+// (see path.Match syntax). A rule matches the streams whose leading parts match its own parts, ex: "tenant1" matches
+// the global stream "tenant1" and all its sub-streams, "tenant1#orders" matches "tenant1#orders#123",
+// and "tenant-*#orders" matches "tenant-2#orders#456".
+type rule struct {
+	parts []string
+	globs []bool
+}
+
+func parseRule(pattern string) (rule, error) {
+	if pattern == "" {
+		return rule{}, fmt.Errorf("empty stream pattern")
+	}
+
+	r := rule{parts: strings.Split(pattern, event.StreamIDPartsDelimiter)}
+	r.globs = make([]bool, len(r.parts))
+	for i, p := range r.parts {
+		if p == "" {
+			return rule{}, fmt.Errorf("empty part in stream pattern %q", pattern)
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return rule{}, fmt.Errorf("invalid stream pattern %q: %w", pattern, err)
+		}
+		r.globs[i] = strings.ContainsAny(p, `*?[\`)
+	}
+	return r, nil
+}
+
+// match returns true if the rule matches the given stream ID parts.
+func (r rule) match(parts []string) bool {
+	if len(parts) < len(r.parts) {
+		return false
+	}
+	for i, p := range r.parts {
+		if !r.globs[i] {
+			if p != parts[i] {
+				return false
+			}
+			continue
+		}
+		if ok, _ := path.Match(p, parts[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compare returns a positive value if the rule is more specific than the given one, a negative value if it's less
+// specific, and zero if both are equally specific.
+//
+// Rules with more parts are more specific. Otherwise, parts are compared from left to right: a literal is more specific
+// than a glob, and a glob with more literal characters is more specific than one with fewer.
+func (r rule) compare(o rule) int {
+	if d := len(r.parts) - len(o.parts); d != 0 {
+		return d
+	}
+	for i := range r.parts {
+		switch {
+		case r.globs[i] == o.globs[i] && !r.globs[i]:
+			continue
+		case !r.globs[i]:
+			return 1
+		case !o.globs[i]:
+			return -1
+		}
+		if d := literals(r.parts[i]) - literals(o.parts[i]); d != 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// overlaps returns true if both rules might match the same stream. It expects equally specific rules.
+// It's conservative when comparing globs: it only rules out an overlap if their literal prefixes or suffixes differ.
+func (r rule) overlaps(o rule) bool {
+	for i := range r.parts {
+		if !r.globs[i] {
+			if r.parts[i] != o.parts[i] {
+				return false
+			}
+			continue
+		}
+		if !globsOverlap(r.parts[i], o.parts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// literals returns the count of non-special characters in the given glob.
+func literals(glob string) int {
+	n := 0
+	for _, c := range glob {
+		if !strings.ContainsRune(`*?[]\`, c) {
+			n++
+		}
+	}
+	return n
+}
+
+func globsOverlap(a, b string) bool {
+	if strings.ContainsRune(a, '\\') || strings.ContainsRune(b, '\\') {
+		return true
+	}
+
+	pa, pb := a[:strings.IndexAny(a, "*?[")], b[:strings.IndexAny(b, "*?[")]
+	if n := min(len(pa), len(pb)); pa[:n] != pb[:n] {
+		return false
+	}
+	sa, sb := a[strings.LastIndexAny(a, "*?]")+1:], b[strings.LastIndexAny(b, "*?]")+1:]
+	if n := min(len(sa), len(sb)); sa[len(sa)-n:] != sb[len(sb)-n:] {
+		return false
+	}
+	return true
+}
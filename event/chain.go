@@ -0,0 +1,194 @@
+package event
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+
+	"github.com/ln80/event-store/event/errors"
+)
+
+const (
+	// MetadataChainHashKey is the metadata key of the record hash, set on the last event of the record.
+	MetadataChainHashKey = "es.chain.hash"
+	// MetadataChainPrevKey is the metadata key of the previous record hash in the global stream.
+	MetadataChainPrevKey = "es.chain.prev"
+)
+
+var (
+	ErrChainRecordFailed = errors.New("chain record failed")
+	ErrHashChainBroken   = errors.New("hash chain broken")
+)
+
+// ChainBreak describes the first broken link found in a hash chain.
+type ChainBreak struct {
+	// Record is the global version of the record where the chain breaks.
+	Record Version
+	// Reason explains why the link is broken.
+	Reason string
+}
+
+// Error implements the error interface.
+func (b ChainBreak) Error() string {
+	return fmt.Sprintf("record %s: %s", b.Record, b.Reason)
+}
+
+// unchained hides the chaining metadata of the envelope, so that it can be serialized and hashed.
+type unchained struct {
+	Envelope
+}
+
+// Metadata returns the envelope metadata without the chaining keys.
+func (e unchained) Metadata() map[string]string {
+	md := maps.Clone(e.Envelope.Metadata())
+	delete(md, MetadataChainHashKey)
+	delete(md, MetadataChainPrevKey)
+	if len(md) == 0 {
+		return nil
+	}
+	return md
+}
+
+// ChainHash returns the hex-encoded hash of the given record linked to the previous record hash.
+//
+// The hash is computed over the serialized events, chaining metadata excluded, thus events must have their final global versions.
+func ChainHash(ctx context.Context, ser Serializer, prev string, record []Envelope) (string, error) {
+	envs := make([]Envelope, 0, len(record))
+	for _, env := range record {
+		envs = append(envs, unchained{env})
+	}
+	b, err := ser.MarshalEventBatch(ctx, envs)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(prev))
+	h.Write(b)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Chain computes the hash of the given record, and sets it alongside the previous hash in the metadata of the last event.
+// It returns the record hash.
+func Chain(ctx context.Context, ser Serializer, prev string, record []Envelope) (string, error) {
+	if len(record) == 0 {
+		return prev, nil
+	}
+
+	last, ok := record[len(record)-1].(interface {
+		Envelope
+		SetMetadata(md map[string]string) Envelope
+	})
+	if !ok {
+		return "", errors.Err(ErrChainRecordFailed, record[len(record)-1].StreamID(), "envelope metadata is read-only")
+	}
+
+	hash, err := ChainHash(ctx, ser, prev, record)
+	if err != nil {
+		return "", errors.Err(ErrChainRecordFailed, last.StreamID(), err)
+	}
+
+	md := maps.Clone(last.Metadata())
+	if md == nil {
+		md = make(map[string]string)
+	}
+	md[MetadataChainHashKey] = hash
+	md[MetadataChainPrevKey] = prev
+	last.SetMetadata(md)
+
+	return hash, nil
+}
+
+// ChainVerifier is implemented by stores that support hash chaining of records.
+type ChainVerifier interface {
+	// Verify walks the global stream of the given stream ID, within the given range of global versions,
+	// and returns an ErrHashChainBroken error which wraps a ChainBreak describing the first broken link.
+	Verify(ctx context.Context, id StreamID, vrange ...Version) error
+}
+
+// chainPageSize defines the count of records replayed at once during verification.
+const chainPageSize uint = 500
+
+// VerifyChain walks the global stream of the given stream ID using Replay, and checks the hash chain of the records
+// within the given range of global versions. It returns an ErrHashChainBroken error wrapping a ChainBreak at the first broken link.
+//
+// The replayer must deliver records as stored: events hidden on read (ex: expired, not retained or soft deleted ones)
+// would be reported as broken links. The link of the first record in range to its predecessor is not checked.
+// Note that removing records (ex: purged, truncated or deleted ones) breaks the chain.
+func VerifyChain(ctx context.Context, replayer StreamReplayer, ser Serializer, id StreamID, vrange ...Version) error {
+	globalID := NewStreamID(id.GlobalID())
+	from, to := VersionRange(vrange)
+
+	var (
+		prev    string
+		started bool
+		record  []Envelope
+		broken  error
+	)
+
+	check := func() bool {
+		if len(record) == 0 {
+			return true
+		}
+		ver := record[0].GlobalVersion().Trunc()
+		md := record[len(record)-1].Metadata()
+		hash, ok := md[MetadataChainHashKey]
+		if !ok {
+			broken = errors.Err(ErrHashChainBroken, globalID.String(), ChainBreak{Record: ver, Reason: "missing hash"})
+			return false
+		}
+		if started && md[MetadataChainPrevKey] != prev {
+			broken = errors.Err(ErrHashChainBroken, globalID.String(), ChainBreak{Record: ver, Reason: "previous hash mismatch"})
+			return false
+		}
+		computed, err := ChainHash(ctx, ser, md[MetadataChainPrevKey], record)
+		if err != nil {
+			broken = err
+			return false
+		}
+		if computed != hash {
+			broken = errors.Err(ErrHashChainBroken, globalID.String(), ChainBreak{Record: ver, Reason: "hash mismatch"})
+			return false
+		}
+		prev, started, record = hash, true, nil
+		return true
+	}
+
+	errStop := errors.New("hash chain verification stopped")
+	for {
+		records := uint(0)
+		lastRecord := VersionZero
+		err := replayer.Replay(ctx, globalID, StreamReplayQuery{From: from, RecordLimit: chainPageSize}, func(ctx context.Context, data StreamData) error {
+			if data.Type != StreamDataTypeRecord {
+				return nil
+			}
+			env := data.Value.(Envelope)
+			if ver := env.GlobalVersion().Trunc(); !ver.Equal(lastRecord) {
+				if !check() || ver.After(to.Trunc()) {
+					return errStop
+				}
+				records++
+				lastRecord = ver
+			}
+			record = append(record, env)
+			return nil
+		})
+		if err != nil && !errors.ErrIs(err, errStop) {
+			return err
+		}
+		// pages end at record boundaries
+		if err == nil {
+			check()
+		}
+		if broken != nil {
+			return broken
+		}
+		if err != nil || records < chainPageSize {
+			return nil
+		}
+		from = lastRecord.Incr()
+	}
+}
@@ -1,6 +1,9 @@
 package event
 
-import "context"
+import (
+	"context"
+	"maps"
+)
 
 type ContextKey string
 
@@ -9,14 +12,23 @@ func (c ContextKey) String() string {
 }
 
 var (
-	ContextNamespaceKey = ContextKey("namespace")
-	ContextUserKey      = ContextKey("user")
-	ContextIPAddrKey    = ContextKey("ip_addr")
+	ContextNamespaceKey     = ContextKey("namespace")
+	ContextUserKey          = ContextKey("user")
+	ContextIPAddrKey        = ContextKey("ip_addr")
+	ContextCorrelationIDKey = ContextKey("correlation_id")
+	ContextCausationIDKey   = ContextKey("causation_id")
+	ContextTraceIDKey       = ContextKey("trace_id")
+	ContextMetadataKey      = ContextKey("metadata")
 )
 
 type ContextValues struct {
-	User   string
-	IPAddr string
+	User          string
+	IPAddr        string
+	CorrelationID string
+	CausationID   string
+	TraceID       string
+	// Metadata entries are merged with the ones already found in the context.
+	Metadata map[string]string
 }
 
 func ContextWith(ctx context.Context, values ContextValues) context.Context {
@@ -26,5 +38,54 @@ func ContextWith(ctx context.Context, values ContextValues) context.Context {
 	if values.IPAddr != "" {
 		ctx = context.WithValue(ctx, ContextIPAddrKey, values.IPAddr)
 	}
+	if values.CorrelationID != "" {
+		ctx = context.WithValue(ctx, ContextCorrelationIDKey, values.CorrelationID)
+	}
+	if values.CausationID != "" {
+		ctx = context.WithValue(ctx, ContextCausationIDKey, values.CausationID)
+	}
+	if values.TraceID != "" {
+		ctx = context.WithValue(ctx, ContextTraceIDKey, values.TraceID)
+	}
+	if len(values.Metadata) > 0 {
+		md := maps.Clone(contextMetadata(ctx))
+		if md == nil {
+			md = make(map[string]string, len(values.Metadata))
+		}
+		maps.Copy(md, values.Metadata)
+		ctx = context.WithValue(ctx, ContextMetadataKey, md)
+	}
 	return ctx
 }
+
+// ContextWithCause returns a context which makes the events wrapped within it caused by the given envelope.
+//
+// The causation ID is set to the envelope ID, while the correlation and trace IDs are inherited from the envelope.
+// The envelope ID is used as the correlation ID if the envelope is the first one of the business flow.
+func ContextWithCause(ctx context.Context, env Envelope) context.Context {
+	correlationID := env.CorrelationID()
+	if correlationID == "" {
+		correlationID = env.ID()
+	}
+	return ContextWith(ctx, ContextValues{
+		CorrelationID: correlationID,
+		CausationID:   env.ID(),
+		TraceID:       env.TraceID(),
+	})
+}
+
+func contextString(ctx context.Context, key ContextKey) string {
+	if ctx.Value(key) == nil {
+		return ""
+	}
+	str, _ := ctx.Value(key).(string)
+	return str
+}
+
+func contextMetadata(ctx context.Context) map[string]string {
+	if ctx.Value(ContextMetadataKey) == nil {
+		return nil
+	}
+	md, _ := ctx.Value(ContextMetadataKey).(map[string]string)
+	return md
+}
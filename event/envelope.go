@@ -2,6 +2,8 @@ package event
 
 import (
 	"context"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/ln80/event-store/event/errors"
@@ -21,6 +23,12 @@ type Envelope interface {
 	IPAddr() string
 	Dests() []string
 	TTL() time.Duration
+	CorrelationID() string
+	CausationID() string
+	TraceID() string
+	// Metadata returns the envelope headers that don't belong to the domain event,
+	// ex: source service, client app or request ID. The returned map must not be modified.
+	Metadata() map[string]string
 }
 
 // RWEnvelope defines an envelope with some properties setter methods.
@@ -32,6 +40,10 @@ type RWEnvelope interface {
 	SetVersion(v Version) Envelope
 	SetDests(dests []string) Envelope
 	SetTTL(ttl time.Duration) Envelope
+	SetCorrelationID(id string) Envelope
+	SetCausationID(id string) Envelope
+	SetTraceID(id string) Envelope
+	SetMetadata(md map[string]string) Envelope
 }
 
 // GlobalVersionSetter defines an envelope with a global version setter.
@@ -40,12 +52,24 @@ type GlobalVersionSetter interface {
 	SetGlobalVersion(v Version) Envelope
 }
 
+// VersionSetter defines an envelope with a version setter.
+type VersionSetter interface {
+	Envelope
+	SetVersion(v Version) Envelope
+}
+
 // NamespaceSetter defines an envelope with a namespace setter.
 type NamespaceSetter interface {
 	Envelope
 	SetNamespace(namespace string) Envelope
 }
 
+// TraceIDSetter defines an envelope with a trace ID setter.
+type TraceIDSetter interface {
+	Envelope
+	SetTraceID(id string) Envelope
+}
+
 // MustGlobalVersionSetter asserts that the given envelope implements GlobalVersionSetter interface.
 func MustGlobalVersionSetter(env Envelope) GlobalVersionSetter {
 	rev, ok := env.(GlobalVersionSetter)
@@ -104,6 +128,22 @@ func WithNameSpace(namespace string) EnvelopeOption {
 	}
 }
 
+// WithMetadata adds the given entries to the envelope metadata.
+// They override the metadata defaults found in the context.
+func WithMetadata(md map[string]string) EnvelopeOption {
+	return func(env RWEnvelope) {
+		if len(md) == 0 {
+			return
+		}
+		merged := maps.Clone(env.Metadata())
+		if merged == nil {
+			merged = make(map[string]string, len(md))
+		}
+		maps.Copy(merged, md)
+		env.SetMetadata(merged)
+	}
+}
+
 func WithGlobalVersionIncr(startingVer Version, limit int, diff VersionSequenceDiff) EnvelopeOption {
 	ver := startingVer
 	count := 0
@@ -165,6 +205,14 @@ func Wrap(ctx context.Context, stmID StreamID, events []any, opts ...EnvelopeOpt
 			}
 		}
 
+		env.SetCorrelationID(contextString(ctx, ContextCorrelationIDKey))
+		env.SetCausationID(contextString(ctx, ContextCausationIDKey))
+		env.SetTraceID(contextString(ctx, ContextTraceIDKey))
+
+		if md := contextMetadata(ctx); len(md) > 0 {
+			env.SetMetadata(maps.Clone(md))
+		}
+
 		for _, opt := range opts {
 			if opt == nil {
 				continue
@@ -183,6 +231,29 @@ func Wrap(ctx context.Context, stmID StreamID, events []any, opts ...EnvelopeOpt
 	return envs
 }
 
+// CopyEnvelope returns a copy of the given envelope, which can be updated without altering the original one.
+// Note that the event itself is shared by both envelopes.
+func CopyEnvelope(env Envelope) Envelope {
+	return &envelope{
+		streamID:       env.StreamID(),
+		eID:            env.ID(),
+		eType:          env.Type(),
+		event:          env.Event(),
+		at:             env.At(),
+		version:        env.Version(),
+		globalStreamID: env.GlobalStreamID(),
+		globalVersion:  env.GlobalVersion(),
+		user:           env.User(),
+		ipAddr:         env.IPAddr(),
+		dests:          slices.Clone(env.Dests()),
+		ttl:            env.TTL(),
+		correlationID:  env.CorrelationID(),
+		causationID:    env.CausationID(),
+		traceID:        env.TraceID(),
+		metadata:       maps.Clone(env.Metadata()),
+	}
+}
+
 // envelope presents the internal Envelope implementation it usually has
 // more capabilities comparing to the encoding format related ones.
 type envelope struct {
@@ -199,6 +270,10 @@ type envelope struct {
 	dests          []string
 	ttl            time.Duration
 	namespace      string
+	correlationID  string
+	causationID    string
+	traceID        string
+	metadata       map[string]string
 }
 
 var _ Envelope = &envelope{}
@@ -264,6 +339,26 @@ func (e *envelope) TTL() time.Duration {
 	return e.ttl
 }
 
+// CorrelationID implements the CorrelationID method of the Envelope interface
+func (e *envelope) CorrelationID() string {
+	return e.correlationID
+}
+
+// CausationID implements the CausationID method of the Envelope interface
+func (e *envelope) CausationID() string {
+	return e.causationID
+}
+
+// TraceID implements the TraceID method of the Envelope interface
+func (e *envelope) TraceID() string {
+	return e.traceID
+}
+
+// Metadata implements the Metadata method of the Envelope interface
+func (e *envelope) Metadata() map[string]string {
+	return e.metadata
+}
+
 // SetAt implements the SetAt method of the RWEnvelope interface.
 func (e *envelope) SetAt(t time.Time) Envelope {
 	e.at = t
@@ -311,6 +406,30 @@ func (e *envelope) SetIPAddr(ipAddr string) Envelope {
 	return e
 }
 
+// SetCorrelationID implements the SetCorrelationID method of the RWEnvelop interface
+func (e *envelope) SetCorrelationID(id string) Envelope {
+	e.correlationID = id
+	return e
+}
+
+// SetCausationID implements the SetCausationID method of the RWEnvelop interface
+func (e *envelope) SetCausationID(id string) Envelope {
+	e.causationID = id
+	return e
+}
+
+// SetTraceID implements the SetTraceID method of the RWEnvelop interface
+func (e *envelope) SetTraceID(id string) Envelope {
+	e.traceID = id
+	return e
+}
+
+// SetMetadata implements the SetMetadata method of the RWEnvelop interface
+func (e *envelope) SetMetadata(md map[string]string) Envelope {
+	e.metadata = md
+	return e
+}
+
 var _ Transformer = &envelope{}
 
 func (e *envelope) Transform(fn func(any) any) {
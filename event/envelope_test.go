@@ -91,6 +91,67 @@ func TestEnvelope(t *testing.T) {
 		}
 	})
 
+	t.Run("with tracing context values", func(t *testing.T) {
+		ctx := ContextWith(ctx, ContextValues{
+			CorrelationID: "corr-1",
+			TraceID:       "trace-1",
+		})
+
+		cause := Wrap(ctx, stmID, []any{&Event{Val: "1"}})[0]
+		if want, got := "corr-1", cause.CorrelationID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := "", cause.CausationID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		evts := Wrap(ContextWithCause(context.Background(), cause), stmID, []any{&Event{Val: "2"}, &Event2{Val: "3"}})
+		for _, evt := range evts {
+			if want, got := "corr-1", evt.CorrelationID(); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+			if want, got := cause.ID(), evt.CausationID(); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+			if want, got := "trace-1", evt.TraceID(); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+
+		// the cause envelope starts the business flow
+		cause = Wrap(context.Background(), stmID, []any{&Event{Val: "1"}})[0]
+		evt := Wrap(ContextWithCause(context.Background(), cause), stmID, []any{&Event{Val: "2"}})[0]
+		if want, got := cause.ID(), evt.CorrelationID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("with metadata", func(t *testing.T) {
+		ctx := ContextWith(ctx, ContextValues{Metadata: map[string]string{"source": "svc", "app": "web"}})
+		ctx = ContextWith(ctx, ContextValues{Metadata: map[string]string{"requestID": "req-1"}})
+
+		evts := Wrap(ctx, stmID, []any{&Event{Val: "1"}, &Event2{Val: "2"}},
+			WithMetadata(map[string]string{"app": "mobile"}),
+		)
+		want := map[string]string{"source": "svc", "app": "mobile", "requestID": "req-1"}
+		for _, evt := range evts {
+			if got := evt.Metadata(); !reflect.DeepEqual(want, got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+
+		// context defaults are left untouched
+		if want, got := "web", contextMetadata(ctx)["app"]; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// metadata are not shared between envelopes
+		evts[0].Metadata()["source"] = "altered"
+		if want, got := "svc", evts[1].Metadata()["source"]; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
 	t.Run("with options", func(t *testing.T) {
 		events := []any{
 			&Event{
@@ -280,4 +341,34 @@ func TestEnvelope(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("copy", func(t *testing.T) {
+		env := Wrap(ctx, stmID, []any{&Event{Val: "1"}}, WithMetadata(map[string]string{"k": "v"}))[0]
+		env.(RWEnvelope).SetDests([]string{"dest"})
+		MustGlobalVersionSetter(env).SetGlobalVersion(NewVersion().Add(1, 0))
+
+		cp := CopyEnvelope(env)
+		if want, got := env.ID(), cp.ID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := env.Type(), cp.Type(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := env.GlobalVersion(), cp.GlobalVersion(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := env.Metadata(), cp.Metadata(); !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// updating the copy must not alter the original envelope
+		MustGlobalVersionSetter(cp).SetGlobalVersion(NewVersion().Add(2, 0))
+		cp.(RWEnvelope).SetDests(nil)
+		if want, got := NewVersion().Add(1, 0), env.GlobalVersion(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := []string{"dest"}, env.Dests(); !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
 }
@@ -0,0 +1,152 @@
+package event
+
+import (
+	"fmt"
+
+	"github.com/ln80/event-store/event/errors"
+)
+
+var (
+	ErrStreamAlreadyExists  = errors.New("stream already exists")
+	ErrStreamNotFound       = errors.New("stream not found")
+	ErrWrongExpectedVersion = errors.New("wrong expected version")
+	ErrRebaseVersionFailed  = errors.New("rebase version failed")
+)
+
+// ExpectedVersionMode defines how the stream current version is checked before appending a chunk of events.
+type ExpectedVersionMode uint8
+
+const (
+	// ExpectAny appends the chunk regardless of the stream current version.
+	ExpectAny ExpectedVersionMode = iota + 1
+	// ExpectNoStream appends the chunk only if the stream does not exist yet.
+	ExpectNoStream
+	// ExpectStreamExists appends the chunk only if the stream already exists.
+	ExpectStreamExists
+	// ExpectExact appends the chunk only if the stream current version equals the expected one.
+	ExpectExact
+)
+
+// ExpectedVersion presents the stream state expected by a versioned append.
+//
+// Once defined, the chunk versions are rebased to directly follow the stream current version,
+// thus callers don't have to compute versions themselves.
+type ExpectedVersion struct {
+	Mode ExpectedVersionMode
+	// Version is only considered in the case of ExpectExact mode.
+	Version Version
+}
+
+var (
+	AnyVersion   = ExpectedVersion{Mode: ExpectAny}
+	NoStream     = ExpectedVersion{Mode: ExpectNoStream}
+	StreamExists = ExpectedVersion{Mode: ExpectStreamExists}
+)
+
+// ExactVersion returns an expected version that matches the given stream current version.
+// Note that ExactVersion(VersionZero) is similar to NoStream.
+func ExactVersion(v Version) ExpectedVersion {
+	return ExpectedVersion{Mode: ExpectExact, Version: v}
+}
+
+// WithExpectedVersion returns an append option which defines the stream expected version.
+func WithExpectedVersion(ev ExpectedVersion) func(*AppendConfig) {
+	return func(cfg *AppendConfig) {
+		cfg.ExpectedVersion = &ev
+	}
+}
+
+func (ev ExpectedVersion) String() string {
+	switch ev.Mode {
+	case ExpectAny:
+		return "any"
+	case ExpectNoStream:
+		return "no stream"
+	case ExpectStreamExists:
+		return "stream exists"
+	case ExpectExact:
+		return "exact " + ev.Version.String()
+	default:
+		return fmt.Sprintf("unknown mode %d", ev.Mode)
+	}
+}
+
+// Check returns an error if the given stream current version does not match the expected one.
+//
+// The returned error wraps ErrAppendEventsConflict, one of ErrStreamAlreadyExists, ErrStreamNotFound
+// or ErrWrongExpectedVersion, and a VersionConflict which contains the actual version.
+func (ev ExpectedVersion) Check(streamID string, actual Version) error {
+	var err errors.Error
+	switch ev.Mode {
+	case ExpectAny:
+		return nil
+	case ExpectNoStream:
+		if actual.IsZero() {
+			return nil
+		}
+		err = ErrStreamAlreadyExists
+	case ExpectStreamExists:
+		if !actual.IsZero() {
+			return nil
+		}
+		err = ErrStreamNotFound
+	case ExpectExact:
+		if actual.Equal(ev.Version) {
+			return nil
+		}
+		err = ErrWrongExpectedVersion
+	default:
+		return errors.Err(ErrUnsupportedAppendOption, streamID, "invalid expected version "+ev.String())
+	}
+
+	return errors.Err(ErrAppendEventsConflict, streamID,
+		errors.Err(err, streamID, VersionConflict{Expected: ev, Actual: actual}))
+}
+
+// VersionConflict contains the details of an expected version mismatch.
+// It allows callers to resolve the conflict using the actual stream version.
+type VersionConflict struct {
+	Expected ExpectedVersion
+	Actual   Version
+}
+
+// Error implements error interface.
+func (c VersionConflict) Error() string {
+	return "expected " + c.Expected.String() + ", actual version " + c.Actual.String()
+}
+
+// RebaseVersions shifts the integer part of the given chunk versions so that the chunk directly follows
+// the given current version. Fractional parts are kept as is.
+//
+// Events must support version update (ex: created using Wrap), otherwise an error is returned.
+func RebaseVersions(events []Envelope, current Version) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	first := events[0].Version().Trunc()
+	next := current.Trunc().Incr()
+	if first.Equal(next) {
+		return nil
+	}
+
+	for _, env := range events {
+		rwEnv, ok := env.(interface {
+			SetVersion(v Version) Envelope
+		})
+		if !ok {
+			return errors.Err(ErrRebaseVersionFailed, env.StreamID(), fmt.Sprintf("envelope %T does not support version update", env))
+		}
+		ver := env.Version()
+		if ver.Before(first) {
+			return errors.Err(ErrRebaseVersionFailed, env.StreamID(), "unsorted versions "+ver.String())
+		}
+		rebased := next.Add(ver.p-first.p, ver.f)
+		if ver.eof {
+			rebased = rebased.EOF()
+		}
+		rwEnv.SetVersion(rebased)
+	}
+
+	return nil
+}
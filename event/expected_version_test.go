@@ -0,0 +1,84 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestExpectedVersion_Check(t *testing.T) {
+	cur := VersionMin.Add(1, 2).EOF()
+
+	tcs := []struct {
+		ev     ExpectedVersion
+		actual Version
+		err    error
+	}{
+		{ev: AnyVersion, actual: VersionZero},
+		{ev: AnyVersion, actual: cur},
+		{ev: NoStream, actual: VersionZero},
+		{ev: NoStream, actual: cur, err: ErrStreamAlreadyExists},
+		{ev: StreamExists, actual: cur},
+		{ev: StreamExists, actual: VersionZero, err: ErrStreamNotFound},
+		{ev: ExactVersion(cur), actual: cur},
+		{ev: ExactVersion(VersionZero), actual: VersionZero},
+		{ev: ExactVersion(VersionMin), actual: cur, err: ErrWrongExpectedVersion},
+		{ev: ExpectedVersion{}, actual: cur, err: ErrUnsupportedAppendOption},
+	}
+	for i, tc := range tcs {
+		err := tc.ev.Check("stmID", tc.actual)
+		if tc.err == nil {
+			if err != nil {
+				t.Fatalf("%d: expect err be nil, got %v", i, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("%d: expect %v, %v be equals", i, tc.err, err)
+		}
+		if tc.err == ErrUnsupportedAppendOption {
+			continue
+		}
+		if !errors.Is(err, ErrAppendEventsConflict) {
+			t.Fatalf("%d: expect %v, %v be equals", i, ErrAppendEventsConflict, err)
+		}
+		var conflict VersionConflict
+		if !errors.As(err, &conflict) {
+			t.Fatalf("%d: expect version conflict be found in %v", i, err)
+		}
+		if want, got := tc.actual, conflict.Actual; want != got {
+			t.Fatalf("%d: expect %v, %v be equals", i, want, got)
+		}
+	}
+}
+
+func TestRebaseVersions(t *testing.T) {
+	ctx := context.Background()
+	stmID := NewStreamID("tenantID")
+
+	envs := Wrap(ctx, stmID, []any{&Event{Val: "1"}, &Event2{Val: "2"}, &Event{Val: "3"}},
+		WithVersionIncr(VersionMin, 3, VersionSeqDiffPart))
+	if err := RebaseVersions(envs, VersionMin.Add(4, 1).EOF()); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	want := []Version{VersionMin.Add(5, 0), VersionMin.Add(6, 0), VersionMin.Add(7, 0).EOF()}
+	for i, env := range envs {
+		if want, got := want[i], env.Version(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	// the fractional parts are kept as is
+	envs = Wrap(ctx, stmID, []any{&Event{Val: "1"}, &Event2{Val: "2"}},
+		WithVersionIncr(VersionMin.Add(9, 0), 2, VersionSeqDiffFracPart))
+	if err := RebaseVersions(envs, VersionZero); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	want = []Version{VersionMin, VersionMin.Add(0, 1).EOF()}
+	for i, env := range envs {
+		if want, got := want[i], env.Version(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/ln80/event-store/event/errors"
+)
+
+var (
+	ErrPurgeExpiredFailed = errors.New("purge expired events failed")
+)
+
+// Expired returns true if the event TTL is elapsed at the given time.
+func Expired(env Envelope, at time.Time) bool {
+	return env.TTL() > 0 && env.At().Add(env.TTL()).Before(at)
+}
+
+// ExpiryPurger is implemented by stores that are able to physically purge expired events.
+type ExpiryPurger interface {
+	// PurgeExpired removes the records whose events are all expired at the given time. It returns the count of purged events.
+	//
+	// The last record of a stream is kept even if expired, it preserves the stream head and prevents versions reuse.
+	// Partially expired records are kept as well, their expired events are filtered on read.
+	PurgeExpired(ctx context.Context, at time.Time) (int, error)
+}
+
+// ReaperConfig presents the expiry reaper configuration.
+type ReaperConfig struct {
+	// Interval defines the delay between two purges.
+	Interval time.Duration
+}
+
+// Reaper periodically purges expired events from a store.
+type Reaper struct {
+	purger ExpiryPurger
+	cfg    *ReaperConfig
+}
+
+// NewReaper returns an expiry reaper of the given store.
+func NewReaper(purger ExpiryPurger, opts ...func(*ReaperConfig)) *Reaper {
+	cfg := &ReaperConfig{
+		Interval: time.Minute,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	return &Reaper{
+		purger: purger,
+		cfg:    cfg,
+	}
+}
+
+// Run purges expired events until the context is canceled.
+// It returns nil once the context is canceled, or the first purge failure.
+func (r *Reaper) Run(ctx context.Context) error {
+	for {
+		if _, err := r.purger.PurgeExpired(ctx, time.Now().UTC()); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.cfg.Interval):
+		}
+	}
+}
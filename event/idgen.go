@@ -0,0 +1,203 @@
+package event
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ln80/event-store/event/errors"
+)
+
+// stringID implements the ID interface.
+type stringID string
+
+// String implements ID interface.
+func (id stringID) String() string {
+	return string(id)
+}
+
+// IDTime returns the timestamp embedded in the given ID, using the default generator.
+// It fails if the default generator does not implement IDTimestamper.
+func IDTime(id string) (time.Time, error) {
+	ts, ok := DefaultIDGenerator().(IDTimestamper)
+	if !ok {
+		return time.Time{}, errors.Err(ErrInvalidID, "", "default ID generator does not embed timestamps")
+	}
+	return ts.Time(id)
+}
+
+func randomBytes(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+}
+
+// monotonic generates 128-bit values made of a 48-bit millisecond timestamp followed by a random part.
+// Within the same millisecond, the random part of the last value is incremented, which makes values strictly increasing.
+// It also prevents values from going backward in the case of a clock drift.
+type monotonic struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	hiBits uint // count of random bits on top of the 64 lower ones
+
+	ms     uint64
+	hi, lo uint64
+}
+
+func (m *monotonic) next() (ms, hi, lo uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ms := uint64(m.now().UnixMilli()); ms > m.ms {
+		m.ms = ms
+		m.reset()
+		return m.ms, m.hi, m.lo
+	}
+
+	// increment the random part, move to the next millisecond on overflow
+	m.lo++
+	if m.lo == 0 {
+		m.hi++
+		if m.hi == 1<<m.hiBits {
+			m.ms++
+			m.reset()
+		}
+	}
+
+	return m.ms, m.hi, m.lo
+}
+
+// reset sets a new random part, its most significant bit is cleared to leave room for increments.
+func (m *monotonic) reset() {
+	var b [16]byte
+	randomBytes(b[:])
+	m.hi = binary.BigEndian.Uint64(b[:8]) & (1<<(m.hiBits-1) - 1)
+	m.lo = binary.BigEndian.Uint64(b[8:])
+}
+
+type uuidV4Generator struct{}
+
+// UUIDv4Generator returns a generator of random UUIDs (version 4).
+func UUIDv4Generator() IDGenerator {
+	return uuidV4Generator{}
+}
+
+// NewID implements IDGenerator interface.
+func (uuidV4Generator) NewID() ID {
+	var b [16]byte
+	randomBytes(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return stringID(formatUUID(b))
+}
+
+type uuidV7Generator struct {
+	m *monotonic
+}
+
+// UUIDv7Generator returns a generator of time-ordered UUIDs (version 7), monotonic within the same millisecond.
+func UUIDv7Generator() IDGenerator {
+	// rand_a (12 bits) and rand_b (62 bits) make a 74-bit random part
+	return uuidV7Generator{m: &monotonic{now: time.Now, hiBits: 10}}
+}
+
+// NewID implements IDGenerator interface.
+func (g uuidV7Generator) NewID() ID {
+	ms, hi, lo := g.m.next()
+
+	randA := hi<<2 | lo>>62
+	randB := lo & (1<<62 - 1)
+
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], ms<<16|0x7000|randA)
+	binary.BigEndian.PutUint64(b[8:], 0x8000000000000000|randB)
+	return stringID(formatUUID(b))
+}
+
+// Time implements IDTimestamper interface.
+func (uuidV7Generator) Time(id string) (time.Time, error) {
+	b, err := parseUUID(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if b[6]>>4 != 7 {
+		return time.Time{}, errors.Err(ErrInvalidID, "", "not a UUID version 7: "+id)
+	}
+	ms := binary.BigEndian.Uint64(b[:8]) >> 16
+	return time.UnixMilli(int64(ms)).UTC(), nil
+}
+
+func formatUUID(b [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
+
+func parseUUID(id string) (b [16]byte, err error) {
+	if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[18] != '-' || id[23] != '-' {
+		return b, errors.Err(ErrInvalidID, "", "invalid UUID: "+id)
+	}
+	if _, err := hex.Decode(b[:], []byte(strings.ReplaceAll(id, "-", ""))); err != nil {
+		return b, errors.Err(ErrInvalidID, "", err)
+	}
+	return b, nil
+}
+
+// crockford is the Base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+type ulidGenerator struct {
+	m *monotonic
+}
+
+// ULIDGenerator returns a generator of ULIDs (https://github.com/ulid/spec), monotonic within the same millisecond.
+func ULIDGenerator() IDGenerator {
+	return ulidGenerator{m: &monotonic{now: time.Now, hiBits: 16}}
+}
+
+// NewID implements IDGenerator interface.
+func (g ulidGenerator) NewID() ID {
+	ms, hi, lo := g.m.next()
+
+	// encode the 128-bit value, prefixed with 2 padding bits, using 26 characters of 5 bits.
+	bhi := ms<<16 | hi
+	blo := lo
+	var buf [26]byte
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = crockford[blo&0x1f]
+		blo = blo>>5 | bhi<<59
+		bhi >>= 5
+	}
+	return stringID(buf[:])
+}
+
+// Time implements IDTimestamper interface.
+func (ulidGenerator) Time(id string) (time.Time, error) {
+	if len(id) != 26 {
+		return time.Time{}, errors.Err(ErrInvalidID, "", "invalid ULID: "+id)
+	}
+	// the first 10 characters hold the 2 padding bits followed by the 48-bit timestamp
+	ms := uint64(0)
+	for _, c := range strings.ToUpper(id[:10]) {
+		i := strings.IndexRune(crockford, c)
+		if i < 0 {
+			return time.Time{}, errors.Err(ErrInvalidID, "", "invalid ULID: "+id)
+		}
+		ms = ms<<5 | uint64(i)
+	}
+	if ms >= 1<<48 {
+		return time.Time{}, errors.Err(ErrInvalidID, "", "invalid ULID: "+id)
+	}
+	return time.UnixMilli(int64(ms)).UTC(), nil
+}
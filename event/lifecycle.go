@@ -0,0 +1,52 @@
+package event
+
+import (
+	"context"
+
+	"github.com/ln80/event-store/event/errors"
+)
+
+var (
+	ErrStreamDeleted         = errors.New("stream deleted")
+	ErrStreamArchived        = errors.New("stream archived")
+	ErrStreamLifecycleFailed = errors.New("stream lifecycle operation failed")
+)
+
+// StreamStatus presents the lifecycle status of a stream.
+type StreamStatus string
+
+const (
+	// StreamActive is the default status of a stream.
+	StreamActive StreamStatus = "active"
+	// StreamSoftDeleted streams are hidden from reads, and closed for appends until they are restored.
+	StreamSoftDeleted StreamStatus = "soft_deleted"
+	// StreamDeleted streams are definitely closed, only their tombstone is kept.
+	StreamDeleted StreamStatus = "deleted"
+	// StreamArchived streams are moved to a cold store, and closed for appends.
+	StreamArchived StreamStatus = "archived"
+)
+
+// StreamTombstone is the event appended to a stream once it's hard deleted.
+// It remains visible on replay and query, which allows consumers (ex: projections) to clean up their own state.
+type StreamTombstone struct{}
+
+// StreamLifecycle defines the stream-level lifecycle operations.
+//
+// Lifecycle operations only apply to the given stream, sub-streams are left untouched.
+type StreamLifecycle interface {
+	// Status returns the lifecycle status of the stream.
+	Status(ctx context.Context, id StreamID) (StreamStatus, error)
+	// SoftDelete hides the stream from load, replay and query, and blocks further appends.
+	// Events are kept, and the stream can be restored.
+	SoftDelete(ctx context.Context, id StreamID) error
+	// Restore makes a soft deleted stream active again.
+	Restore(ctx context.Context, id StreamID) error
+	// HardDelete removes the stream events, and appends a tombstone which blocks further appends.
+	HardDelete(ctx context.Context, id StreamID) error
+	// TruncateBefore removes the stream records before the given version (ex: the version of the last snapshot).
+	// The last record is always kept to preserve the stream sequence.
+	TruncateBefore(ctx context.Context, id StreamID, ver Version) error
+	// Archive moves the stream events to the given cold store, and blocks further appends.
+	// Archived events are still loadable through the current store, but they are excluded from replay and query.
+	Archive(ctx context.Context, id StreamID, cold Store) error
+}
@@ -0,0 +1,57 @@
+package event
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ln80/event-store/event/errors"
+)
+
+// SizeLimit defines the maximum size in bytes of a record (aka chunk of events) and of each of its events.
+// Sizes are measured using the store serializer. A zero value means no limit.
+type SizeLimit struct {
+	Record int
+	Event  int
+}
+
+// IsZero returns true if no limit is defined.
+func (l SizeLimit) IsZero() bool {
+	return l.Record <= 0 && l.Event <= 0
+}
+
+// CheckSizeLimit measures the given record and its events using the serializer.
+// It returns ErrEventSizeLimitExceeded if any limit is exceeded.
+//
+// Note that sizes are measured before the store assigns global versions, thus they might slightly differ
+// from the persisted ones.
+func CheckSizeLimit(ctx context.Context, ser Serializer, id StreamID, events []Envelope, limit SizeLimit) error {
+	if len(events) == 0 || limit.IsZero() {
+		return nil
+	}
+
+	if limit.Event > 0 {
+		for _, env := range events {
+			b, err := ser.MarshalEvent(ctx, env)
+			if err != nil {
+				return errors.Err(ErrAppendEventsFailed, id.String(), err)
+			}
+			if len(b) > limit.Event {
+				return errors.Err(ErrEventSizeLimitExceeded, id.String(),
+					fmt.Sprintf("event %s size %d exceeds %d bytes", env.ID(), len(b), limit.Event))
+			}
+		}
+	}
+
+	if limit.Record > 0 {
+		b, err := ser.MarshalEventBatch(ctx, events)
+		if err != nil {
+			return errors.Err(ErrAppendEventsFailed, id.String(), err)
+		}
+		if len(b) > limit.Record {
+			return errors.Err(ErrEventSizeLimitExceeded, id.String(),
+				fmt.Sprintf("record size %d exceeds %d bytes", len(b), limit.Record))
+		}
+	}
+
+	return nil
+}
@@ -27,6 +27,15 @@ func WithAliases(aliases ...string) func(registryEntryProps) {
 	}
 }
 
+// WithRevision defines the current schema revision of the event. The revision is persisted by serializers
+// along with the event, it allows to select the upcasters to apply on legacy events.
+// The default revision is zero.
+func WithRevision(rev int) func(registryEntryProps) {
+	return func(rep registryEntryProps) {
+		rep["revision"] = rev
+	}
+}
+
 type registryEntry struct {
 	name  string
 	typ   reflect.Type
@@ -78,6 +87,9 @@ type Register interface {
 	// Get returns a pointer of a zero type's value.
 	Get(name string) (ptr any, err error)
 
+	// Revision returns the current schema revision of the given event type, or zero if not defined.
+	Revision(name string) int
+
 	// GetFromGlobal returns a pointer to equivalent event of the given one from the global registry.
 	GetFromGlobal(evt any) (ptr any, err error)
 
@@ -187,6 +199,24 @@ func (r *register) Get(name string) (any, error) {
 	return reflect.New(entry.typ).Interface(), nil
 }
 
+// Revision implements Revision method of the Register interface.
+func (r *register) Revision(name string) int {
+	regMu.Lock()
+	defer regMu.Unlock()
+
+	entry, ok := registry[r.namespace][name]
+	if !ok && r.namespace != GlobalRegistryName {
+		splits := strings.Split(name, ".")
+		entry, ok = registry[r.namespace][r.namespace+"."+splits[len(splits)-1]]
+	}
+	if !ok {
+		return 0
+	}
+	rev, _ := entry.Property("revision").(int)
+
+	return rev
+}
+
 // GetFromGlobal implements GetFromGlobal methods of the Register interface.
 func (r *register) GetFromGlobal(evt any) (ptr any, err error) {
 	defer func() {
@@ -0,0 +1,93 @@
+package event
+
+import (
+	"context"
+	"time"
+)
+
+// StreamMetadata presents the stream-level metadata, stored alongside the stream.
+//
+// Its retention policy applies to the whole stream, unlike the per-event TTL.
+type StreamMetadata struct {
+	// MaxAge defines the retention period of the stream records. Zero means no limit.
+	MaxAge time.Duration
+	// MaxCount defines the maximum count of retained events. Zero means no limit.
+	MaxCount int
+	// Owner of the stream, ex: a service or a team name.
+	Owner string
+	// ACL presents access control hints, they are not enforced by the store.
+	ACL []string
+	// Properties contains custom properties.
+	Properties map[string]string
+}
+
+// HasRetention returns true if a retention policy is defined.
+func (md StreamMetadata) HasRetention() bool {
+	return md.MaxAge > 0 || md.MaxCount > 0
+}
+
+// Retain returns the given stream events that are retained at the given time according to the retention policy.
+//
+// Records (i.e. events sharing the same global version integer part) are retained or dropped as a whole:
+// a record is retained if its last event is within both the max age and the max count limits.
+// The last record is always retained to preserve the stream sequence. Events must be sorted by global version.
+func (md StreamMetadata) Retain(envs []Envelope, at time.Time) []Envelope {
+	if !md.HasRetention() || len(envs) == 0 {
+		return envs
+	}
+
+	countCut := 0
+	if md.MaxCount > 0 && len(envs) > md.MaxCount {
+		countCut = len(envs) - md.MaxCount
+	}
+	ageCut := time.Time{}
+	if md.MaxAge > 0 {
+		ageCut = at.Add(-md.MaxAge)
+	}
+
+	result := make([]Envelope, 0, len(envs))
+	for i := 0; i < len(envs); {
+		record := envs[i].GlobalVersion().Trunc()
+		j := i
+		for j < len(envs) && envs[j].GlobalVersion().Trunc().Equal(record) {
+			j++
+		}
+		last := envs[j-1]
+		if j == len(envs) || (j-1 >= countCut && !last.At().Before(ageCut)) {
+			result = append(result, envs[i:j]...)
+		}
+		i = j
+	}
+
+	return result
+}
+
+// StreamMetadataStore is implemented by stores that support stream-level metadata.
+type StreamMetadataStore interface {
+	// SetStreamMetadata sets (or replaces) the metadata of the given stream.
+	SetStreamMetadata(ctx context.Context, id StreamID, md StreamMetadata) error
+	// GetStreamMetadata returns the metadata of the given stream, or a zero value if not found.
+	GetStreamMetadata(ctx context.Context, id StreamID) (StreamMetadata, error)
+}
+
+// RetentionCompactor is implemented by stores that are able to physically remove the records
+// which are out of their stream retention policy.
+type RetentionCompactor interface {
+	// CompactRetention removes the records which are not retained at the given time. It returns the count of removed events.
+	//
+	// Similarly to ExpiryPurger, the last record of a stream is kept.
+	CompactRetention(ctx context.Context, at time.Time) (int, error)
+}
+
+// NewCompactor returns a job which periodically compacts the store streams according to their retention policy.
+func NewCompactor(compactor RetentionCompactor, opts ...func(*ReaperConfig)) *Reaper {
+	return NewReaper(purgerFunc(compactor.CompactRetention), opts...)
+}
+
+// purgerFunc allows to use a function as an ExpiryPurger.
+type purgerFunc func(ctx context.Context, at time.Time) (int, error)
+
+// PurgeExpired implements ExpiryPurger interface.
+func (f purgerFunc) PurgeExpired(ctx context.Context, at time.Time) (int, error) {
+	return f(ctx, at)
+}
@@ -30,3 +30,25 @@ type Serializer interface {
 	// Similarly to UnmarshalEvent, events might be nil if event type is not found in the registry.
 	UnmarshalEventBatch(ctx context.Context, b []byte) ([]Envelope, error)
 }
+
+// EventsUnmarshaler is implemented by serializers whose upcasters might split a single event into several ones.
+type EventsUnmarshaler interface {
+	// UnmarshalEvents returns the events derived from the given binary event.
+	// Derived events share the original event versions, it's up to the caller to renumber them
+	// once the rest of the record's events are unmarshaled, using RenumberEvents.
+	UnmarshalEvents(ctx context.Context, b []byte) ([]Envelope, error)
+}
+
+// UnmarshalEvents returns the events derived from the given binary event.
+// It falls back to UnmarshalEvent if the serializer doesn't implement EventsUnmarshaler.
+func UnmarshalEvents(ctx context.Context, ser Serializer, b []byte) ([]Envelope, error) {
+	if u, ok := ser.(EventsUnmarshaler); ok {
+		return u.UnmarshalEvents(ctx, b)
+	}
+
+	env, err := ser.UnmarshalEvent(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	return []Envelope{env}, nil
+}
@@ -0,0 +1,215 @@
+package sourcing
+
+import (
+	"context"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
+)
+
+var (
+	ErrAggregateNotFound = errors.New("aggregate not found")
+)
+
+// Mutator applies the given event to the aggregate state.
+type Mutator[T any] func(state *T, evt any)
+
+// Aggregate wraps an aggregate state, and tracks its uncommitted changes
+// as well as the stream version it was loaded from.
+type Aggregate[T any] struct {
+	id      event.StreamID
+	state   *T
+	version event.Version
+	changes []any
+	mutator Mutator[T]
+
+	// snapshot related fields
+	snapshot      *Snapshot
+	sinceSnapshot int
+}
+
+// ID returns the aggregate stream ID.
+func (a *Aggregate[T]) ID() event.StreamID {
+	return a.id
+}
+
+// State returns the aggregate state.
+func (a *Aggregate[T]) State() *T {
+	return a.state
+}
+
+// Version returns the aggregate stream version, it does not include the uncommitted changes.
+func (a *Aggregate[T]) Version() event.Version {
+	return a.version
+}
+
+// Changes returns the uncommitted changes.
+func (a *Aggregate[T]) Changes() []any {
+	return a.changes
+}
+
+// Apply mutates the aggregate state using the given events, and tracks them as uncommitted changes.
+func (a *Aggregate[T]) Apply(evts ...any) {
+	for _, evt := range evts {
+		a.mutator(a.state, evt)
+		a.changes = append(a.changes, evt)
+	}
+}
+
+// RepositoryConfig presents the repository configuration.
+type RepositoryConfig struct {
+	// ConflictRetries defines how many times Update retries the whole load-handle-save cycle
+	// in the case of an append conflict.
+	ConflictRetries int
+	// Snapshots, if defined, is used to load aggregates on top of their latest snapshot,
+	// and to take new snapshots on save.
+	Snapshots *SnapshotLoader
+	// EnvelopeOptions are passed to Wrap while saving the uncommitted changes.
+	EnvelopeOptions []event.EnvelopeOption
+}
+
+// Repository loads and saves aggregates of type T on top of an event sourcing store.
+type Repository[T any] struct {
+	store   Store
+	mutator Mutator[T]
+
+	cfg *RepositoryConfig
+}
+
+// NewRepository returns an aggregate repository which rehydrates aggregates using the given mutator.
+func NewRepository[T any](store Store, mutator Mutator[T], opts ...func(*RepositoryConfig)) *Repository[T] {
+	cfg := &RepositoryConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	return &Repository[T]{
+		store:   store,
+		mutator: mutator,
+		cfg:     cfg,
+	}
+}
+
+// New returns a new aggregate with an empty state.
+func (r *Repository[T]) New(id event.StreamID) *Aggregate[T] {
+	return &Aggregate[T]{
+		id:      id,
+		state:   new(T),
+		version: event.VersionZero,
+		mutator: r.mutator,
+	}
+}
+
+// Load rehydrates the aggregate from its stream.
+// It returns ErrAggregateNotFound if the stream is empty.
+func (r *Repository[T]) Load(ctx context.Context, id event.StreamID) (*Aggregate[T], error) {
+	agg := r.New(id)
+
+	var (
+		stm *Stream
+		err error
+	)
+	if r.cfg.Snapshots != nil {
+		var snap *Snapshot
+		snap, stm, err = r.cfg.Snapshots.Load(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if snap != nil {
+			switch state := snap.State.(type) {
+			case *T:
+				*agg.state = *state
+			case T:
+				*agg.state = state
+			default:
+				return nil, errors.Err(ErrLoadSnapshotFailed, id.String(), "invalid snapshot state type")
+			}
+			agg.version = snap.Version
+			agg.snapshot = snap
+		}
+	} else {
+		stm, err = r.store.LoadStream(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if stm.Empty() && agg.version.IsZero() {
+		return nil, errors.Err(ErrAggregateNotFound, id.String(), nil)
+	}
+
+	for _, evt := range stm.Unwrap().Events() {
+		r.mutator(agg.state, evt)
+	}
+	if !stm.Empty() {
+		agg.version = stm.Version()
+	}
+	agg.sinceSnapshot = len(stm.Unwrap())
+
+	return agg, nil
+}
+
+// Save appends the aggregate uncommitted changes to its stream.
+// The loaded version is used for optimistic concurrency; event.ErrAppendEventsConflict is returned
+// if the stream has been changed in the meantime.
+func (r *Repository[T]) Save(ctx context.Context, agg *Aggregate[T], opts ...func(*event.AppendConfig)) error {
+	if len(agg.changes) == 0 {
+		return nil
+	}
+
+	stm := Wrap(ctx, agg.id, agg.version, agg.changes, r.cfg.EnvelopeOptions...)
+	if err := r.store.AppendToStream(ctx, stm, opts...); err != nil {
+		return err
+	}
+
+	agg.sinceSnapshot += len(agg.changes)
+	agg.version = stm.Version()
+	agg.changes = nil
+
+	if r.cfg.Snapshots != nil {
+		ok, err := r.cfg.Snapshots.Save(ctx, agg.snapshot, Snapshot{
+			StreamID: agg.id,
+			Version:  agg.version,
+			State:    *agg.state,
+		}, agg.sinceSnapshot)
+		if err != nil {
+			return err
+		}
+		if ok {
+			agg.snapshot = &Snapshot{StreamID: agg.id, Version: agg.version}
+			agg.sinceSnapshot = 0
+		}
+	}
+
+	return nil
+}
+
+// Update loads the aggregate, calls the given handler, and saves the uncommitted changes.
+// In the case of an append conflict, the whole cycle is retried up to ConflictRetries times.
+// A not found aggregate is passed to the handler as a new one.
+func (r *Repository[T]) Update(ctx context.Context, id event.StreamID, h func(agg *Aggregate[T]) error, opts ...func(*event.AppendConfig)) error {
+	for attempt := 0; ; attempt++ {
+		agg, err := r.Load(ctx, id)
+		if err != nil {
+			if !errors.ErrIs(err, ErrAggregateNotFound) {
+				return err
+			}
+			agg = r.New(id)
+		}
+
+		if err := h(agg); err != nil {
+			return err
+		}
+
+		err = r.Save(ctx, agg, opts...)
+		if err == nil {
+			return nil
+		}
+		if !errors.ErrIs(err, event.ErrAppendEventsConflict) || attempt >= r.cfg.ConflictRetries {
+			return err
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package sourcing
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+)
+
+type counter struct {
+	Count int
+	Vals  []string
+}
+
+func counterMutator(state *counter, evt any) {
+	switch evt := evt.(type) {
+	case *Event:
+		state.Count++
+		state.Vals = append(state.Vals, evt.Val)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+
+	store := &mockStore{streams: make(map[string]event.Stream)}
+	repo := NewRepository(store, counterMutator)
+
+	stmID := event.NewStreamID("globalID", "counter", "1")
+
+	if _, err := repo.Load(ctx, stmID); !errors.Is(err, ErrAggregateNotFound) {
+		t.Fatalf("expect %v, %v be equals", ErrAggregateNotFound, err)
+	}
+
+	agg := repo.New(stmID)
+	agg.Apply(&Event{Val: "1"}, &Event{Val: "2"})
+	if want, got := 2, len(agg.Changes()); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if err := repo.Save(ctx, agg); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 0, len(agg.Changes()); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if want, got := event.VersionMin.Add(0, 1).EOF(), agg.Version(); !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// saving without changes is a no-op
+	if err := repo.Save(ctx, agg); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	ragg, err := repo.Load(ctx, stmID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 2, ragg.State().Count; want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if want, got := agg.Version(), ragg.Version(); !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// concurrent changes on a stale aggregate must conflict
+	ragg.Apply(&Event{Val: "3"})
+	if err := repo.Save(ctx, ragg); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	agg.Apply(&Event{Val: "4"})
+	if want, err := event.ErrAppendEventsConflict, repo.Save(ctx, agg); !errors.Is(err, want) {
+		t.Fatalf("expect %v, %v be equals", want, err)
+	}
+}
+
+func TestRepository_Update(t *testing.T) {
+	ctx := context.Background()
+
+	store := &mockStore{streams: make(map[string]event.Stream)}
+
+	stmID := event.NewStreamID("globalID", "counter", "1")
+
+	// concurrent writer appends on the first attempt only
+	concurrent := func(calls *int) func(agg *Aggregate[counter]) error {
+		return func(agg *Aggregate[counter]) error {
+			*calls++
+			if *calls == 1 {
+				_ = store.AppendToStream(ctx, Wrap(ctx, stmID, agg.Version(), []any{&Event{Val: "concurrent"}}))
+			}
+			agg.Apply(&Event{Val: "update"})
+			return nil
+		}
+	}
+
+	calls := 0
+	repo := NewRepository(store, counterMutator)
+	if want, err := event.ErrAppendEventsConflict, repo.Update(ctx, stmID, concurrent(&calls)); !errors.Is(err, want) {
+		t.Fatalf("expect %v, %v be equals", want, err)
+	}
+
+	calls = 0
+	repo = NewRepository(store, counterMutator, func(rc *RepositoryConfig) {
+		rc.ConflictRetries = 1
+	})
+	if err := repo.Update(ctx, stmID, concurrent(&calls)); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 2, calls; want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	agg, err := repo.Load(ctx, stmID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := []string{"concurrent", "concurrent", "update"}, agg.State().Vals; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// handler errors are not retried
+	errHandler := errors.New("handler error")
+	if err := repo.Update(ctx, stmID, func(agg *Aggregate[counter]) error {
+		return errHandler
+	}); !errors.Is(err, errHandler) {
+		t.Fatalf("expect %v, %v be equals", errHandler, err)
+	}
+}
+
+func TestRepository_WithSnapshots(t *testing.T) {
+	ctx := context.Background()
+
+	store := &mockStore{streams: make(map[string]event.Stream)}
+	snapshots := &mockSnapshotStore{snapshots: make(map[string]Snapshot)}
+
+	repo := NewRepository(store, counterMutator, func(rc *RepositoryConfig) {
+		rc.Snapshots = NewSnapshotLoader(store, snapshots, func(slc *SnapshotLoaderConfig) {
+			slc.Policy = EveryNEvents(3)
+		})
+	})
+
+	stmID := event.NewStreamID("globalID", "counter", "1")
+
+	for i := 0; i < 4; i++ {
+		if err := repo.Update(ctx, stmID, func(agg *Aggregate[counter]) error {
+			agg.Apply(&Event{Val: "val"})
+			return nil
+		}); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	}
+
+	snap, ok := snapshots.snapshots[stmID.String()]
+	if !ok {
+		t.Fatal("expect snapshot be saved")
+	}
+	if want, got := 3, snap.State.(counter).Count; want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	agg, err := repo.Load(ctx, stmID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 4, agg.State().Count; want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if want, got := event.VersionMin.Add(3, 0).EOF(), agg.Version(); !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	// only the tail is loaded
+	if want, got := snap.Version.Incr(), store.vranges[len(store.vranges)-1][0]; !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
@@ -0,0 +1,142 @@
+package sourcing
+
+import (
+	"context"
+	"time"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
+)
+
+var (
+	ErrSaveSnapshotFailed = errors.New("save snapshot failed")
+	ErrLoadSnapshotFailed = errors.New("load snapshot failed")
+)
+
+// Snapshot presents the state of an aggregate at a given stream version.
+//
+// The state is serialized using the event store serializer, it must be registered in the event registry.
+type Snapshot struct {
+	StreamID event.StreamID
+	Version  event.Version
+	State    any
+	At       time.Time
+}
+
+// SnapshotStore defines the storage of the aggregates' snapshots.
+type SnapshotStore interface {
+	// SaveSnapshot stores the given snapshot. An older snapshot than the stored one is ignored.
+	SaveSnapshot(ctx context.Context, snap Snapshot) error
+	// LoadSnapshot returns the latest snapshot of the given stream, or nil if not found.
+	LoadSnapshot(ctx context.Context, id event.StreamID) (*Snapshot, error)
+}
+
+// SnapshotProgress presents the stream progress since the last snapshot.
+type SnapshotProgress struct {
+	// From and To are respectively the last snapshot version and the current stream version.
+	From, To event.Version
+	// Events is the count of events appended since the last snapshot.
+	Events int
+}
+
+// SnapshotPolicy decides whether a snapshot must be taken based on the stream progress.
+type SnapshotPolicy func(p SnapshotProgress) bool
+
+// EveryNEvents returns a policy that takes a snapshot once at least n events are appended since the last one.
+func EveryNEvents(n int) SnapshotPolicy {
+	return func(p SnapshotProgress) bool {
+		return p.Events >= n
+	}
+}
+
+// EveryNRecords returns a policy that takes a snapshot once at least n records
+// (aka chunks of events) are appended since the last one.
+func EveryNRecords(n int) SnapshotPolicy {
+	return func(p SnapshotProgress) bool {
+		if n <= 0 {
+			return true
+		}
+		return !p.To.Trunc().Before(p.From.Trunc().Add(uint64(n), 0))
+	}
+}
+
+// SnapshotLoaderConfig presents the snapshot loader configuration.
+type SnapshotLoaderConfig struct {
+	// Policy decides when to take a new snapshot. It defaults to every 100 events.
+	Policy SnapshotPolicy
+}
+
+// SnapshotLoader loads aggregate streams on top of their latest snapshot.
+type SnapshotLoader struct {
+	store     Store
+	snapshots SnapshotStore
+
+	cfg *SnapshotLoaderConfig
+}
+
+// NewSnapshotLoader returns a snapshot loader based on the given event store and snapshot store.
+func NewSnapshotLoader(store Store, snapshots SnapshotStore, opts ...func(*SnapshotLoaderConfig)) *SnapshotLoader {
+	cfg := &SnapshotLoaderConfig{
+		Policy: EveryNEvents(100),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	return &SnapshotLoader{
+		store:     store,
+		snapshots: snapshots,
+		cfg:       cfg,
+	}
+}
+
+// Load returns the latest snapshot of the given stream, if any, and the tail of the stream
+// which contains only the events appended after the snapshot.
+func (l *SnapshotLoader) Load(ctx context.Context, id event.StreamID) (*Snapshot, *Stream, error) {
+	snap, err := l.snapshots.LoadSnapshot(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vrange := []event.Version{}
+	if snap != nil {
+		vrange = append(vrange, snap.Version.Trunc().Add(1, 0))
+	}
+
+	stm, err := l.store.LoadStream(ctx, id, vrange...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return snap, stm, nil
+}
+
+// Save takes a snapshot of the given state if the loader policy allows it.
+// The last snapshot is the one returned by Load, if any; and events is the count of events
+// appended since then, including the loaded tail. It returns true if the snapshot is saved.
+func (l *SnapshotLoader) Save(ctx context.Context, last *Snapshot, snap Snapshot, events int) (bool, error) {
+	p := SnapshotProgress{
+		From:   event.VersionZero,
+		To:     snap.Version,
+		Events: events,
+	}
+	if last != nil {
+		p.From = last.Version
+	}
+
+	if events == 0 || !l.cfg.Policy(p) {
+		return false, nil
+	}
+
+	if snap.At.IsZero() {
+		snap.At = time.Now().UTC()
+	}
+	if err := l.snapshots.SaveSnapshot(ctx, snap); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
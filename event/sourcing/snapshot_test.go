@@ -0,0 +1,169 @@
+package sourcing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+)
+
+type mockStore struct {
+	streams map[string]event.Stream
+	vranges [][]event.Version
+}
+
+func (s *mockStore) AppendToStream(ctx context.Context, chunk Stream, optFns ...func(opt *event.AppendConfig)) error {
+	last := event.VersionZero
+	if stm := s.streams[chunk.ID().String()]; len(stm) > 0 {
+		last = stm[len(stm)-1].Version()
+	}
+	if !chunk.Unwrap()[0].Version().Next(last) {
+		return event.ErrAppendEventsConflict
+	}
+	s.streams[chunk.ID().String()] = append(s.streams[chunk.ID().String()], chunk.Unwrap()...)
+	return nil
+}
+
+func (s *mockStore) LoadStream(ctx context.Context, id event.StreamID, vrange ...event.Version) (*Stream, error) {
+	s.vranges = append(s.vranges, vrange)
+
+	envs := event.Stream{}
+	for _, env := range s.streams[id.String()] {
+		if len(vrange) > 0 && env.Version().Before(vrange[0]) {
+			continue
+		}
+		envs = append(envs, env)
+	}
+	return NewStream(id, envs), nil
+}
+
+type mockSnapshotStore struct {
+	snapshots map[string]Snapshot
+}
+
+func (s *mockSnapshotStore) SaveSnapshot(ctx context.Context, snap Snapshot) error {
+	s.snapshots[snap.StreamID.String()] = snap
+	return nil
+}
+
+func (s *mockSnapshotStore) LoadSnapshot(ctx context.Context, id event.StreamID) (*Snapshot, error) {
+	snap, ok := s.snapshots[id.String()]
+	if !ok {
+		return nil, nil
+	}
+	return &snap, nil
+}
+
+func TestSnapshotPolicy(t *testing.T) {
+	tcs := []struct {
+		name   string
+		policy SnapshotPolicy
+		p      SnapshotProgress
+		ok     bool
+	}{
+		{
+			name:   "every n events",
+			policy: EveryNEvents(3),
+			p:      SnapshotProgress{From: event.VersionZero, To: event.VersionMin.Add(0, 2).EOF(), Events: 3},
+			ok:     true,
+		},
+		{
+			name:   "every n events not reached",
+			policy: EveryNEvents(3),
+			p:      SnapshotProgress{From: event.VersionZero, To: event.VersionMin.Add(0, 1).EOF(), Events: 2},
+			ok:     false,
+		},
+		{
+			name:   "every n records",
+			policy: EveryNRecords(2),
+			p:      SnapshotProgress{From: event.VersionMin.EOF(), To: event.VersionMin.Add(2, 0).EOF(), Events: 2},
+			ok:     true,
+		},
+		{
+			name:   "every n records not reached",
+			policy: EveryNRecords(2),
+			p:      SnapshotProgress{From: event.VersionMin.EOF(), To: event.VersionMin.Add(1, 5).EOF(), Events: 6},
+			ok:     false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if want, got := tc.ok, tc.policy(tc.p); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		})
+	}
+}
+
+func TestSnapshotLoader(t *testing.T) {
+	ctx := context.Background()
+
+	store := &mockStore{streams: make(map[string]event.Stream)}
+	snapshots := &mockSnapshotStore{snapshots: make(map[string]Snapshot)}
+
+	loader := NewSnapshotLoader(store, snapshots, func(slc *SnapshotLoaderConfig) {
+		slc.Policy = EveryNRecords(2)
+	})
+
+	stmID := event.NewStreamID("globalID", "service", "aggregateID")
+
+	// no snapshot found
+	snap, stm, err := loader.Load(ctx, stmID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if snap != nil {
+		t.Fatalf("expect snapshot be nil, got %v", snap)
+	}
+	if want, got := 0, len(stm.Unwrap()); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	chunk1 := Wrap(ctx, stmID, event.VersionZero, []any{&Event{Val: "1"}, &Event{Val: "2"}})
+	_ = store.AppendToStream(ctx, chunk1)
+
+	// policy is not satisfied yet
+	ok, err := loader.Save(ctx, nil, Snapshot{StreamID: stmID, Version: chunk1.Version(), State: "state 1"}, 2)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if ok {
+		t.Fatal("expect snapshot not be saved")
+	}
+
+	chunk2 := Wrap(ctx, stmID, chunk1.Version(), []any{&Event{Val: "3"}})
+	_ = store.AppendToStream(ctx, chunk2)
+
+	ok, err = loader.Save(ctx, nil, Snapshot{StreamID: stmID, Version: chunk2.Version(), State: "state 2"}, 3)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if !ok {
+		t.Fatal("expect snapshot be saved")
+	}
+
+	chunk3 := Wrap(ctx, stmID, chunk2.Version(), []any{&Event{Val: "4"}})
+	_ = store.AppendToStream(ctx, chunk3)
+
+	// load the snapshot and only the stream tail
+	snap, stm, err = loader.Load(ctx, stmID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := "state 2", snap.State; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if snap.At.IsZero() {
+		t.Fatal("expect snapshot timestamp be set")
+	}
+	if want, got := chunk2.Version().Incr(), store.vranges[len(store.vranges)-1][0]; !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := 1, len(stm.Unwrap()); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if want, got := chunk3.Version(), stm.Version(); !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
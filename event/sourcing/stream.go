@@ -4,6 +4,11 @@ import (
 	"context"
 
 	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
+)
+
+var (
+	ErrInvalidMultiStreamChunks = errors.New("invalid multi-stream chunks")
 )
 
 type Store interface {
@@ -11,6 +16,46 @@ type Store interface {
 	LoadStream(ctx context.Context, id event.StreamID, vrange ...event.Version) (*Stream, error)
 }
 
+// MultiStreamStore defines a store that atomically appends chunks of events to several streams.
+type MultiStreamStore interface {
+	Store
+	// AppendToStreams appends the given chunks, under the same global stream, as a single operation.
+	// Either all chunks are appended, each one as a record with consecutive global versions, or none is.
+	//
+	// The current version of each stream is inferred from the first version of its chunk. Expected version options
+	// (see event.WithExpectedVersion) aren't supported, and result in an event.ErrUnsupportedAppendOption error.
+	AppendToStreams(ctx context.Context, chunks []Stream, optFns ...func(opt *event.AppendConfig)) error
+}
+
+// ValidateChunks makes sure the given chunks are valid, and target distinct streams of the same global stream.
+// Empty chunks are ignored.
+func ValidateChunks(chunks []Stream) error {
+	globalID := ""
+	seen := make(map[string]struct{}, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.Empty() {
+			continue
+		}
+		id := chunk.ID()
+		if globalID == "" {
+			globalID = id.GlobalID()
+		}
+		if id.GlobalID() != globalID {
+			return errors.Err(ErrInvalidMultiStreamChunks, id.String(), "global stream mismatch "+globalID+" "+id.GlobalID())
+		}
+		if _, ok := seen[id.String()]; ok {
+			return errors.Err(ErrInvalidMultiStreamChunks, id.String(), "duplicate stream")
+		}
+		seen[id.String()] = struct{}{}
+
+		if err := chunk.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func Wrap(ctx context.Context, stmID event.StreamID, curVer event.Version, events []any, opts ...event.EnvelopeOption) Stream {
 
 	options := append([]event.EnvelopeOption{
@@ -27,25 +72,24 @@ func Wrap(ctx context.Context, stmID event.StreamID, curVer event.Version, event
 }
 
 type Stream struct {
-	events  event.Stream
-	iD      event.StreamID
-	version event.Version
+	events event.Stream
+	iD     event.StreamID
 }
 
 func NewStream(id event.StreamID, events event.Stream) *Stream {
-	stm := &Stream{
-		iD:      id,
-		version: event.VersionZero,
-		events:  events,
+	return &Stream{
+		iD:     id,
+		events: events,
 	}
-	if l := len(events); l > 0 {
-		stm.version = events[l-1].Version()
-	}
-	return stm
 }
 
+// Version returns the version of the last event in the stream.
+// It's not cached, as stores might rebase the event versions on append (ex: expected version modes).
 func (s *Stream) Version() event.Version {
-	return s.version
+	if l := len(s.events); l > 0 {
+		return s.events[l-1].Version()
+	}
+	return event.VersionZero
 }
 
 func (s *Stream) ID() event.StreamID {
@@ -29,4 +29,12 @@ func TestStream(t *testing.T) {
 	if want, got := events, stm.Unwrap().Events(); !reflect.DeepEqual(want, got) {
 		t.Fatalf("expect %v, %v be equals", want, got)
 	}
+
+	// the version follows events rebased by the store
+	if err := event.RebaseVersions(stm.Unwrap(), event.VersionMin); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := event.VersionMin.Incr().Add(0, 1).EOF(), stm.Version(); !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
 }
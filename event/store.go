@@ -18,8 +18,42 @@ var (
 // AppendConfig presents a generic definition of write operation options.
 // the behavior might differs based on the event store implementation.
 type AppendConfig struct {
-	AddToTx    func(ctx context.Context) (items []any)
+	AddToTx func(ctx context.Context) (items []any)
+	// AddTracing returns the trace ID to set to the appended events that don't have one.
 	AddTracing func(ctx context.Context) (traceID string)
+	// ExpectedVersion defines the stream state expected by AppendToStream.
+	// If missing, the expected version is inferred from the first version of the chunk.
+	ExpectedVersion *ExpectedVersion
+	// SkipIndex skips the secondary indexing of the appended events, used by StreamQuery filters.
+	// Stores that don't maintain secondary indexes ignore it.
+	SkipIndex bool
+}
+
+// WithoutIndex skips the secondary indexing of the appended events, see StreamIndexer.
+func WithoutIndex() func(*AppendConfig) {
+	return func(cfg *AppendConfig) {
+		cfg.SkipIndex = true
+	}
+}
+
+// Trace sets the trace ID returned by AddTracing to the given events if the option is defined.
+// Events that already have a trace ID, or that do not implement TraceIDSetter, are left untouched.
+func (cfg *AppendConfig) Trace(ctx context.Context, events []Envelope) {
+	if cfg == nil || cfg.AddTracing == nil {
+		return
+	}
+	traceID := cfg.AddTracing(ctx)
+	if traceID == "" {
+		return
+	}
+	for _, env := range events {
+		if env.TraceID() != "" {
+			continue
+		}
+		if rwEnv, ok := env.(TraceIDSetter); ok {
+			rwEnv.SetTraceID(traceID)
+		}
+	}
 }
 
 // Store defines the interface of the event logging store aka timestamp-based event store.
@@ -30,3 +64,9 @@ type Store interface {
 	// Load events from a stream based on the given timestamp range
 	Load(ctx context.Context, id StreamID, trange ...time.Time) ([]Envelope, error)
 }
+
+// StreamIndexer is implemented by stores that maintain secondary indexes used by StreamQuery filters.
+type StreamIndexer interface {
+	// Reindex indexes the events of the given global stream that were appended using WithoutIndex option.
+	Reindex(ctx context.Context, id StreamID) error
+}
@@ -170,6 +170,9 @@ type Validation struct {
 	Boundaries    ValidationBoundaries
 	SkipVersion   bool
 	SkipTimeStamp bool
+	// AllowRecordGaps tolerates missing records in the stream sequence, ex: records purged once expired.
+	// Events of the same record must remain consecutive.
+	AllowRecordGaps bool
 }
 
 // ValidateEvent validates the event according to its sequence in the stream.
@@ -218,7 +221,7 @@ func ValidateEvent(env Envelope, cur *Cursor, opts ...func(v *Validation)) (igno
 		if cur.Ver.IsZero() && ver.Equal(v.Boundaries.From) {
 			cur.Ver = ver
 		} else {
-			if !ver.Next(cur.Ver) {
+			if !ver.Next(cur.Ver) && !(v.AllowRecordGaps && nextRecord(ver, cur.Ver)) {
 				return false, errors.Err(ErrInvalidStream, cur.StreamID, "invalid version sequence: "+cur.Ver.String()+","+ver.String())
 			}
 			cur.Ver = ver
@@ -248,6 +251,12 @@ func ValidateEvent(env Envelope, cur *Cursor, opts ...func(v *Validation)) (igno
 	return false, nil
 }
 
+// nextRecord returns true if the given version starts a record that comes after the record of the current version,
+// the current record must be complete.
+func nextRecord(ver, cur Version) bool {
+	return ver.f == 0 && ver.p > cur.p && (cur.f == 0 || cur.eof)
+}
+
 // Validate a chunk of events.
 // Validate will define the validation boundaries and cursor based on the current,
 // any validation boundaries set at the option-level will ignored.
@@ -141,6 +141,31 @@ func TestStream_Validation(t *testing.T) {
 			t.Fatalf("expect err be nil, got %v", err)
 		}
 	})
+	t.Run("validate stream with record gaps", func(t *testing.T) {
+		envs := Wrap(context.Background(), streamID, append(events, &Event{Val: "3"}))
+		envs[0].(RWEnvelope).SetVersion(VersionMin.EOF())
+		envs[1].(RWEnvelope).SetVersion(VersionMin.Add(2, 0))
+		envs[2].(RWEnvelope).SetVersion(VersionMin.Add(2, 2).EOF())
+
+		cur := NewCursor(streamID.String())
+		if _, err := ValidateEvent(envs[0], cur); err != nil {
+			t.Fatalf("expect err be nil, got %v", err)
+		}
+		if _, err := ValidateEvent(envs[1], cur); !errors.Is(err, ErrInvalidStream) {
+			t.Fatalf("expect err be %v, got %v", ErrInvalidStream, err)
+		}
+
+		allowGaps := func(v *Validation) {
+			v.AllowRecordGaps = true
+		}
+		if _, err := ValidateEvent(envs[1], cur, allowGaps); err != nil {
+			t.Fatalf("expect err be nil, got %v", err)
+		}
+		// events of the same record must remain consecutive
+		if _, err := ValidateEvent(envs[2], cur, allowGaps); !errors.Is(err, ErrInvalidStream) {
+			t.Fatalf("expect err be %v, got %v", ErrInvalidStream, err)
+		}
+	})
 }
 
 func TestStream_Basic(t *testing.T) {
@@ -12,6 +12,7 @@ const (
 	StreamDataTypeRecord   StreamDataType = "record"
 	StreamDataTypeEnd      StreamDataType = "end"
 	StreamDataTypeContinue StreamDataType = "continue"
+	StreamDataTypeLagging  StreamDataType = "lagging"
 )
 
 // StreamData mainly contains an event record.
@@ -33,6 +34,7 @@ const (
 
 const (
 	StreamerReplayQueryDefaultLimit uint = 500
+	StreamerQueryDefaultLimit       int  = 500
 )
 
 // StreamReplayHandler process the given event in the replay stream process
@@ -73,6 +75,18 @@ func (q *StreamReplayQuery) Build() {
 	}
 }
 
+// StreamSubscriber presents a push-based subscription to a stream.
+type StreamSubscriber interface {
+	// Subscribe replays the stream events starting from the given global version, and then keeps
+	// delivering newly appended events until the context is canceled or the handler fails.
+	//
+	// Besides records, the handler may receive the following signals:
+	// - StreamDataTypeContinue: the subscription has caught up and is now tailing new appends;
+	// - StreamDataTypeLagging: the consumer has fallen behind and the subscription is catching up again.
+	// In both cases, the signal value is the global version of the last delivered event, if any.
+	Subscribe(ctx context.Context, streamID StreamID, from Version, h StreamReplayHandler) error
+}
+
 // Streamer mainly used to query global streams for event replay and projections.
 type StreamQuerier interface {
 	// Travel a stream based on the given query params.
@@ -91,9 +105,23 @@ type StreamQuery struct {
 	Types   []string
 	IPAddrs []string
 
+	CorrelationIDs []string
+	CausationIDs   []string
+	TraceIDs       []string
+
 	Order StreamOrder
 }
 
+// Build applies filter default values if they are missing.
+func (q *StreamQuery) Build() {
+	if q.RecordLimit <= 0 {
+		q.RecordLimit = StreamerQueryDefaultLimit
+	}
+	if q.Order == "" {
+		q.Order = StreamOrderASC
+	}
+}
+
 // StreamQueryResult contains the result of a time-based travel query
 type StreamQueryResult struct {
 	Events []Envelope
@@ -29,3 +29,26 @@ func TestStreamReplay_Query(t *testing.T) {
 		t.Fatalf("expect %v, %v be equals", want, got)
 	}
 }
+
+func TestStream_Query(t *testing.T) {
+	q1 := StreamQuery{}
+	q1.Build()
+	if want, got := StreamerQueryDefaultLimit, q1.RecordLimit; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := StreamOrderASC, q1.Order; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	q2 := StreamQuery{
+		RecordLimit: 10,
+		Order:       StreamOrderDESC,
+	}
+	q2.Build()
+	if want, got := 10, q2.RecordLimit; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := StreamOrderDESC, q2.Order; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
@@ -1,15 +1,91 @@
 package event
 
 import (
+	"sync/atomic"
+	"time"
+
+	"github.com/ln80/event-store/event/errors"
 	"github.com/rs/xid"
 )
 
+var (
+	ErrInvalidID = errors.New("invalid ID")
+)
+
 // ID presents the interface responsible for generating UID.
 // It serves as an abstraction to avoid strong coupling to a specific library/algorithm
 type ID interface {
 	String() string
 }
 
+// IDGenerator generates unique IDs, ex: event IDs.
+type IDGenerator interface {
+	NewID() ID
+}
+
+// IDTimestamper is implemented by generators that embed a timestamp in their IDs.
+// Such IDs are sortable by generation time, and can be used as sort keys.
+type IDTimestamper interface {
+	// Time returns the timestamp embedded in the given ID.
+	Time(id string) (time.Time, error)
+}
+
+var idGenerator atomic.Value
+
+func init() {
+	idGenerator.Store(&holder{XIDGenerator()})
+}
+
+// holder allows to store different generator types in the same atomic value.
+type holder struct {
+	IDGenerator
+}
+
+// SetIDGenerator overrides the default generator used by UID, which is XIDGenerator.
+func SetIDGenerator(gen IDGenerator) {
+	if gen == nil {
+		return
+	}
+	idGenerator.Store(&holder{gen})
+}
+
+// DefaultIDGenerator returns the generator used by UID.
+func DefaultIDGenerator() IDGenerator {
+	return idGenerator.Load().(*holder).IDGenerator
+}
+
+// UID returns a new ID using the default generator.
 func UID() ID {
+	return DefaultIDGenerator().NewID()
+}
+
+// WithIDGenerator overrides the default generator of the envelope ID.
+func WithIDGenerator(gen IDGenerator) EnvelopeOption {
+	return func(env RWEnvelope) {
+		if e, ok := env.(*envelope); ok && gen != nil {
+			e.eID = gen.NewID().String()
+		}
+	}
+}
+
+type xidGenerator struct{}
+
+// XIDGenerator returns a generator of xid IDs (https://github.com/rs/xid).
+// Its IDs embed a timestamp with a second precision.
+func XIDGenerator() IDGenerator {
+	return xidGenerator{}
+}
+
+// NewID implements IDGenerator interface.
+func (xidGenerator) NewID() ID {
 	return xid.New()
 }
+
+// Time implements IDTimestamper interface.
+func (xidGenerator) Time(id string) (time.Time, error) {
+	x, err := xid.FromString(id)
+	if err != nil {
+		return time.Time{}, errors.Err(ErrInvalidID, "", err)
+	}
+	return x.Time(), nil
+}
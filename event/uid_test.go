@@ -0,0 +1,115 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIDGenerator(t *testing.T) {
+	tcs := []struct {
+		name     string
+		gen      IDGenerator
+		sortable bool
+		length   int
+	}{
+		{name: "xid", gen: XIDGenerator(), sortable: true, length: 20},
+		{name: "uuid v4", gen: UUIDv4Generator(), length: 36},
+		{name: "uuid v7", gen: UUIDv7Generator(), sortable: true, length: 36},
+		{name: "ulid", gen: ULIDGenerator(), sortable: true, length: 26},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			before := time.Now()
+
+			seen := make(map[string]bool)
+			last := ""
+			for i := 0; i < 10000; i++ {
+				id := tc.gen.NewID().String()
+				if want, got := tc.length, len(id); want != got {
+					t.Fatalf("expect %v, %v be equals", want, got)
+				}
+				if seen[id] {
+					t.Fatalf("expect id %s be unique", id)
+				}
+				seen[id] = true
+				if tc.sortable && id <= last {
+					t.Fatalf("expect id %s be greater than %s", id, last)
+				}
+				last = id
+			}
+
+			ts, ok := tc.gen.(IDTimestamper)
+			if ok != tc.sortable {
+				t.Fatalf("expect %v, %v be equals", tc.sortable, ok)
+			}
+			if !ok {
+				return
+			}
+			at, err := ts.Time(last)
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if at.Before(before.Truncate(time.Second)) || at.After(time.Now()) {
+				t.Fatalf("invalid ID timestamp %v", at)
+			}
+			if _, err := ts.Time("invalid"); !errors.Is(err, ErrInvalidID) {
+				t.Fatalf("expect %v error to occur, got: %v", ErrInvalidID, err)
+			}
+		})
+	}
+}
+
+func TestIDGenerator_Monotonic(t *testing.T) {
+	now := time.Now()
+	m := &monotonic{now: func() time.Time { return now }, hiBits: 16}
+
+	ms, hi, lo := m.next()
+	if want, got := uint64(now.UnixMilli()), ms; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// within the same millisecond the random part is incremented
+	_, hi2, lo2 := m.next()
+	if hi2 != hi || lo2 != lo+1 {
+		t.Fatalf("expect random part be incremented, got %d %d", hi2, lo2)
+	}
+
+	// clock drift doesn't make values go backward
+	now = now.Add(-time.Second)
+	if ms2, _, _ := m.next(); ms2 != ms {
+		t.Fatalf("expect %v, %v be equals", ms, ms2)
+	}
+
+	// overflow moves to the next millisecond
+	m.hi, m.lo = 1<<m.hiBits-1, 1<<64-1
+	if ms2, _, _ := m.next(); ms2 != ms+1 {
+		t.Fatalf("expect %v, %v be equals", ms+1, ms2)
+	}
+}
+
+func TestIDGenerator_Wrap(t *testing.T) {
+	ctx := context.Background()
+	streamID := NewStreamID("tenantID")
+
+	envs := Wrap(ctx, streamID, []any{&Event{Val: "1"}}, WithIDGenerator(UUIDv7Generator()))
+	if _, err := UUIDv7Generator().(IDTimestamper).Time(envs[0].ID()); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	defer SetIDGenerator(XIDGenerator())
+	SetIDGenerator(ULIDGenerator())
+
+	envs = Wrap(ctx, streamID, []any{&Event{Val: "1"}})
+	if want, got := 26, len(envs[0].ID()); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if _, err := IDTime(envs[0].ID()); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if id := UID().String(); id <= envs[0].ID() {
+		t.Fatalf("expect id %s be greater than %s", id, envs[0].ID())
+	}
+}
@@ -0,0 +1,189 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ln80/event-store/event/errors"
+)
+
+var (
+	ErrUpcastEventFailed = errors.New("upcast event failed")
+)
+
+// maxUpcastSteps limits the length of an upcasting chain, it mainly protects against cyclic upcasters.
+const maxUpcastSteps = 100
+
+// RawEvent presents an event payload in a generic form. It's both the input and the output of upcasters.
+//
+// Data values types depend on the encoding format, ex: numbers are presented as json.Number in the case of JSON.
+type RawEvent struct {
+	Type     string
+	Revision int
+	Data     map[string]any
+}
+
+// Upcaster migrates a legacy event payload into a newer shape.
+//
+// It may rename the event type, and return several events to split the original one.
+// The returned events are upcasted in turn if an upcaster matches their type and revision.
+// It must not return the same type and revision of the given event, unless the payload is already up to date.
+type Upcaster func(ctx context.Context, evt RawEvent) ([]RawEvent, error)
+
+type upcasterKey struct {
+	typ string
+	rev int
+}
+
+// Upcasters is a registry of upcasters keyed by event type name and schema revision.
+// Serializers use it to transparently upcast legacy events on unmarshal.
+type Upcasters struct {
+	mu      sync.RWMutex
+	entries map[upcasterKey]Upcaster
+}
+
+// NewUpcasters returns an empty upcasters registry.
+func NewUpcasters() *Upcasters {
+	return &Upcasters{
+		entries: make(map[upcasterKey]Upcaster),
+	}
+}
+
+// Set registers the upcaster of the given event type and revision. It overrides the existing one if any.
+//
+// The event type name must match the one found in the persisted envelopes, including the namespace if any.
+// Revision zero refers to events persisted before defining a revision using WithRevision registry option.
+func (u *Upcasters) Set(typ string, rev int, up Upcaster) *Upcasters {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.entries[upcasterKey{typ: typ, rev: rev}] = up
+	return u
+}
+
+// Match returns true if an upcaster is registered for the given event type and revision.
+func (u *Upcasters) Match(typ string, rev int) bool {
+	if u == nil {
+		return false
+	}
+
+	_, ok := u.get(typ, rev)
+	return ok
+}
+
+// Upcast applies the matching upcasters until the event and its derived ones are up to date.
+// It returns the given event as is if no upcaster matches.
+func (u *Upcasters) Upcast(ctx context.Context, evt RawEvent) ([]RawEvent, error) {
+	if u == nil {
+		return []RawEvent{evt}, nil
+	}
+
+	return u.upcast(ctx, evt, 0)
+}
+
+func (u *Upcasters) upcast(ctx context.Context, evt RawEvent, step int) ([]RawEvent, error) {
+	up, ok := u.get(evt.Type, evt.Revision)
+	if !ok {
+		return []RawEvent{evt}, nil
+	}
+	if step >= maxUpcastSteps {
+		return nil, errors.Err(ErrUpcastEventFailed, "", fmt.Sprintf("upcasting chain too long: %s@%d", evt.Type, evt.Revision))
+	}
+
+	evts, err := up(ctx, evt)
+	if err != nil {
+		return nil, errors.Err(ErrUpcastEventFailed, "", err)
+	}
+
+	result := make([]RawEvent, 0, len(evts))
+	for _, e := range evts {
+		// the upcaster considers the event as up to date
+		if e.Type == evt.Type && e.Revision == evt.Revision {
+			result = append(result, e)
+			continue
+		}
+		es, err := u.upcast(ctx, e, step+1)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, es...)
+	}
+
+	return result, nil
+}
+
+func (u *Upcasters) get(typ string, rev int) (Upcaster, bool) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	up, ok := u.entries[upcasterKey{typ: typ, rev: rev}]
+	return up, ok
+}
+
+// RenumberVersions reassigns the fractional part of the given versions, so that the events of the same record
+// remain contiguous and the last one is marked as EOF.
+//
+// It's mainly used by serializers once upcasting splits an event into several ones, in such a case
+// the derived events share the version of the original event. Versions must be sorted, and zero versions are left untouched.
+func RenumberVersions(vers []Version) []Version {
+	result := make([]Version, len(vers))
+	for i := 0; i < len(vers); {
+		if vers[i].IsZero() {
+			i++
+			continue
+		}
+
+		record := vers[i].Trunc()
+		j := i
+		for j < len(vers) && vers[j].Trunc().Equal(record) {
+			j++
+		}
+		for k := i; k < j; k++ {
+			result[k] = record.Add(0, uint8(k-i))
+		}
+		result[j-1] = result[j-1].EOF()
+
+		i = j
+	}
+
+	return result
+}
+
+// RenumberEvents reassigns the versions and global versions of the given events once upcasting changes
+// the number of events within records (see RenumberVersions). Versions are renumbered per stream, and global versions
+// per global stream. Events must be sorted, and implement VersionSetter and GlobalVersionSetter interfaces,
+// otherwise they're left untouched.
+func RenumberEvents(envs []Envelope) {
+	streams, globalStreams := make(map[string][]int), make(map[string][]int)
+	for i, env := range envs {
+		if env == nil {
+			continue
+		}
+		streams[env.StreamID()] = append(streams[env.StreamID()], i)
+		globalStreams[env.GlobalStreamID()] = append(globalStreams[env.GlobalStreamID()], i)
+	}
+
+	for _, idx := range streams {
+		vers := make([]Version, len(idx))
+		for k, i := range idx {
+			vers[k] = envs[i].Version()
+		}
+		for k, ver := range RenumberVersions(vers) {
+			if rwEnv, ok := envs[idx[k]].(VersionSetter); ok && !ver.IsZero() {
+				rwEnv.SetVersion(ver)
+			}
+		}
+	}
+	for _, idx := range globalStreams {
+		gVers := make([]Version, len(idx))
+		for k, i := range idx {
+			gVers[k] = envs[i].GlobalVersion()
+		}
+		for k, gVer := range RenumberVersions(gVers) {
+			if rwEnv, ok := envs[idx[k]].(GlobalVersionSetter); ok && !gVer.IsZero() {
+				rwEnv.SetGlobalVersion(gVer)
+			}
+		}
+	}
+}
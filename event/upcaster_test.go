@@ -0,0 +1,168 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUpcasters(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no match", func(t *testing.T) {
+		var u *Upcasters
+		if u.Match("Event", 0) {
+			t.Fatal("expect nil upcasters match nothing")
+		}
+
+		evt := RawEvent{Type: "Event", Data: map[string]any{"Val": "foo"}}
+		evts, err := NewUpcasters().Upcast(ctx, evt)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := []RawEvent{evt}, evts; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("chain, rename and split", func(t *testing.T) {
+		u := NewUpcasters().
+			// rename the legacy event type
+			Set("Legacy", 0, func(ctx context.Context, evt RawEvent) ([]RawEvent, error) {
+				return []RawEvent{{Type: "Event", Revision: 0, Data: evt.Data}}, nil
+			}).
+			// rename a field
+			Set("Event", 0, func(ctx context.Context, evt RawEvent) ([]RawEvent, error) {
+				return []RawEvent{{Type: "Event", Revision: 1, Data: map[string]any{"Val": evt.Data["Value"]}}}, nil
+			}).
+			// split the event into two events
+			Set("Event", 1, func(ctx context.Context, evt RawEvent) ([]RawEvent, error) {
+				return []RawEvent{
+					{Type: "Event", Revision: 2, Data: evt.Data},
+					{Type: "Event2", Revision: 2, Data: evt.Data},
+				}, nil
+			})
+
+		if !u.Match("Legacy", 0) {
+			t.Fatal("expect upcaster be found")
+		}
+		if u.Match("Event", 2) {
+			t.Fatal("expect upcaster not be found")
+		}
+
+		evts, err := u.Upcast(ctx, RawEvent{Type: "Legacy", Data: map[string]any{"Value": "foo"}})
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		want := []RawEvent{
+			{Type: "Event", Revision: 2, Data: map[string]any{"Val": "foo"}},
+			{Type: "Event2", Revision: 2, Data: map[string]any{"Val": "foo"}},
+		}
+		if got := evts; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("with error", func(t *testing.T) {
+		errMock := errors.New("upcaster mock error")
+		u := NewUpcasters().
+			Set("Event", 0, func(ctx context.Context, evt RawEvent) ([]RawEvent, error) {
+				return nil, errMock
+			}).
+			Set("Event2", 0, func(ctx context.Context, evt RawEvent) ([]RawEvent, error) {
+				return []RawEvent{{Type: "Event3"}}, nil
+			}).
+			Set("Event3", 0, func(ctx context.Context, evt RawEvent) ([]RawEvent, error) {
+				return []RawEvent{{Type: "Event2"}}, nil
+			})
+
+		_, err := u.Upcast(ctx, RawEvent{Type: "Event"})
+		if want, got := ErrUpcastEventFailed, err; !errors.Is(got, want) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := errMock, err; !errors.Is(got, want) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// cyclic upcasters
+		_, err = u.Upcast(ctx, RawEvent{Type: "Event2"})
+		if want, got := ErrUpcastEventFailed, err; !errors.Is(got, want) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+}
+
+func TestRenumberVersions(t *testing.T) {
+	v1 := VersionMin
+	v2 := VersionMin.Add(1, 0)
+
+	vers := []Version{
+		v1, v1.Add(0, 1), v1.Add(0, 1), v1.Add(0, 2).EOF(),
+		v2.EOF(), v2.EOF(),
+		VersionZero,
+	}
+
+	want := []Version{
+		v1, v1.Add(0, 1), v1.Add(0, 2), v1.Add(0, 3).EOF(),
+		v2, v2.Add(0, 1).EOF(),
+		VersionZero,
+	}
+	if got := RenumberVersions(vers); !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestRenumberEvents(t *testing.T) {
+	ctx := context.Background()
+
+	stmID := NewStreamID("tenantID", "service")
+	envs := Wrap(ctx, stmID, []any{&Event{Val: "1"}, &Event{Val: "2"}, &Event2{Val: "3"}},
+		WithVersionIncr(VersionMin, 3, VersionSeqDiffFracPart),
+		WithGlobalVersionIncr(VersionMin, 3, VersionSeqDiffFracPart),
+	)
+	// the first event is split into the first two ones, which share the same versions
+	for i, ver := range []Version{VersionMin, VersionMin, VersionMin.Add(0, 1).EOF()} {
+		envs[i].(RWEnvelope).SetVersion(ver)
+		MustGlobalVersionSetter(envs[i]).SetGlobalVersion(ver)
+	}
+
+	// another stream's record, with the same stream version, follows in the same global stream
+	stmID2 := NewStreamID("tenantID", "service2")
+	envs = append(envs, Wrap(ctx, stmID2, []any{&Event{Val: "4"}},
+		WithVersionIncr(VersionMin, 1, VersionSeqDiffFracPart),
+		WithGlobalVersionIncr(VersionMin.Add(1, 0), 1, VersionSeqDiffFracPart),
+	)...)
+
+	RenumberEvents(envs)
+
+	wantVers := []Version{VersionMin, VersionMin.Add(0, 1), VersionMin.Add(0, 2).EOF(), VersionMin.EOF()}
+	wantGVers := []Version{VersionMin, VersionMin.Add(0, 1), VersionMin.Add(0, 2).EOF(), VersionMin.Add(1, 0).EOF()}
+	for i, env := range envs {
+		if want, got := wantVers[i], env.Version(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := wantGVers[i], env.GlobalVersion(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
+
+func TestRegister_Revision(t *testing.T) {
+	reg := NewRegister("")
+	defer reg.Clear()
+
+	reg.
+		Set(&Event{}).
+		Set(&Event2{}, WithRevision(2))
+
+	if want, got := 0, reg.Revision(TypeOf(&Event{})); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := 2, reg.Revision(TypeOf(&Event2{})); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := 0, reg.Revision("NotFound"); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
@@ -2,6 +2,7 @@ package eventtest
 
 import (
 	"fmt"
+	"maps"
 	"math/rand"
 	"reflect"
 	"strconv"
@@ -80,8 +81,13 @@ func FormatEnv(env event.Envelope) string {
 		ver: %v
 		gVer: %v
 		user: %s
+		corrID: %s
+		causID: %s
+		traceID: %s
+		meta: %v
 		data: %v
-	`, env.StreamID(), env.Type(), env.ID(), env.At().UnixNano(), env.Version(), env.GlobalVersion(), env.User(), env.Event())
+	`, env.StreamID(), env.Type(), env.ID(), env.At().UnixNano(), env.Version(), env.GlobalVersion(), env.User(),
+		env.CorrelationID(), env.CausationID(), env.TraceID(), env.Metadata(), env.Event())
 }
 
 func CmpEnv(env1, env2 event.Envelope) bool {
@@ -89,6 +95,10 @@ func CmpEnv(env1, env2 event.Envelope) bool {
 		env1.StreamID() == env2.StreamID() &&
 		env1.GlobalStreamID() == env2.GlobalStreamID() &&
 		env1.User() == env2.User() &&
+		env1.CorrelationID() == env2.CorrelationID() &&
+		env1.CausationID() == env2.CausationID() &&
+		env1.TraceID() == env2.TraceID() &&
+		maps.Equal(env1.Metadata(), env2.Metadata()) &&
 		env1.At().Equal(env2.At()) &&
 		env1.Version().Equal(env2.Version())
 	if !metaOK {
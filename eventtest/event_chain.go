@@ -0,0 +1,67 @@
+package eventtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/sourcing"
+)
+
+func TestEventChainVerifier(t *testing.T, ctx context.Context, store interface {
+	event.Store
+	sourcing.Store
+	event.StreamReplayer
+	event.ChainVerifier
+}) {
+	t.Helper()
+
+	globalID := event.UID().String()
+	streamA, streamB := event.NewStreamID(globalID, "A"), event.NewStreamID(globalID, "B")
+
+	records := [][]event.Envelope{
+		event.Wrap(ctx, streamA, GenEvents(3)),
+		event.Wrap(ctx, streamB, GenEvents(1), event.WithMetadata(map[string]string{"key": "value"})),
+		event.Wrap(ctx, streamA, GenEvents(2)),
+	}
+	for i, envs := range records {
+		id := streamA
+		if i == 1 {
+			id = streamB
+		}
+		if err := store.Append(ctx, id, envs); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+	}
+	chunk := sourcing.Wrap(ctx, event.NewStreamID(globalID, "C"), event.VersionZero, GenEvents(2))
+	if err := store.AppendToStream(ctx, chunk); err != nil {
+		t.Fatalf("expect to append events, got err: %v", err)
+	}
+
+	t.Run("verify global stream", func(t *testing.T) {
+		if err := store.Verify(ctx, event.NewStreamID(globalID)); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		// the chain is per global stream
+		if err := store.Verify(ctx, streamB); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	})
+
+	t.Run("verify range", func(t *testing.T) {
+		from := records[1][0].GlobalVersion()
+		to := records[2][0].GlobalVersion()
+		if err := store.Verify(ctx, event.NewStreamID(globalID), from, to); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if err := store.Verify(ctx, event.NewStreamID(globalID), to); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	})
+
+	t.Run("verify empty stream", func(t *testing.T) {
+		if err := store.Verify(ctx, event.NewStreamID(event.UID().String())); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package eventtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ln80/event-store/event"
+)
+
+func TestEventStoreExpiry(t *testing.T, ctx context.Context, store interface {
+	event.Store
+	event.StreamReplayer
+	event.ExpiryPurger
+}) {
+	t.Helper()
+
+	replay := func(t *testing.T, id event.StreamID, q event.StreamReplayQuery) []event.Envelope {
+		t.Helper()
+		envs := []event.Envelope{}
+		if err := store.Replay(ctx, id, q, func(ctx context.Context, data event.StreamData) error {
+			if data.Type == event.StreamDataTypeRecord {
+				envs = append(envs, data.Value.(event.Envelope))
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("expect to replay events, got err: %v", err)
+		}
+		return envs
+	}
+
+	assertEvents := func(t *testing.T, want, got []event.Envelope) {
+		t.Helper()
+		if l := len(got); l != len(want) {
+			t.Fatalf("invalid events length, must be %d got: %d", len(want), l)
+		}
+		for i, env := range want {
+			if !CmpEnv(env, got[i]) {
+				t.Fatalf("event %d data altered %v %v", i, FormatEnv(env), FormatEnv(got[i]))
+			}
+		}
+	}
+
+	withTTL := func(env event.RWEnvelope) {
+		env.SetTTL(time.Microsecond)
+	}
+
+	streamID := event.NewStreamID(event.UID().String())
+
+	// the stream is made of an expired record, followed by an alive record, and a last expired record.
+	records := [][]event.Envelope{
+		event.Wrap(ctx, streamID, GenEvents(3), withTTL),
+		event.Wrap(ctx, streamID, GenEvents(2)),
+		event.Wrap(ctx, streamID, GenEvents(2), withTTL),
+	}
+	for _, envs := range records {
+		if err := store.Append(ctx, streamID, envs); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+	}
+	alive := append([]event.Envelope{}, records[1]...)
+
+	// wait for events to expire
+	time.Sleep(10 * time.Microsecond)
+
+	t.Run("replay skips expired events", func(t *testing.T) {
+		t.Helper()
+
+		assertEvents(t, alive, replay(t, streamID, event.StreamReplayQuery{}))
+		assertEvents(t, alive, replay(t, streamID, event.StreamReplayQuery{RecordLimit: 1}))
+	})
+
+	t.Run("purge expired records", func(t *testing.T) {
+		t.Helper()
+
+		count, err := store.PurgeExpired(ctx, time.Now().UTC())
+		if err != nil {
+			t.Fatalf("expect to purge expired events, got err: %v", err)
+		}
+		// the last record is kept as the stream head
+		if count < len(records[0]) {
+			t.Fatalf("invalid purged events count, must be at least %d got: %d", len(records[0]), count)
+		}
+
+		// replay remains consistent once expired records are purged
+		assertEvents(t, alive, replay(t, streamID, event.StreamReplayQuery{}))
+		assertEvents(t, alive, replay(t, streamID, event.StreamReplayQuery{RecordLimit: 1}))
+
+		renvs, err := store.Load(ctx, streamID)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		assertEvents(t, alive, renvs)
+
+		// the stream remains writable
+		envs := event.Wrap(ctx, streamID, GenEvents(2))
+		if err := store.Append(ctx, streamID, envs); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+		alive = append(alive, envs...)
+		assertEvents(t, alive, replay(t, streamID, event.StreamReplayQuery{}))
+	})
+
+	t.Run("reaper", func(t *testing.T) {
+		t.Helper()
+
+		// the former stream head is no longer the last record, another expired record is appended as well
+		more := [][]event.Envelope{
+			event.Wrap(ctx, streamID, GenEvents(2), withTTL),
+			event.Wrap(ctx, streamID, GenEvents(1)),
+		}
+		for _, envs := range more {
+			if err := store.Append(ctx, streamID, envs); err != nil {
+				t.Fatalf("expect to append events, got err: %v", err)
+			}
+		}
+		alive = append(alive, more[1]...)
+
+		time.Sleep(10 * time.Microsecond)
+
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		if err := event.NewReaper(store, func(rc *event.ReaperConfig) {
+			rc.Interval = time.Millisecond
+		}).Run(ctx); err != nil {
+			t.Fatalf("expect reaper to stop without error, got err: %v", err)
+		}
+
+		// expired records are already purged by the reaper
+		count, err := store.PurgeExpired(context.Background(), time.Now().UTC())
+		if err != nil {
+			t.Fatalf("expect to purge expired events, got err: %v", err)
+		}
+		if count != 0 {
+			t.Fatalf("invalid purged events count, must be 0 got: %d", count)
+		}
+		assertEvents(t, alive, replay(t, streamID, event.StreamReplayQuery{}))
+	})
+}
@@ -0,0 +1,260 @@
+package eventtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/sourcing"
+)
+
+func TestEventStreamLifecycle(t *testing.T, ctx context.Context, store interface {
+	event.Store
+	sourcing.Store
+	event.StreamReplayer
+	event.StreamQuerier
+	event.StreamLifecycle
+}, cold interface {
+	event.Store
+	sourcing.Store
+}) {
+	t.Helper()
+
+	replay := func(id event.StreamID) []event.Envelope {
+		t.Helper()
+		envs := []event.Envelope{}
+		if err := store.Replay(ctx, id, event.StreamReplayQuery{}, func(ctx context.Context, data event.StreamData) error {
+			if data.Type == event.StreamDataTypeRecord {
+				envs = append(envs, data.Value.(event.Envelope))
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("expect to replay events, got err: %v", err)
+		}
+		return envs
+	}
+
+	query := func(id event.StreamID) []event.Envelope {
+		t.Helper()
+		result, err := store.Query(ctx, id, event.StreamQuery{RecordLimit: 100})
+		if err != nil {
+			t.Fatalf("expect to query events, got err: %v", err)
+		}
+		return result.Events
+	}
+
+	// countOf returns the count of the given envelopes that belong to the given stream
+	countOf := func(envs []event.Envelope, id event.StreamID) int {
+		count := 0
+		for _, env := range envs {
+			if env.StreamID() == id.String() {
+				count++
+			}
+		}
+		return count
+	}
+
+	// append appends the given count of records to the given stream, and returns the appended events
+	appendRecords := func(id event.StreamID, count int) []event.Envelope {
+		t.Helper()
+		stm, err := store.LoadStream(ctx, id)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		ver := stm.Version()
+		envs := []event.Envelope{}
+		for i := 0; i < count; i++ {
+			chunk := sourcing.Wrap(ctx, id, ver, GenEvents(2))
+			if err := store.AppendToStream(ctx, chunk); err != nil {
+				t.Fatalf("expect to append events, got err: %v", err)
+			}
+			ver = chunk.Version()
+			envs = append(envs, chunk.Unwrap()...)
+		}
+		return envs
+	}
+
+	checkStatus := func(id event.StreamID, want event.StreamStatus) {
+		t.Helper()
+		got, err := store.Status(ctx, id)
+		if err != nil {
+			t.Fatalf("expect err be nil, got %v", err)
+		}
+		if want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	checkClosed := func(id event.StreamID, target error) {
+		t.Helper()
+		stm, err := store.LoadStream(ctx, id)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		if err := store.AppendToStream(ctx, sourcing.Wrap(ctx, id, stm.Version(), GenEvents(1))); !errors.Is(err, target) {
+			t.Fatalf("expect %v error to occur, got: %v", target, err)
+		}
+		if err := store.Append(ctx, id, event.Wrap(ctx, id, GenEvents(1))); !errors.Is(err, target) {
+			t.Fatalf("expect %v error to occur, got: %v", target, err)
+		}
+	}
+
+	checkLoaded := func(id event.StreamID, want []event.Envelope) {
+		t.Helper()
+		renvs, err := store.Load(ctx, id)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		stm, err := store.LoadStream(ctx, id)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		for _, got := range [][]event.Envelope{renvs, stm.Unwrap()} {
+			if want, got := len(want), len(got); want != got {
+				t.Fatalf("invalid loaded events length, must be %d got: %d", want, got)
+			}
+			for i, env := range want {
+				if !CmpEnv(env, got[i]) {
+					t.Fatalf("event %d data altered %v %v", i, FormatEnv(env), FormatEnv(got[i]))
+				}
+			}
+		}
+	}
+
+	t.Run("soft delete and restore stream", func(t *testing.T) {
+		globalID := event.UID().String()
+		streamA, streamB := event.NewStreamID(globalID, "A"), event.NewStreamID(globalID, "B")
+		envsA := appendRecords(streamA, 2)
+		appendRecords(streamB, 1)
+
+		checkStatus(streamA, event.StreamActive)
+
+		if err := store.SoftDelete(ctx, streamA); err != nil {
+			t.Fatalf("expect err be nil, got %v", err)
+		}
+		checkStatus(streamA, event.StreamSoftDeleted)
+
+		// the stream is hidden
+		checkLoaded(streamA, nil)
+		if want, got := 0, countOf(replay(event.NewStreamID(globalID)), streamA); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := 0, countOf(query(event.NewStreamID(globalID)), streamA); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := 2, countOf(replay(event.NewStreamID(globalID)), streamB); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		checkClosed(streamA, event.ErrStreamDeleted)
+
+		// the stream is restored as is
+		if err := store.Restore(ctx, streamA); err != nil {
+			t.Fatalf("expect err be nil, got %v", err)
+		}
+		checkStatus(streamA, event.StreamActive)
+		checkLoaded(streamA, envsA)
+		if want, got := len(envsA), countOf(replay(event.NewStreamID(globalID)), streamA); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		appendRecords(streamA, 1)
+	})
+
+	t.Run("hard delete stream", func(t *testing.T) {
+		globalID := event.UID().String()
+		streamA, streamB := event.NewStreamID(globalID, "A"), event.NewStreamID(globalID, "B")
+		appendRecords(streamA, 2)
+		appendRecords(streamB, 1)
+
+		if err := store.HardDelete(ctx, streamA); err != nil {
+			t.Fatalf("expect err be nil, got %v", err)
+		}
+		checkStatus(streamA, event.StreamDeleted)
+
+		checkLoaded(streamA, nil)
+		checkClosed(streamA, event.ErrStreamDeleted)
+		if err := store.Restore(ctx, streamA); !errors.Is(err, event.ErrStreamDeleted) {
+			t.Fatalf("expect %v error to occur, got: %v", event.ErrStreamDeleted, err)
+		}
+
+		// only the tombstone is kept, the other streams are left untouched
+		for _, envs := range [][]event.Envelope{replay(event.NewStreamID(globalID)), query(event.NewStreamID(globalID))} {
+			if want, got := 1, countOf(envs, streamA); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+			if want, got := 2, countOf(envs, streamB); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+			last := envs[len(envs)-1]
+			if _, ok := last.Event().(*event.StreamTombstone); !ok || last.StreamID() != streamA.String() {
+				t.Fatalf("expect tombstone event be found, got %v", FormatEnv(last))
+			}
+		}
+	})
+
+	t.Run("truncate stream", func(t *testing.T) {
+		streamID := event.NewStreamID(event.UID().String())
+		envs := appendRecords(streamID, 3)
+
+		// retain the last two records
+		if err := store.TruncateBefore(ctx, streamID, envs[2].Version()); err != nil {
+			t.Fatalf("expect err be nil, got %v", err)
+		}
+		checkLoaded(streamID, envs[2:])
+		if want, got := 4, len(replay(streamID)); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := 4, len(query(streamID)); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// the last record is always kept
+		if err := store.TruncateBefore(ctx, streamID, event.VersionMax); err != nil {
+			t.Fatalf("expect err be nil, got %v", err)
+		}
+		checkLoaded(streamID, envs[4:])
+
+		// the stream sequence is preserved
+		envs = append(envs[4:], appendRecords(streamID, 1)...)
+		checkLoaded(streamID, envs)
+	})
+
+	t.Run("archive stream", func(t *testing.T) {
+		globalID := event.UID().String()
+		streamA, streamB := event.NewStreamID(globalID, "A"), event.NewStreamID(globalID, "B")
+		envsA := appendRecords(streamA, 2)
+		appendRecords(streamB, 1)
+
+		if err := store.Archive(ctx, streamA, cold); err != nil {
+			t.Fatalf("expect err be nil, got %v", err)
+		}
+		checkStatus(streamA, event.StreamArchived)
+
+		// archived events are loaded from the cold store
+		checkLoaded(streamA, envsA)
+		renvs, err := cold.Load(ctx, streamA)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		if want, got := len(envsA), len(renvs); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// archived events are excluded from replay and query
+		for _, envs := range [][]event.Envelope{replay(event.NewStreamID(globalID)), query(event.NewStreamID(globalID))} {
+			if want, got := 0, countOf(envs, streamA); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+			if want, got := 2, countOf(envs, streamB); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+
+		if err := store.Append(ctx, streamA, event.Wrap(ctx, streamA, GenEvents(1))); !errors.Is(err, event.ErrStreamArchived) {
+			t.Fatalf("expect %v error to occur, got: %v", event.ErrStreamArchived, err)
+		}
+		if err := store.SoftDelete(ctx, streamA); !errors.Is(err, event.ErrStreamArchived) {
+			t.Fatalf("expect %v error to occur, got: %v", event.ErrStreamArchived, err)
+		}
+	})
+}
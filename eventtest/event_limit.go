@@ -0,0 +1,98 @@
+package eventtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/sourcing"
+)
+
+// TestEventStoreSizeLimit expects the given store to be configured using the given size limit.
+// The record limit must be greater than the event one.
+func TestEventStoreSizeLimit(t *testing.T, ctx context.Context, store event.Store, limit event.SizeLimit) {
+	t.Helper()
+
+	if limit.Event <= 0 || limit.Record <= limit.Event {
+		t.Fatalf("invalid size limit, the record limit must be greater than the event one, got: %+v", limit)
+	}
+
+	genEvents := func(count, textSize int) []any {
+		events := make([]any, count)
+		for i := range events {
+			events[i] = &Event2{
+				ID:       "id",
+				Val:      "val",
+				LongText: generateRandomText(textSize),
+			}
+		}
+		return events
+	}
+
+	t.Run("event size limit exceeded", func(t *testing.T) {
+		t.Helper()
+		streamID := event.NewStreamID(event.UID().String())
+
+		envs := event.Wrap(ctx, streamID, genEvents(1, limit.Event))
+		if err := store.Append(ctx, streamID, envs); !errors.Is(err, event.ErrEventSizeLimitExceeded) {
+			t.Fatalf("expect err be %v, got: %v", event.ErrEventSizeLimitExceeded, err)
+		}
+
+		renvs, err := store.Load(ctx, streamID)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		if l := len(renvs); l != 0 {
+			t.Fatalf("invalid loaded events length, must be %d got: %d", 0, l)
+		}
+	})
+
+	t.Run("record size limit exceeded", func(t *testing.T) {
+		t.Helper()
+		streamID := event.NewStreamID(event.UID().String())
+
+		// each event is within the event limit, while the whole record is not
+		textSize := limit.Event / 4
+		envs := event.Wrap(ctx, streamID, genEvents(limit.Record/textSize+1, textSize))
+		if err := store.Append(ctx, streamID, envs); !errors.Is(err, event.ErrEventSizeLimitExceeded) {
+			t.Fatalf("expect err be %v, got: %v", event.ErrEventSizeLimitExceeded, err)
+		}
+
+		renvs, err := store.Load(ctx, streamID)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		if l := len(renvs); l != 0 {
+			t.Fatalf("invalid loaded events length, must be %d got: %d", 0, l)
+		}
+
+		sstore, ok := store.(sourcing.Store)
+		if !ok {
+			return
+		}
+		envs = event.Wrap(ctx, streamID, genEvents(limit.Record/textSize+1, textSize),
+			event.WithVersionIncr(event.VersionMin, limit.Record/textSize+1, event.VersionSeqDiffPart))
+		if err := sstore.AppendToStream(ctx, *sourcing.NewStream(streamID, envs)); !errors.Is(err, event.ErrEventSizeLimitExceeded) {
+			t.Fatalf("expect err be %v, got: %v", event.ErrEventSizeLimitExceeded, err)
+		}
+	})
+
+	t.Run("within size limit", func(t *testing.T) {
+		t.Helper()
+		streamID := event.NewStreamID(event.UID().String())
+
+		envs := event.Wrap(ctx, streamID, genEvents(2, limit.Event/4))
+		if err := store.Append(ctx, streamID, envs); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+
+		renvs, err := store.Load(ctx, streamID)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		if l := len(renvs); l != 2 {
+			t.Fatalf("invalid loaded events length, must be %d got: %d", 2, l)
+		}
+	})
+}
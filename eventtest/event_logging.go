@@ -15,7 +15,7 @@ func TestEventLoggingStore(t *testing.T, ctx context.Context, store event.Store)
 		t.Helper()
 		streamID := event.NewStreamID(event.UID().String())
 		// test append events to stream
-		envs := event.Wrap(ctx, streamID, GenEvents(10))
+		envs := event.Wrap(ctx, streamID, GenEvents(10), event.WithMetadata(map[string]string{"source": "eventtest"}))
 		if err := event.Stream(envs).Validate(func(v *event.Validation) {
 			v.SkipVersion = true
 		}); err != nil {
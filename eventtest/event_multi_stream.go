@@ -0,0 +1,141 @@
+package eventtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/sourcing"
+)
+
+func TestEventMultiStreamStore(t *testing.T, ctx context.Context, store sourcing.MultiStreamStore) {
+	t.Helper()
+
+	globalID := event.UID().String()
+	accountA := event.NewStreamID(globalID, "account", "A")
+	accountB := event.NewStreamID(globalID, "account", "B")
+
+	loadStream := func(id event.StreamID) *sourcing.Stream {
+		t.Helper()
+		stm, err := store.LoadStream(ctx, id)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		return stm
+	}
+
+	t.Run("multi-stream append", func(t *testing.T) {
+		t.Helper()
+
+		chunkA := sourcing.Wrap(ctx, accountA, event.VersionZero, GenEvents(2))
+		chunkB := sourcing.Wrap(ctx, accountB, event.VersionZero, GenEvents(3))
+		if err := store.AppendToStreams(ctx, []sourcing.Stream{chunkA, chunkB}); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+
+		renvsA, renvsB := loadStream(accountA).Unwrap(), loadStream(accountB).Unwrap()
+		for _, c := range []struct {
+			envs, renvs []event.Envelope
+		}{
+			{envs: chunkA.Unwrap(), renvs: renvsA},
+			{envs: chunkB.Unwrap(), renvs: renvsB},
+		} {
+			if want, got := len(c.envs), len(c.renvs); want != got {
+				t.Fatalf("invalid loaded events length, must be %d got: %d", want, got)
+			}
+			for i, env := range c.envs {
+				if !CmpEnv(env, c.renvs[i]) {
+					t.Fatalf("event %d data altered %v %v", i, FormatEnv(env), FormatEnv(c.renvs[i]))
+				}
+			}
+		}
+
+		// each chunk is appended as a record with consecutive global versions
+		lastA, firstB := renvsA[len(renvsA)-1].GlobalVersion(), renvsB[0].GlobalVersion()
+		if !firstB.Next(lastA) {
+			t.Fatalf("expect global versions %v, %v be consecutive", lastA, firstB)
+		}
+	})
+
+	t.Run("multi-stream append is atomic", func(t *testing.T) {
+		t.Helper()
+
+		verA, verB := loadStream(accountA).Version(), loadStream(accountB).Version()
+
+		// the chunk of stream B conflicts with its current version
+		chunkA := sourcing.Wrap(ctx, accountA, verA, GenEvents(1))
+		chunkB := sourcing.Wrap(ctx, accountB, event.VersionZero, GenEvents(1))
+		err := store.AppendToStreams(ctx, []sourcing.Stream{chunkA, chunkB})
+		if !errors.Is(err, event.ErrAppendEventsConflict) {
+			t.Fatalf("expect conflict error to occur, got: %v", err)
+		}
+
+		// expected versions are inferred per chunk, the option is not supported
+		chunkA = sourcing.Wrap(ctx, accountA, verA, GenEvents(1))
+		chunkC := sourcing.Wrap(ctx, event.NewStreamID(globalID, "account", "C"), event.VersionZero, GenEvents(1))
+		err = store.AppendToStreams(ctx, []sourcing.Stream{chunkC, chunkA}, event.WithExpectedVersion(event.AnyVersion))
+		if !errors.Is(err, event.ErrUnsupportedAppendOption) {
+			t.Fatalf("expect %v error to occur, got: %v", event.ErrUnsupportedAppendOption, err)
+		}
+
+		// none of the chunks is written
+		if want, got := verA, loadStream(accountA).Version(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := verB, loadStream(accountB).Version(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if stm := loadStream(chunkC.ID()); !stm.Empty() {
+			t.Fatalf("expect stream %s be empty", chunkC.ID())
+		}
+
+		// the global stream sequence is not altered
+		chunkA = sourcing.Wrap(ctx, accountA, verA, GenEvents(1))
+		chunkB = sourcing.Wrap(ctx, accountB, verB, GenEvents(1))
+		if err := store.AppendToStreams(ctx, []sourcing.Stream{chunkA, chunkB}); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+		renvsA, renvsB := loadStream(accountA).Unwrap(), loadStream(accountB).Unwrap()
+		if l := len(renvsB); l < 2 {
+			t.Fatalf("invalid loaded events length, got: %d", l)
+		}
+		prevB, lastA, lastB := renvsB[len(renvsB)-2].GlobalVersion(), renvsA[len(renvsA)-1].GlobalVersion(), renvsB[len(renvsB)-1].GlobalVersion()
+		if !lastA.Trunc().Equal(prevB.Trunc().Incr()) || !lastB.Next(lastA) {
+			t.Fatalf("expect global versions %v, %v, %v be consecutive", prevB, lastA, lastB)
+		}
+	})
+
+	t.Run("multi-stream append invalid chunks", func(t *testing.T) {
+		t.Helper()
+
+		verA := loadStream(accountA).Version()
+
+		// chunks must belong to the same global stream
+		chunkA := sourcing.Wrap(ctx, accountA, verA, GenEvents(1))
+		other := event.NewStreamID(event.UID().String(), "account", "A")
+		chunkO := sourcing.Wrap(ctx, other, event.VersionZero, GenEvents(1))
+		err := store.AppendToStreams(ctx, []sourcing.Stream{chunkA, chunkO})
+		if !errors.Is(err, sourcing.ErrInvalidMultiStreamChunks) {
+			t.Fatalf("expect invalid chunks error to occur, got: %v", err)
+		}
+
+		// chunks must target distinct streams
+		err = store.AppendToStreams(ctx, []sourcing.Stream{chunkA, sourcing.Wrap(ctx, accountA, verA, GenEvents(1))})
+		if !errors.Is(err, sourcing.ErrInvalidMultiStreamChunks) {
+			t.Fatalf("expect invalid chunks error to occur, got: %v", err)
+		}
+
+		if want, got := verA, loadStream(accountA).Version(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if stm := loadStream(other); !stm.Empty() {
+			t.Fatalf("expect stream %s be empty", other)
+		}
+
+		// empty chunks are ignored
+		if err := store.AppendToStreams(ctx, []sourcing.Stream{*sourcing.NewStream(accountA, nil)}); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package eventtest
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/sourcing"
+)
+
+func TestEventStreamRetention(t *testing.T, ctx context.Context, store interface {
+	event.Store
+	sourcing.Store
+	event.StreamReplayer
+	event.StreamQuerier
+	event.StreamSubscriber
+	event.StreamMetadataStore
+	event.RetentionCompactor
+}) {
+	t.Helper()
+
+	replay := func(t *testing.T, id event.StreamID) []event.Envelope {
+		t.Helper()
+		envs := []event.Envelope{}
+		if err := store.Replay(ctx, id, event.StreamReplayQuery{}, func(ctx context.Context, data event.StreamData) error {
+			if data.Type == event.StreamDataTypeRecord {
+				envs = append(envs, data.Value.(event.Envelope))
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("expect to replay events, got err: %v", err)
+		}
+		return envs
+	}
+
+	assertEvents := func(t *testing.T, want, got []event.Envelope) {
+		t.Helper()
+		if l := len(got); l != len(want) {
+			t.Fatalf("invalid events length, must be %d got: %d", len(want), l)
+		}
+		for i, env := range want {
+			if !CmpEnv(env, got[i]) {
+				t.Fatalf("event %d data altered %v %v", i, FormatEnv(env), FormatEnv(got[i]))
+			}
+		}
+	}
+
+	query := func(t *testing.T, id event.StreamID) []event.Envelope {
+		t.Helper()
+		result, err := store.Query(ctx, id, event.StreamQuery{RecordLimit: 100})
+		if err != nil {
+			t.Fatalf("expect to query events, got err: %v", err)
+		}
+		return result.Events
+	}
+
+	// subscribe returns the events delivered by a subscription until it catches up
+	subscribe := func(t *testing.T, id event.StreamID) []event.Envelope {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		envs := []event.Envelope{}
+		if err := store.Subscribe(ctx, id, event.VersionZero, func(ctx context.Context, data event.StreamData) error {
+			switch data.Type {
+			case event.StreamDataTypeRecord:
+				envs = append(envs, data.Value.(event.Envelope))
+			case event.StreamDataTypeContinue:
+				cancel()
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("expect to subscribe to events, got err: %v", err)
+		}
+		return envs
+	}
+
+	// assertRead asserts that retention is enforced by stream reads, regardless of records compaction
+	assertRead := func(t *testing.T, id event.StreamID, want []event.Envelope) {
+		t.Helper()
+		assertEvents(t, want, replay(t, id))
+		assertEvents(t, want, query(t, id))
+		assertEvents(t, want, subscribe(t, id))
+	}
+
+	assertLoaded := func(t *testing.T, id event.StreamID, want []event.Envelope) {
+		t.Helper()
+		renvs, err := store.Load(ctx, id)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		assertEvents(t, want, renvs)
+
+		stm, err := store.LoadStream(ctx, id)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		assertEvents(t, want, stm.Unwrap())
+	}
+
+	// appendRecords appends the given count of records to the given stream, and returns the appended events
+	appendRecords := func(t *testing.T, id event.StreamID, count int) []event.Envelope {
+		t.Helper()
+		stm, err := store.LoadStream(ctx, id)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		ver := stm.Version()
+		envs := []event.Envelope{}
+		for i := 0; i < count; i++ {
+			chunk := sourcing.Wrap(ctx, id, ver, GenEvents(2))
+			if err := store.AppendToStream(ctx, chunk); err != nil {
+				t.Fatalf("expect to append events, got err: %v", err)
+			}
+			ver = chunk.Version()
+			envs = append(envs, chunk.Unwrap()...)
+		}
+		return envs
+	}
+
+	t.Run("set and get stream metadata", func(t *testing.T) {
+		streamID := event.NewStreamID(event.UID().String())
+
+		md, err := store.GetStreamMetadata(ctx, streamID)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if md.HasRetention() || md.Owner != "" {
+			t.Fatalf("expect zero metadata, got %+v", md)
+		}
+
+		want := event.StreamMetadata{
+			MaxAge:     time.Hour,
+			MaxCount:   10,
+			Owner:      "billing",
+			ACL:        []string{"read:support"},
+			Properties: map[string]string{"tier": "gold"},
+		}
+		if err := store.SetStreamMetadata(ctx, streamID, want); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		got, err := store.GetStreamMetadata(ctx, streamID)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want.MaxAge != got.MaxAge ||
+			want.MaxCount != got.MaxCount ||
+			want.Owner != got.Owner ||
+			!slices.Equal(want.ACL, got.ACL) ||
+			!maps.Equal(want.Properties, got.Properties) {
+			t.Fatalf("expect %+v, %+v be equals", want, got)
+		}
+	})
+
+	t.Run("retain by max count", func(t *testing.T) {
+		streamID := event.NewStreamID(event.UID().String())
+		envs := appendRecords(t, streamID, 3)
+
+		if err := store.SetStreamMetadata(ctx, streamID, event.StreamMetadata{MaxCount: 3}); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		// records are retained as a whole
+		assertLoaded(t, streamID, envs[2:])
+		assertRead(t, streamID, envs[2:])
+
+		count, err := store.CompactRetention(ctx, time.Now().UTC())
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := 2, count; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		assertRead(t, streamID, envs[2:])
+		assertLoaded(t, streamID, envs[2:])
+
+		// the stream sequence is preserved
+		envs = append(envs[4:], appendRecords(t, streamID, 1)...)
+		assertLoaded(t, streamID, envs)
+	})
+
+	t.Run("retain by max age", func(t *testing.T) {
+		streamID := event.NewStreamID(event.UID().String())
+		envs := appendRecords(t, streamID, 2)
+
+		if err := store.SetStreamMetadata(ctx, streamID, event.StreamMetadata{MaxAge: time.Millisecond}); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		// wait for records to age
+		time.Sleep(5 * time.Millisecond)
+
+		// the last record is kept as the stream head
+		assertLoaded(t, streamID, envs[2:])
+		assertRead(t, streamID, envs[2:])
+
+		count, err := store.CompactRetention(ctx, time.Now().UTC())
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		// the count may include events of other streams
+		if count < 2 {
+			t.Fatalf("invalid compacted events count, must be at least %d got: %d", 2, count)
+		}
+		assertRead(t, streamID, envs[2:])
+
+		// the stream remains writable
+		assertLoaded(t, streamID, appendRecords(t, streamID, 1))
+	})
+
+	t.Run("compactor", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		if err := event.NewCompactor(store, func(rc *event.ReaperConfig) {
+			rc.Interval = time.Millisecond
+		}).Run(ctx); err != nil {
+			t.Fatalf("expect compactor to stop without error, got err: %v", err)
+		}
+	})
+}
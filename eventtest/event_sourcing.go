@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/ln80/event-store/event"
+	event_errors "github.com/ln80/event-store/event/errors"
 	"github.com/ln80/event-store/event/sourcing"
 )
 
@@ -76,4 +77,80 @@ func TestEventSourcingStore(t *testing.T, ctx context.Context, store sourcing.St
 			t.Fatalf("event data altered %v %v", FormatEnv(renvs[0]), FormatEnv(envs[1]))
 		}
 	})
+	t.Run("event sourcing expected version", func(t *testing.T) {
+		t.Helper()
+		streamID := event.NewStreamID(event.UID().String())
+
+		checkConflict := func(err, target error, actual event.Version) {
+			t.Helper()
+			if !errors.Is(err, event.ErrAppendEventsConflict) || !errors.Is(err, target) {
+				t.Fatalf("expect %v conflict error to occur, got: %v", target, err)
+			}
+			ok, conflict := event_errors.ErrAs[event.VersionConflict](err)
+			if !ok {
+				t.Fatalf("expect version conflict details be found, got: %v", err)
+			}
+			if want, got := actual, conflict.Actual; !want.Equal(got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+
+		// the stream is required to exist
+		stm := sourcing.Wrap(ctx, streamID, event.VersionZero, GenEvents(2))
+		err := store.AppendToStream(ctx, stm, event.WithExpectedVersion(event.StreamExists))
+		checkConflict(err, event.ErrStreamNotFound, event.VersionZero)
+
+		// create the stream only if new
+		if err := store.AppendToStream(ctx, stm, event.WithExpectedVersion(event.NoStream)); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+		err = store.AppendToStream(ctx,
+			sourcing.Wrap(ctx, streamID, event.VersionZero, GenEvents(2)),
+			event.WithExpectedVersion(event.NoStream))
+		checkConflict(err, event.ErrStreamAlreadyExists, stm.Version())
+
+		// append regardless of the current version, versions are computed by the store
+		stm2 := sourcing.Wrap(ctx, streamID, event.VersionZero, GenEvents(3))
+		if err := store.AppendToStream(ctx, stm2, event.WithExpectedVersion(event.AnyVersion)); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+		if want, got := stm.Version().Trunc().Incr(), stm2.Unwrap()[0].Version(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		current := stm2.Version()
+		if want, got := stm2.Unwrap()[2].Version(), current; !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// append only if the current version matches the exact one
+		err = store.AppendToStream(ctx,
+			sourcing.Wrap(ctx, streamID, event.VersionZero, GenEvents(1)),
+			event.WithExpectedVersion(event.ExactVersion(stm.Version())))
+		checkConflict(err, event.ErrWrongExpectedVersion, current)
+
+		if err := store.AppendToStream(ctx,
+			sourcing.Wrap(ctx, streamID, event.VersionZero, GenEvents(1)),
+			event.WithExpectedVersion(event.ExactVersion(current))); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+		if err := store.AppendToStream(ctx,
+			sourcing.Wrap(ctx, streamID, event.VersionZero, GenEvents(1)),
+			event.WithExpectedVersion(event.StreamExists)); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+
+		rstm, err := store.LoadStream(ctx, streamID)
+		if err != nil {
+			t.Fatalf("expect to load events got err %v", err)
+		}
+		if err := rstm.Validate(); err != nil {
+			t.Fatalf("expect loaded stream be valid, got err: %v", err)
+		}
+		if want, got := 7, len(rstm.Unwrap()); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := event.VersionMin.Add(3, 0), rstm.Version(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
 }
@@ -82,7 +82,7 @@ func TestEventStreamQuerier(t *testing.T, ctx context.Context, store interface {
 
 			// Sort descending events to compare with ascending
 			slices.SortFunc(descResult.Events, func(a, b event.Envelope) int {
-				return b.At().Compare(a.At())
+				return a.At().Compare(b.At())
 			})
 
 			for i := 0; i < len(result.Events); i++ {
@@ -186,6 +186,31 @@ func TestEventStreamQuerier(t *testing.T, ctx context.Context, store interface {
 				t.Fatalf("expect cursor to return different events")
 			}
 		}
+
+		// Walk through all pages using the returned cursors
+		seen := make(map[string]struct{})
+		pageQuery := event.StreamQuery{
+			RecordLimit: 10,
+		}
+		for {
+			page, err := store.Query(ctx, event.NewStreamID(globalID), pageQuery)
+			if err != nil {
+				t.Fatalf("expect to query events with cursor, got err: %v", err)
+			}
+			for _, evt := range page.Events {
+				if _, ok := seen[evt.ID()]; ok {
+					t.Fatalf("expect event %s be returned once", evt.ID())
+				}
+				seen[evt.ID()] = struct{}{}
+			}
+			if page.Cursor == nil {
+				break
+			}
+			pageQuery.Cursor = page.Cursor
+		}
+		if want, got := 25, len(seen); want != got {
+			t.Fatalf("expect events count be %d, got %d", want, got)
+		}
 	})
 
 	t.Run("querier with filters", func(t *testing.T) {
@@ -284,4 +309,64 @@ func TestEventStreamQuerier(t *testing.T, ctx context.Context, store interface {
 			t.Fatalf("expect events count to be <= %d, got %d", q.RecordLimit, len(result.Events))
 		}
 	})
+	t.Run("querier with tracing filters", func(t *testing.T) {
+		streamID := event.NewStreamID(event.UID().String())
+
+		withTracing := func(cfg *event.AppendConfig) {
+			cfg.AddTracing = func(ctx context.Context) string {
+				return "trace-1"
+			}
+		}
+
+		// the first event starts a business flow
+		ctx1 := event.ContextWith(ctx, event.ContextValues{CorrelationID: "flow-1"})
+		envs1 := event.Wrap(ctx1, streamID, GenEvents(1))
+		if err := store.Append(ctx1, streamID, envs1, withTracing); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+
+		// the second event is caused by the first one
+		ctx2 := event.ContextWithCause(ctx, envs1[0])
+		envs2 := event.Wrap(ctx2, streamID, GenEvents(2))
+		if err := store.Append(ctx2, streamID, envs2); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+
+		// the third event is out of the business flow
+		if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, GenEvents(1))); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+
+		for _, tc := range []struct {
+			q    event.StreamQuery
+			want []event.Envelope
+		}{
+			{
+				q:    event.StreamQuery{CorrelationIDs: []string{"flow-1"}},
+				want: slices.Concat(envs1, envs2),
+			},
+			{
+				q:    event.StreamQuery{CausationIDs: []string{envs1[0].ID()}},
+				want: envs2,
+			},
+			{
+				// the trace ID is propagated through the business flow
+				q:    event.StreamQuery{TraceIDs: []string{"trace-1"}},
+				want: slices.Concat(envs1, envs2),
+			},
+		} {
+			result, err := store.Query(ctx, streamID, tc.q)
+			if err != nil {
+				t.Fatalf("expect to query events, got err: %v", err)
+			}
+			if want, got := len(tc.want), len(result.Events); want != got {
+				t.Fatalf("expect events count be %d, got %d", want, got)
+			}
+			for i, env := range tc.want {
+				if !CmpEnv(env, result.Events[i]) {
+					t.Fatalf("event %d data altered %v %v", i, FormatEnv(env), FormatEnv(result.Events[i]))
+				}
+			}
+		}
+	})
 }
@@ -0,0 +1,171 @@
+package eventtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ln80/event-store/event"
+)
+
+type TestEventStreamSubscriberOptions struct {
+	// Timeout defines the max duration to wait for events to be delivered.
+	Timeout time.Duration
+}
+
+func TestEventStreamSubscriber(t *testing.T, ctx context.Context, store interface {
+	event.StreamSubscriber
+	event.Store
+}, opts ...func(*TestEventStreamSubscriberOptions)) {
+	t.Helper()
+
+	opt := &TestEventStreamSubscriberOptions{
+		Timeout: 5 * time.Second,
+	}
+	for _, optFn := range opts {
+		if optFn == nil {
+			continue
+		}
+		optFn(opt)
+	}
+
+	// global stream ID
+	globalID := event.UID().String()
+
+	// init two sub-streams
+	streamID1 := event.NewStreamID(globalID, "service1")
+	streamID2 := event.NewStreamID(globalID, "service2")
+
+	if err := store.Append(ctx, streamID1, event.Wrap(ctx, streamID1, GenEvents(10))); err != nil {
+		t.Fatalf("expect to append events, got err: %v", err)
+	}
+
+	// subscribe runs a subscription in background and returns a function to wait for the given count of events.
+	subscribe := func(t *testing.T, id event.StreamID, from event.Version) (wait func(count int) []event.Envelope, stop func()) {
+		t.Helper()
+
+		var (
+			mu     sync.Mutex
+			events = make([]event.Envelope, 0)
+		)
+		caughtUp := make(chan struct{}, 1)
+		done := make(chan error, 1)
+
+		ctx, cancel := context.WithCancel(ctx)
+		go func() {
+			done <- store.Subscribe(ctx, id, from, func(ctx context.Context, data event.StreamData) error {
+				switch data.Type {
+				case event.StreamDataTypeRecord:
+					mu.Lock()
+					events = append(events, data.Value.(event.Envelope))
+					mu.Unlock()
+				case event.StreamDataTypeContinue:
+					select {
+					case caughtUp <- struct{}{}:
+					default:
+					}
+				}
+				return nil
+			})
+		}()
+
+		select {
+		case <-caughtUp:
+		case <-time.After(opt.Timeout):
+			t.Fatal("expect subscription to catch up")
+		}
+
+		wait = func(count int) []event.Envelope {
+			t.Helper()
+			deadline := time.After(opt.Timeout)
+			for {
+				mu.Lock()
+				l := len(events)
+				mu.Unlock()
+				if l >= count {
+					break
+				}
+				select {
+				case <-deadline:
+					t.Fatalf("expect events count be %d, got %d", count, l)
+				case <-time.After(time.Millisecond):
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]event.Envelope{}, events...)
+		}
+		stop = func() {
+			t.Helper()
+			cancel()
+			if err := <-done; err != nil {
+				t.Fatalf("expect err be nil, got %v", err)
+			}
+		}
+		return
+	}
+
+	t.Run("subscriber basic", func(t *testing.T) {
+		wait, stop := subscribe(t, event.NewStreamID(globalID), event.VersionMin)
+
+		if want, got := 10, len(wait(10)); want != got {
+			t.Fatalf("expect events count be %d, got %d", want, got)
+		}
+
+		if err := store.Append(ctx, streamID2, event.Wrap(ctx, streamID2, GenEvents(5))); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+
+		events := wait(15)
+		stop()
+
+		if want, got := 15, len(events); want != got {
+			t.Fatalf("expect events count be %d, got %d", want, got)
+		}
+
+		// check stream sequence
+		cur := event.NewCursor(globalID)
+		vOpt := func(v *event.Validation) {
+			v.GlobalStream = true
+		}
+		for _, evt := range events {
+			if _, err := event.ValidateEvent(evt, cur, vOpt); err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+		}
+	})
+
+	t.Run("subscriber from version", func(t *testing.T) {
+		wait, stop := subscribe(t, event.NewStreamID(globalID), event.VersionMin.Add(1, 0))
+
+		events := wait(5)
+		stop()
+
+		if want, got := 5, len(events); want != got {
+			t.Fatalf("expect events count be %d, got %d", want, got)
+		}
+		if want, got := event.VersionMin.Add(1, 0), events[0].GlobalVersion(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("subscriber to sub-stream", func(t *testing.T) {
+		wait, stop := subscribe(t, streamID1, event.VersionMin)
+
+		if err := store.Append(ctx, streamID1, event.Wrap(ctx, streamID1, GenEvents(2))); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+		if err := store.Append(ctx, streamID2, event.Wrap(ctx, streamID2, GenEvents(2))); err != nil {
+			t.Fatalf("expect to append events, got err: %v", err)
+		}
+		events := wait(12)
+		stop()
+
+		for _, evt := range events {
+			if want, got := streamID1.String(), evt.StreamID(); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+	})
+}
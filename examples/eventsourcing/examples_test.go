@@ -88,3 +88,69 @@ func Example() {
 	// evt 1: bank.MoneyWithdrawn, ver: 00000000000000000002.000e
 	// account balance: 500
 }
+
+// account is the state of a bank account aggregate managed by a generic repository.
+type account struct {
+	ID      string
+	Balance int
+}
+
+func (a *account) onEvent(evt any) {
+	switch evt := evt.(type) {
+	case *bank.AccountOpened:
+		a.ID = evt.ID
+	case *bank.MoneyDeposited:
+		a.Balance += evt.Amount
+	case *bank.MoneyWithdrawn:
+		a.Balance -= evt.Amount
+	}
+}
+
+func Example_repository() {
+	ctx := context.Background()
+
+	var namespace = "bank"
+
+	event.NewRegister(namespace).
+		Set(bank.AccountOpened{}).
+		Set(bank.MoneyWithdrawn{}).
+		Set(bank.MoneyDeposited{})
+
+	repo := sourcing.NewRepository(memory.NewEventStore(), (*account).onEvent, func(rc *sourcing.RepositoryConfig) {
+		rc.ConflictRetries = 3
+	})
+
+	tenantID := "faa1bb0a-e0cc-47ea-a03a-998939743c68"
+	accountID := "70a77d0c-0c8e-41f2-8251-5b82f2c40bde"
+
+	stmID := event.NewStreamID(tenantID, namespace, accountID)
+
+	// open account and deposit money
+	acc := repo.New(stmID)
+	acc.Apply(
+		&bank.AccountOpened{ID: accountID, Owner: "Paris Denesik"},
+		&bank.MoneyDeposited{AccountID: accountID, Amount: 1000},
+	)
+	if err := repo.Save(ctx, acc); err != nil {
+		log.Fatal(err)
+	}
+
+	// load the account, withdraw money, and retry in the case of a concurrent update
+	if err := repo.Update(ctx, stmID, func(acc *sourcing.Aggregate[account]) error {
+		acc.Apply(&bank.MoneyWithdrawn{AccountID: accountID, Amount: 300})
+		return nil
+	}); err != nil {
+		log.Fatal(err)
+	}
+
+	acc, err := repo.Load(ctx, stmID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("stream version: %v\n", acc.Version())
+	fmt.Printf("account balance: %d\n", acc.State().Balance)
+
+	// Output:
+	// stream version: 00000000000000000002.000e
+	// account balance: 700
+}
@@ -0,0 +1,108 @@
+package file
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	segmentExt = ".seg"
+
+	// frameHeaderSize is the size of a record frame header: payload length + payload checksum.
+	frameHeaderSize = 8
+)
+
+// errPartialFrame is returned when a frame is truncated.
+// It usually means that the process crashed in the middle of a write.
+var errPartialFrame = errors.New("partial frame")
+
+// errChecksumMismatch is returned when a complete frame does not match its checksum.
+// Unlike a partial frame, it can't be the result of an interrupted write.
+var errChecksumMismatch = errors.New("frame checksum mismatch")
+
+// segmentName returns the file name of the segment identified by the given sequence.
+func segmentName(seq uint64) string {
+	return fmt.Sprintf("%020d%s", seq, segmentExt)
+}
+
+// listSegments returns the sorted sequences of segments found in the given directory.
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	seqs := make([]uint64, 0)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), segmentExt) {
+			continue
+		}
+		var seq uint64
+		if _, err := fmt.Sscanf(strings.TrimSuffix(e.Name(), segmentExt), "%d", &seq); err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool {
+		return seqs[i] < seqs[j]
+	})
+
+	return seqs, nil
+}
+
+// writeFrame writes the payload prefixed with its length and checksum.
+func writeFrame(w io.Writer, payload []byte) (int, error) {
+	buf := make([]byte, frameHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[frameHeaderSize:], payload)
+
+	return w.Write(buf)
+}
+
+// readFrame reads the next frame payload. It returns io.EOF if there is no more frames,
+// errPartialFrame if the frame is incomplete, and errChecksumMismatch if the frame is corrupted.
+func readFrame(r io.Reader) ([]byte, error) {
+	header := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil, errPartialFrame
+		}
+		return nil, err
+	}
+
+	payload := make([]byte, binary.LittleEndian.Uint32(header[0:4]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errPartialFrame
+		}
+		return nil, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, errChecksumMismatch
+	}
+
+	return payload, nil
+}
+
+// syncDir flushes the directory entries, it's required to make new segment files durable.
+func syncDir(dir string) error {
+	d, err := os.Open(filepath.Clean(dir))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,365 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/event/sourcing"
+	"github.com/ln80/event-store/logger"
+	"github.com/ln80/event-store/memory"
+)
+
+var (
+	ErrCorruptedSegment = errors.New("corrupted segment")
+	ErrStoreClosed      = errors.New("store closed")
+)
+
+const (
+	DefaultSegmentMaxSize int64 = 64 << 20
+)
+
+// StoreConfig presents the file-based event store configuration.
+type StoreConfig struct {
+	// SegmentMaxSize defines the size (in bytes) from which a new segment file is created.
+	SegmentMaxSize int64
+	// SkipSync disables fsync after each append; it trades durability for throughput.
+	SkipSync bool
+	// SizeLimit defines the maximum size of appended records and events. No limit is applied by default.
+	SizeLimit event.SizeLimit
+}
+
+// Store implements different event store interfaces on top of local disk.
+//
+// Each appended record (aka chunk of events) is serialized using the given event.Serializer,
+// and written as a checksummed frame to an append-only segment file.
+// Segments are replayed at startup to rebuild an in-memory index that serves read operations.
+// A partially written frame at the end of the last segment is considered as the result of a crash,
+// and it's truncated during the recovery; whereas a checksum mismatch is reported as a corrupted segment.
+//
+// Expired events are filtered on read, but the store does not implement event.ExpiryPurger: global versions
+// are re-assigned during the recovery, thus removing records from segments would shift the following ones.
+type Store struct {
+	dir        string
+	serializer event.Serializer
+	index      *memory.Store
+
+	mu          sync.Mutex
+	segment     *os.File
+	segmentSeq  uint64
+	segmentSize int64
+	recovering  bool
+
+	cfg *StoreConfig
+}
+
+// interface safe-guards
+var (
+	_ event.Store            = &Store{}
+	_ sourcing.Store         = &Store{}
+	_ event.StreamReplayer   = &Store{}
+	_ event.StreamQuerier    = &Store{}
+	_ event.StreamSubscriber = &Store{}
+)
+
+// NewEventStore returns a file-based event store that persists segments in the given directory.
+// It recovers the store state from the existing segments, if any.
+func NewEventStore(ctx context.Context, dir string, ser event.Serializer, opts ...func(*StoreConfig)) (*Store, error) {
+	cfg := &StoreConfig{
+		SegmentMaxSize: DefaultSegmentMaxSize,
+		SkipSync:       false,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	s := &Store{
+		dir:        dir,
+		serializer: ser,
+		cfg:        cfg,
+	}
+	s.index = memory.NewEventStore(func(sc *memory.StoreConfig) {
+		sc.OnAppend = s.persist
+	})
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	if err := s.recover(ctx); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close closes the current segment file. The store is no longer writable once closed.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.segment == nil {
+		return nil
+	}
+	err := s.segment.Close()
+	s.segment = nil
+
+	return err
+}
+
+// Append implements event.Store interface.
+func (s *Store) Append(ctx context.Context, id event.StreamID, events []event.Envelope, opts ...func(*event.AppendConfig)) error {
+	// trace IDs are set before checking the size limit, the in-memory index leaves them as is.
+	if err := prepareEvents(ctx, id, events, opts); err != nil {
+		return err
+	}
+
+	if err := event.CheckSizeLimit(ctx, s.serializer, id, events, s.cfg.SizeLimit); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.index.Append(ctx, id, events, opts...)
+}
+
+// Load implements event.Store interface.
+func (s *Store) Load(ctx context.Context, id event.StreamID, trange ...time.Time) ([]event.Envelope, error) {
+	return s.index.Load(ctx, id, trange...)
+}
+
+// AppendToStream implements sourcing.Store interface.
+func (s *Store) AppendToStream(ctx context.Context, chunk sourcing.Stream, opts ...func(*event.AppendConfig)) error {
+	if err := prepareEvents(ctx, chunk.ID(), chunk.Unwrap(), opts); err != nil {
+		return err
+	}
+
+	if err := event.CheckSizeLimit(ctx, s.serializer, chunk.ID(), chunk.Unwrap(), s.cfg.SizeLimit); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.index.AppendToStream(ctx, chunk, opts...)
+}
+
+// LoadStream implements sourcing.Store interface.
+func (s *Store) LoadStream(ctx context.Context, id event.StreamID, vrange ...event.Version) (*sourcing.Stream, error) {
+	return s.index.LoadStream(ctx, id, vrange...)
+}
+
+// Replay implements event.StreamReplayer interface.
+func (s *Store) Replay(ctx context.Context, id event.StreamID, q event.StreamReplayQuery, h event.StreamReplayHandler) error {
+	return s.index.Replay(ctx, id, q, h)
+}
+
+// Query implements event.StreamQuerier interface.
+func (s *Store) Query(ctx context.Context, id event.StreamID, q event.StreamQuery) (*event.StreamQueryResult, error) {
+	return s.index.Query(ctx, id, q)
+}
+
+// Subscribe implements event.StreamSubscriber interface.
+func (s *Store) Subscribe(ctx context.Context, id event.StreamID, from event.Version, h event.StreamReplayHandler) error {
+	return s.index.Subscribe(ctx, id, from, h)
+}
+
+// prepareEvents sets the trace IDs of the given events according to the append options.
+//
+// AddToTx option is not supported: records are durably written before the in-memory index runs the tx items,
+// thus a failed item can't roll back the record, which would reappear once the store is reopened.
+func prepareEvents(ctx context.Context, id event.StreamID, events []event.Envelope, opts []func(*event.AppendConfig)) error {
+	cfg := &event.AppendConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	if cfg.AddToTx != nil {
+		return errors.Err(event.ErrUnsupportedAppendOption, id.String(), "AddToTx is not supported by the file store")
+	}
+	cfg.Trace(ctx, events)
+
+	return nil
+}
+
+// persist writes the given record to the current segment.
+// It's called by the in-memory index once the global versions are set, which makes
+// the index state only change if the record is durably persisted.
+func (s *Store) persist(ctx context.Context, id event.StreamID, events []event.Envelope) error {
+	if s.recovering {
+		return nil
+	}
+
+	if s.segment == nil {
+		return errors.Err(event.ErrAppendEventsFailed, id.String(), ErrStoreClosed)
+	}
+
+	b, err := s.serializer.MarshalEventBatch(ctx, events)
+	if err != nil {
+		return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+	}
+
+	if s.segmentSize > 0 && s.segmentSize+int64(frameHeaderSize+len(b)) > s.cfg.SegmentMaxSize {
+		if err := s.rotate(ctx); err != nil {
+			return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+		}
+	}
+
+	n, err := writeFrame(s.segment, b)
+	if err != nil {
+		// remove any partially written frame
+		_ = s.segment.Truncate(s.segmentSize)
+		return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+	}
+
+	if !s.cfg.SkipSync {
+		if err := s.segment.Sync(); err != nil {
+			_ = s.segment.Truncate(s.segmentSize)
+			return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+		}
+	}
+
+	s.segmentSize += int64(n)
+
+	return nil
+}
+
+// rotate opens the next segment and closes the current one.
+// The current segment is kept if the next one can't be opened, so that the rotation is retried on the next append.
+func (s *Store) rotate(ctx context.Context) error {
+	current := s.segment
+	if err := s.openSegment(s.segmentSeq + 1); err != nil {
+		return err
+	}
+	if err := current.Close(); err != nil {
+		logger.FromContext(ctx).WithName("file").Error(err, "Close rotated segment failed",
+			"segment", segmentName(s.segmentSeq-1))
+	}
+	return nil
+}
+
+// openSegment opens (or creates) the segment identified by the given sequence as the current one.
+func (s *Store) openSegment(seq uint64) error {
+	path := filepath.Join(s.dir, segmentName(seq))
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := syncDir(s.dir); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	s.segment = f
+	s.segmentSeq = seq
+	s.segmentSize = info.Size()
+
+	return nil
+}
+
+// recover rebuilds the in-memory index from the existing segments,
+// and opens the last segment for writing.
+func (s *Store) recover(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recovering = true
+	defer func() {
+		s.recovering = false
+	}()
+
+	seqs, err := listSegments(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for i, seq := range seqs {
+		last := i == len(seqs)-1
+		if err := s.recoverSegment(ctx, seq, last); err != nil {
+			return err
+		}
+	}
+
+	seq := uint64(1)
+	if l := len(seqs); l > 0 {
+		seq = seqs[l-1]
+	}
+
+	return s.openSegment(seq)
+}
+
+// recoverSegment reads the records of the given segment and appends them to the in-memory index.
+// A partial frame is only tolerated at the end of the last segment, it's truncated in such a case.
+// A frame that does not match its checksum fails the recovery with an ErrCorruptedSegment error,
+// otherwise the valid frames that follow it would be lost.
+func (s *Store) recoverSegment(ctx context.Context, seq uint64, last bool) error {
+	path := filepath.Join(s.dir, segmentName(seq))
+
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset := int64(0)
+	for {
+		payload, err := readFrame(f)
+		if err == io.EOF {
+			return nil
+		}
+		if err == errChecksumMismatch {
+			return errors.Err(ErrCorruptedSegment, "", fmt.Errorf("%s at offset %d: %w", segmentName(seq), offset, err))
+		}
+		if err == errPartialFrame {
+			if !last {
+				return errors.Err(ErrCorruptedSegment, "", segmentName(seq))
+			}
+
+			logger.FromContext(ctx).WithName("file").V(1).Info("Truncate partial record found in the last segment",
+				"segment", segmentName(seq),
+				"offset", offset)
+
+			return os.Truncate(path, offset)
+		}
+		if err != nil {
+			return err
+		}
+
+		envs, err := s.serializer.UnmarshalEventBatch(ctx, payload)
+		if err != nil {
+			return errors.Err(ErrCorruptedSegment, "", err)
+		}
+		if len(envs) > 0 {
+			id, err := event.ParseStreamID(envs[0].StreamID())
+			if err != nil {
+				return errors.Err(ErrCorruptedSegment, envs[0].StreamID(), err)
+			}
+			// Global versions are re-assigned by the index. The result is the same
+			// as the original one because records are restored in the same order.
+			// Records were checked on append, thus the index doesn't check them again.
+			if err := s.index.AppendRecovered(ctx, id, envs); err != nil {
+				return errors.Err(ErrCorruptedSegment, id.String(), err)
+			}
+		}
+
+		offset += int64(frameHeaderSize + len(payload))
+	}
+}
@@ -0,0 +1,358 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/sourcing"
+	"github.com/ln80/event-store/eventtest"
+	"github.com/ln80/event-store/json"
+	"github.com/ln80/event-store/memory"
+)
+
+func newTestStore(t *testing.T, ctx context.Context, dir string, opts ...func(*StoreConfig)) *Store {
+	t.Helper()
+
+	store, err := NewEventStore(ctx, dir, json.NewEventSerializer(""), opts...)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	return store
+}
+
+func TestEventStore(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	eventtest.TestEventLoggingStore(t, ctx, newTestStore(t, ctx, t.TempDir()))
+	eventtest.TestEventSourcingStore(t, ctx, newTestStore(t, ctx, t.TempDir()))
+	eventtest.TestEventStreamReplayer(t, ctx, newTestStore(t, ctx, t.TempDir()), func(opt *eventtest.TestEventStreamReplayerOptions) {
+		opt.SupportOrderDESC = true
+	})
+	eventtest.TestEventStreamQuerier(t, ctx, newTestStore(t, ctx, t.TempDir()), func(opt *eventtest.TestEventStreamQuerierOptions) {
+		opt.SupportOrderDESC = true
+	})
+	eventtest.TestEventStreamSubscriber(t, ctx, newTestStore(t, ctx, t.TempDir()))
+
+	limit := event.SizeLimit{Record: 4096, Event: 1024}
+	eventtest.TestEventStoreSizeLimit(t, ctx, newTestStore(t, ctx, t.TempDir(), func(sc *StoreConfig) {
+		sc.SizeLimit = limit
+	}), limit)
+}
+
+func TestEventStore_Expiry(t *testing.T) {
+	t.Skip("file store does not implement event.ExpiryPurger, segments are append-only and global versions are re-assigned on recovery")
+}
+
+func TestEventStore_Recovery(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	dir := t.TempDir()
+
+	// use a tiny segment size to force segment rotation
+	store := newTestStore(t, ctx, dir, func(sc *StoreConfig) {
+		sc.SegmentMaxSize = 512
+	})
+
+	streamID := event.NewStreamID(event.UID().String(), "service")
+
+	stm := sourcing.Wrap(ctx, streamID, event.VersionZero, eventtest.GenEvents(5))
+	if err := store.AppendToStream(ctx, stm); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	stm2 := sourcing.Wrap(ctx, streamID, stm.Version(), eventtest.GenEvents(5))
+	if err := store.AppendToStream(ctx, stm2); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// append to a closed store must fail
+	stm3 := sourcing.Wrap(ctx, streamID, stm2.Version(), eventtest.GenEvents(1))
+	if want, err := event.ErrAppendEventsFailed, store.AppendToStream(ctx, stm3); !errors.Is(err, want) {
+		t.Fatalf("expect %v, %v be equals", want, err)
+	}
+
+	seqs, err := listSegments(dir)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if l := len(seqs); l < 2 {
+		t.Fatalf("expect segments be rotated, got %d segment(s)", l)
+	}
+
+	// simulate a crash in the middle of a write
+	last := filepath.Join(dir, segmentName(seqs[len(seqs)-1]))
+	info, err := os.Stat(last)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	f, err := os.OpenFile(last, os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if _, err := f.Write([]byte{0xff, 0x01, 0x00}); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	_ = f.Close()
+
+	// reopen the store and check data integrity
+	store = newTestStore(t, ctx, dir)
+
+	rinfo, err := os.Stat(last)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := info.Size(), rinfo.Size(); want != got {
+		t.Fatalf("expect partial frame be truncated, %d, %d be equals", want, got)
+	}
+
+	rstm, err := store.LoadStream(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	envs := append(stm.Unwrap(), stm2.Unwrap()...)
+	renvs := rstm.Unwrap()
+	if want, got := len(envs), len(renvs); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	for i, env := range envs {
+		if !eventtest.CmpEnv(env, renvs[i]) {
+			t.Fatalf("event %d data altered %v %v", i, eventtest.FormatEnv(env), eventtest.FormatEnv(renvs[i]))
+		}
+		if want, got := env.GlobalVersion(), renvs[i].GlobalVersion(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	// make sure optimistic concurrency still holds after recovery
+	if want, err := event.ErrAppendEventsConflict, store.AppendToStream(ctx, stm2); !errors.Is(err, want) {
+		t.Fatalf("expect %v, %v be equals", want, err)
+	}
+	if err := store.AppendToStream(ctx, stm3); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+}
+
+func TestEventStore_RecoveryWithManyRecords(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	dir := t.TempDir()
+
+	store := newTestStore(t, ctx, dir, func(sc *StoreConfig) {
+		sc.SkipSync = true
+	})
+
+	streamID := event.NewStreamID(event.UID().String())
+
+	count := 5000
+	envs := make([]event.Envelope, 0, count)
+	for i := 0; i < count; i++ {
+		renvs := event.Wrap(ctx, streamID, eventtest.GenEvents(1))
+		if err := store.Append(ctx, streamID, renvs); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		envs = append(envs, renvs...)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// records are restored without checking their idempotency one by one
+	store = newTestStore(t, ctx, dir)
+
+	renvs, err := store.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := count, len(renvs); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if want, got := envs[count-1].GlobalVersion(), renvs[count-1].GlobalVersion(); !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// appends still check idempotency once recovered
+	if want, err := event.ErrAppendEventsConflict, store.Append(ctx, streamID, envs[:1]); !errors.Is(err, want) {
+		t.Fatalf("expect %v, %v be equals", want, err)
+	}
+}
+
+func TestEventStore_AddToTx(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	dir := t.TempDir()
+
+	store := newTestStore(t, ctx, dir)
+
+	streamID := event.NewStreamID(event.UID().String())
+
+	// records are persisted before tx items run, thus the option is rejected
+	addToTx := func(cfg *event.AppendConfig) {
+		cfg.AddToTx = func(ctx context.Context) []any {
+			return []any{memory.TxItem(func(ctx context.Context) error {
+				return errors.New("tx item failure")
+			})}
+		}
+	}
+	if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(2)), addToTx); !errors.Is(err, event.ErrUnsupportedAppendOption) {
+		t.Fatalf("expect %v error to occur, got: %v", event.ErrUnsupportedAppendOption, err)
+	}
+	stm := sourcing.Wrap(ctx, streamID, event.VersionZero, eventtest.GenEvents(2))
+	if err := store.AppendToStream(ctx, stm, addToTx); !errors.Is(err, event.ErrUnsupportedAppendOption) {
+		t.Fatalf("expect %v error to occur, got: %v", event.ErrUnsupportedAppendOption, err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// rejected events don't reappear once the store is reopened
+	store = newTestStore(t, ctx, dir)
+	envs, err := store.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 0, len(envs); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestEventStore_WithCorruptedSegment(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	store := newTestStore(t, ctx, dir, func(sc *StoreConfig) {
+		sc.SegmentMaxSize = 512
+	})
+
+	streamID := event.NewStreamID(event.UID().String())
+	for i := 0; i < 3; i++ {
+		if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(3))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	}
+	_ = store.Close()
+
+	// corrupt the first segment which is not the last one
+	first := filepath.Join(dir, segmentName(1))
+	if err := os.Truncate(first, frameHeaderSize+1); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	_, err := NewEventStore(ctx, dir, json.NewEventSerializer(""))
+	if want, got := ErrCorruptedSegment, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestEventStore_WithCorruptedFrame(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	store := newTestStore(t, ctx, dir)
+
+	streamID := event.NewStreamID(event.UID().String())
+	for i := 0; i < 3; i++ {
+		if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(3))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	}
+	_ = store.Close()
+
+	// flip a payload byte of the first frame, which is followed by valid frames in the last segment
+	last := filepath.Join(dir, segmentName(1))
+	info, err := os.Stat(last)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	f, err := os.OpenFile(last, os.O_RDWR, 0o640)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	b := make([]byte, 1)
+	if _, err := f.ReadAt(b, frameHeaderSize); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if _, err := f.WriteAt([]byte{b[0] ^ 0xff}, frameHeaderSize); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	_ = f.Close()
+
+	_, err = NewEventStore(ctx, dir, json.NewEventSerializer(""))
+	if want, got := ErrCorruptedSegment, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// the valid frames must not be truncated
+	rinfo, err := os.Stat(last)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := info.Size(), rinfo.Size(); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+}
+
+func TestEventStore_WithRotationFailure(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	store := newTestStore(t, ctx, dir, func(sc *StoreConfig) {
+		sc.SegmentMaxSize = 512
+	})
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, eventtest.GenEvents(3))
+	if err := store.Append(ctx, streamID, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// make the next segment impossible to open
+	next := filepath.Join(dir, segmentName(2))
+	if err := os.Mkdir(next, 0o750); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, err := event.ErrAppendEventsFailed, store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(3))); !errors.Is(err, want) {
+		t.Fatalf("expect %v, %v be equals", want, err)
+	}
+
+	// the store keeps working once the rotation succeeds
+	if err := os.Remove(next); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	envs2 := event.Wrap(ctx, streamID, eventtest.GenEvents(3))
+	if err := store.Append(ctx, streamID, envs2); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	_ = store.Close()
+
+	store = newTestStore(t, ctx, dir)
+	renvs, err := store.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := len(envs)+len(envs2), len(renvs); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+}
@@ -27,6 +27,7 @@ require (
 	github.com/ln80/struct-sensitive v0.7.0
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/rs/zerolog v1.35.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 // replace github.com/ln80/struct-sensitive v0.6.0 => ../struct-sensitive
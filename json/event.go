@@ -33,7 +33,14 @@ func convertEvent(evt event.Envelope) (to *jsonEvent, err error) {
 		FRawEvent: json.RawMessage(data),
 		FAt:       evt.At().UnixNano(),
 		FUser:     evt.User(),
+		FIPAddr:   evt.IPAddr(),
 		FDests:    evt.Dests(),
+		FTTL:      evt.TTL(),
+
+		FCorrelationID: evt.CorrelationID(),
+		FCausationID:   evt.CausationID(),
+		FTraceID:       evt.TraceID(),
+		FMetadata:      evt.Metadata(),
 	}
 	if !evt.Version().Equal(event.VersionZero) {
 		to.fVersion = evt.Version()
@@ -47,21 +54,26 @@ func convertEvent(evt event.Envelope) (to *jsonEvent, err error) {
 }
 
 type jsonEvent struct {
-	FStreamID         string          `json:"StmID"`
-	fGlobalStreamID   string          `json:"-"`
-	FRawGlobalVersion string          `json:"GVer,omitempty"`
-	fGlobalVersion    event.Version   `json:"-"`
-	FRawVersion       string          `json:"Ver,omitempty"`
-	fVersion          event.Version   `json:"-"`
-	FID               string          `json:"ID"`
-	FType             string          `json:"Type"`
-	FRawEvent         json.RawMessage `json:"Data"`
-	fEvent            any             `json:"-"`
-	FAt               int64           `json:"At"`
-	FUser             string          `json:"User,omitempty"`
-	FIPAddr           string          `json:"IPAddr,omitempty"`
-	FDests            []string        `json:"Dests,omitempty"`
-	FTTL              time.Duration   `json:"TTL,omitempty"`
+	FStreamID         string            `json:"StmID"`
+	fGlobalStreamID   string            `json:"-"`
+	FRawGlobalVersion string            `json:"GVer,omitempty"`
+	fGlobalVersion    event.Version     `json:"-"`
+	FRawVersion       string            `json:"Ver,omitempty"`
+	fVersion          event.Version     `json:"-"`
+	FID               string            `json:"ID"`
+	FType             string            `json:"Type"`
+	FRev              int               `json:"Rev,omitempty"`
+	FRawEvent         json.RawMessage   `json:"Data"`
+	fEvent            any               `json:"-"`
+	FAt               int64             `json:"At"`
+	FUser             string            `json:"User,omitempty"`
+	FIPAddr           string            `json:"IPAddr,omitempty"`
+	FDests            []string          `json:"Dests,omitempty"`
+	FTTL              time.Duration     `json:"TTL,omitempty"`
+	FCorrelationID    string            `json:"CorrID,omitempty"`
+	FCausationID      string            `json:"CausID,omitempty"`
+	FTraceID          string            `json:"TraceID,omitempty"`
+	FMetadata         map[string]string `json:"Meta,omitempty"`
 
 	reg event.Register `json:"-"`
 }
@@ -171,6 +183,28 @@ func (e *jsonEvent) TTL() time.Duration {
 	return e.FTTL
 }
 
+func (e *jsonEvent) CorrelationID() string {
+	return e.FCorrelationID
+}
+
+func (e *jsonEvent) CausationID() string {
+	return e.FCausationID
+}
+
+func (e *jsonEvent) TraceID() string {
+	return e.FTraceID
+}
+
+func (e *jsonEvent) Metadata() map[string]string {
+	return e.FMetadata
+}
+
+func (e *jsonEvent) SetVersion(v event.Version) event.Envelope {
+	e.fVersion = v
+	e.FRawVersion = e.fVersion.String()
+	return e
+}
+
 func (e *jsonEvent) SetGlobalVersion(v event.Version) event.Envelope {
 	e.fGlobalVersion = v
 	e.FRawGlobalVersion = e.fGlobalVersion.String()
@@ -1,11 +1,14 @@
 package json
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
 )
 
 // eventSerializer implements event.Serializer interface.
@@ -13,16 +16,33 @@ import (
 // to unmarshal envelop data aka domain event.
 type eventSerializer struct {
 	eventRegistry event.Register
+
+	cfg *EventSerializerConfig
+}
+
+type EventSerializerConfig struct {
+	// Upcasters are applied on unmarshal to migrate legacy events to their current shape.
+	Upcasters *event.Upcasters
 }
 
 // NewEventSerializer returns a json event serializer
-func NewEventSerializer(namespace string) event.Serializer {
+func NewEventSerializer(namespace string, opts ...func(*EventSerializerConfig)) event.Serializer {
+	cfg := &EventSerializerConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
 	return &eventSerializer{
 		eventRegistry: event.NewRegister(namespace),
+		cfg:           cfg,
 	}
 }
 
 var _ event.Serializer = &eventSerializer{}
+var _ event.EventsUnmarshaler = &eventSerializer{}
 
 func (s *eventSerializer) MarshalEvent(ctx context.Context, evt event.Envelope) (b []byte, err error) {
 	if evt == nil {
@@ -38,13 +58,10 @@ func (s *eventSerializer) MarshalEvent(ctx context.Context, evt event.Envelope)
 
 	var (
 		jsonEvt *jsonEvent
-		ok      bool
 	)
-	if jsonEvt, ok = evt.(*jsonEvent); !ok {
-		jsonEvt, err = convertEvent(evt)
-		if err != nil {
-			return
-		}
+	jsonEvt, err = s.convert(evt)
+	if err != nil {
+		return
 	}
 	b, err = json.Marshal(jsonEvt)
 
@@ -71,7 +88,7 @@ func (s *eventSerializer) MarshalEventBatch(ctx context.Context, events []event.
 		var (
 			jsonEvt *jsonEvent
 		)
-		jsonEvt, err = convertEvent(evt)
+		jsonEvt, err = s.convert(evt)
 		if err != nil {
 			return
 		}
@@ -83,13 +100,43 @@ func (s *eventSerializer) MarshalEventBatch(ctx context.Context, events []event.
 }
 
 func (s *eventSerializer) UnmarshalEvent(ctx context.Context, b []byte) (event.Envelope, error) {
+	envs, err := s.UnmarshalEvents(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	// a single envelope can't present the derived events
+	if l := len(envs); l != 1 {
+		streamID := ""
+		if l > 0 {
+			streamID = envs[0].StreamID()
+		}
+		return nil, errors.Err(event.ErrUpcastEventFailed, streamID, "splitting or dropping an event requires UnmarshalEvents or a batch unmarshal")
+	}
+	return envs[0], nil
+}
+
+// UnmarshalEvents implements event.EventsUnmarshaler.
+func (s *eventSerializer) UnmarshalEvents(ctx context.Context, b []byte) ([]event.Envelope, error) {
 	jsonEvt := jsonEvent{
 		reg: s.eventRegistry,
 	}
 	if err := json.Unmarshal(b, &jsonEvt); err != nil {
 		return nil, err
 	}
-	return &jsonEvt, nil
+	if !s.cfg.Upcasters.Match(jsonEvt.FType, jsonEvt.FRev) {
+		return []event.Envelope{&jsonEvt}, nil
+	}
+
+	jsonEvents, err := s.upcast(ctx, &jsonEvt)
+	if err != nil {
+		return nil, err
+	}
+
+	envs := make([]event.Envelope, len(jsonEvents))
+	for i, jsonEvt := range jsonEvents {
+		envs[i] = jsonEvt
+	}
+	return envs, nil
 }
 
 func (s *eventSerializer) UnmarshalEventBatch(ctx context.Context, b []byte) ([]event.Envelope, error) {
@@ -98,11 +145,86 @@ func (s *eventSerializer) UnmarshalEventBatch(ctx context.Context, b []byte) ([]
 		return nil, err
 	}
 
-	envs := make([]event.Envelope, len(jsonEvents))
-	for i, jsonEvt := range jsonEvents {
+	upcasted := false
+	envs := make([]event.Envelope, 0, len(jsonEvents))
+	for _, jsonEvt := range jsonEvents {
 		jsonEvt := jsonEvt
 		jsonEvt.reg = s.eventRegistry
-		envs[i] = &jsonEvt
+		if !s.cfg.Upcasters.Match(jsonEvt.FType, jsonEvt.FRev) {
+			envs = append(envs, &jsonEvt)
+			continue
+		}
+
+		evts, err := s.upcast(ctx, &jsonEvt)
+		if err != nil {
+			return nil, err
+		}
+		upcasted = upcasted || len(evts) != 1
+		for _, evt := range evts {
+			envs = append(envs, evt)
+		}
 	}
+	if upcasted {
+		event.RenumberEvents(envs)
+	}
+
 	return envs, nil
 }
+
+// convert returns the json version of the given envelope along with the current revision of its event.
+func (s *eventSerializer) convert(evt event.Envelope) (*jsonEvent, error) {
+	if jsonEvt, ok := evt.(*jsonEvent); ok {
+		return jsonEvt, nil
+	}
+
+	jsonEvt, err := convertEvent(evt)
+	if err != nil {
+		return nil, err
+	}
+	jsonEvt.FRev = s.eventRegistry.Revision(evt.Type())
+
+	return jsonEvt, nil
+}
+
+// upcast applies the matching upcasters to the given event.
+// It may return several events in the case of a split, the derived events share the original event version.
+func (s *eventSerializer) upcast(ctx context.Context, jsonEvt *jsonEvent) ([]*jsonEvent, error) {
+	data := map[string]any{}
+
+	// keep numbers as is to avoid losing precision while re-encoding
+	dec := json.NewDecoder(bytes.NewReader(jsonEvt.FRawEvent))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return nil, errors.Err(event.ErrUpcastEventFailed, jsonEvt.FStreamID, err)
+	}
+
+	raws, err := s.cfg.Upcasters.Upcast(ctx, event.RawEvent{
+		Type:     jsonEvt.FType,
+		Revision: jsonEvt.FRev,
+		Data:     data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*jsonEvent, len(raws))
+	for i, raw := range raws {
+		b, err := json.Marshal(raw.Data)
+		if err != nil {
+			return nil, errors.Err(event.ErrUpcastEventFailed, jsonEvt.FStreamID, err)
+		}
+
+		e := *jsonEvt
+		e.FType = raw.Type
+		e.FRev = raw.Revision
+		e.FRawEvent = json.RawMessage(b)
+		e.fEvent = nil
+		// derived events must have unique and deterministic IDs
+		if i > 0 {
+			e.FID = jsonEvt.FID + "-" + strconv.Itoa(i)
+		}
+		result[i] = &e
+	}
+
+	return result, nil
+}
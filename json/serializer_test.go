@@ -2,7 +2,9 @@ package json
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/ln80/event-store/event"
@@ -56,3 +58,168 @@ func TestSerializer_WithError(t *testing.T) {
 		}
 	})
 }
+
+type Money struct {
+	Value    int64
+	Currency string
+}
+
+type MoneyDeposited struct {
+	AccountID string
+	Amount    Money
+}
+
+type FeeCharged struct {
+	AccountID string
+	Fee       int64
+}
+
+// legacyMoneyDeposited presents the revision zero of MoneyDeposited event.
+type legacyMoneyDeposited struct {
+	Account string
+	Amount  int64
+	Fee     int64
+}
+
+func TestSerializer_Upcast(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	event.NewRegister("").
+		Set(MoneyDeposited{}, event.WithRevision(1)).
+		Set(FeeCharged{})
+
+	ctx := context.Background()
+
+	depositType, feeType := event.TypeOf(MoneyDeposited{}), event.TypeOf(FeeCharged{})
+
+	// split the legacy deposit event into a deposit and a fee events
+	upcasters := event.NewUpcasters().
+		Set(depositType, 0, func(ctx context.Context, evt event.RawEvent) ([]event.RawEvent, error) {
+			return []event.RawEvent{
+				{
+					Type:     depositType,
+					Revision: 1,
+					Data: map[string]any{
+						"AccountID": evt.Data["Account"],
+						"Amount":    map[string]any{"Value": evt.Data["Amount"], "Currency": "EUR"},
+					},
+				},
+				{
+					Type: feeType,
+					Data: map[string]any{
+						"AccountID": evt.Data["Account"],
+						"Fee":       evt.Data["Fee"],
+					},
+				},
+			}, nil
+		})
+
+	ser := NewEventSerializer("", func(cfg *EventSerializerConfig) {
+		cfg.Upcasters = upcasters
+	})
+
+	stmID := event.NewStreamID("tenantID")
+	envs := event.Wrap(ctx, stmID,
+		[]any{&legacyMoneyDeposited{Account: "acc-1", Amount: 100, Fee: 2}, &eventtest.Event1{Val: "foo"}},
+		event.WithVersionIncr(event.VersionMin, 2, event.VersionSeqDiffFracPart),
+		event.WithGlobalVersionIncr(event.VersionMin, 2, event.VersionSeqDiffFracPart),
+	)
+
+	// simulate a record persisted before the introduction of MoneyDeposited revision 1
+	legacy := make([]jsonEvent, len(envs))
+	for i, env := range envs {
+		jsonEvt, err := convertEvent(env)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		legacy[i] = *jsonEvt
+	}
+	legacy[0].FType = depositType
+
+	b, err := json.Marshal(legacy)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	renvs, err := ser.UnmarshalEventBatch(ctx, b)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 3, len(renvs); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	wantEvents := []any{
+		&MoneyDeposited{AccountID: "acc-1", Amount: Money{Value: 100, Currency: "EUR"}},
+		&FeeCharged{AccountID: "acc-1", Fee: 2},
+		&eventtest.Event1{Val: "foo"},
+	}
+	wantVersions := []event.Version{
+		event.VersionMin,
+		event.VersionMin.Add(0, 1),
+		event.VersionMin.Add(0, 2).EOF(),
+	}
+	ids := map[string]bool{}
+	for i, renv := range renvs {
+		if want, got := wantEvents[i], renv.Event(); !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := wantVersions[i], renv.Version(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := wantVersions[i], renv.GlobalVersion(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		ids[renv.ID()] = true
+	}
+	if want, got := 3, len(ids); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// the upcasted record remains a valid stream chunk
+	if err := event.Stream(renvs).Validate(); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// the current revision is persisted, thus upcasters are skipped
+	b, err = ser.MarshalEventBatch(ctx, renvs)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	renvs2, err := ser.UnmarshalEventBatch(ctx, b)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	for i, renv := range renvs2 {
+		if !eventtest.CmpEnv(renvs[i], renv) {
+			t.Fatalf("event %d data altered %v %v", i, eventtest.FormatEnv(renvs[i]), eventtest.FormatEnv(renv))
+		}
+	}
+
+	// single event unmarshal does not support split
+	b, err = json.Marshal(legacy[0])
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	_, err = ser.UnmarshalEvent(ctx, b)
+	if want, got := event.ErrUpcastEventFailed, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// whereas derived events share the original versions until they're renumbered
+	renvs, err = event.UnmarshalEvents(ctx, ser, b)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 2, len(renvs); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	for i, renv := range renvs {
+		if want, got := wantEvents[i], renv.Event(); !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := event.VersionMin, renv.Version(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/ln80/event-store/event"
+)
+
+var _ event.StreamIndexer = &Store{}
+
+// indexKey returns the key of the given event in the secondary index.
+func indexKey(env event.Envelope) string {
+	return env.StreamID() + "/" + env.ID()
+}
+
+// skipIndex excludes the given events from the secondary index if required by the append config.
+// The store lock must be held by the caller.
+func (s *Store) skipIndex(id event.StreamID, events []event.Envelope, cfg *event.AppendConfig) {
+	if !cfg.SkipIndex || len(events) == 0 {
+		return
+	}
+	keys, ok := s.unindexed[id.GlobalID()]
+	if !ok {
+		keys = make(map[string]struct{})
+		s.unindexed[id.GlobalID()] = keys
+	}
+	for _, env := range events {
+		keys[indexKey(env)] = struct{}{}
+	}
+}
+
+// indexedEvents returns the given events of the global stream that are indexed.
+func (s *Store) indexedEvents(id event.StreamID, envs []event.Envelope) []event.Envelope {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := s.unindexed[id.GlobalID()]
+	if len(keys) == 0 {
+		return envs
+	}
+
+	fenvs := make([]event.Envelope, 0, len(envs))
+	for _, env := range envs {
+		if _, ok := keys[indexKey(env)]; ok {
+			continue
+		}
+		fenvs = append(fenvs, env)
+	}
+	return fenvs
+}
+
+// filtered returns true if the query relies on the secondary index.
+func filtered(q event.StreamQuery) bool {
+	return len(q.Users) > 0 ||
+		len(q.Types) > 0 ||
+		len(q.IPAddrs) > 0 ||
+		len(q.CorrelationIDs) > 0 ||
+		len(q.CausationIDs) > 0 ||
+		len(q.TraceIDs) > 0
+}
+
+// Reindex implements event.StreamIndexer.
+func (s *Store) Reindex(ctx context.Context, id event.StreamID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.unindexed, id.GlobalID())
+
+	return nil
+}
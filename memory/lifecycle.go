@@ -0,0 +1,252 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ln80/event-store/event"
+	event_errors "github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/event/sourcing"
+)
+
+var _ event.StreamLifecycle = &Store{}
+
+// streamState tracks the lifecycle status of a stream. Active streams have no state.
+type streamState struct {
+	status event.StreamStatus
+	cold   event.Store
+}
+
+// status returns the lifecycle status of the given stream, the store lock must be held by the caller.
+func (s *Store) status(streamID string) event.StreamStatus {
+	if st, ok := s.states[streamID]; ok {
+		return st.status
+	}
+	return event.StreamActive
+}
+
+// checkActive makes sure the given stream is open for appends, the store lock must be held by the caller.
+func (s *Store) checkActive(streamID string) error {
+	switch s.status(streamID) {
+	case event.StreamSoftDeleted, event.StreamDeleted:
+		return event_errors.Err(event.ErrAppendEventsFailed, streamID, event.ErrStreamDeleted)
+	case event.StreamArchived:
+		return event_errors.Err(event.ErrAppendEventsFailed, streamID, event.ErrStreamArchived)
+	}
+	return nil
+}
+
+// loadInactive loads the events of the given stream if it's not active.
+// The returned flag is false if the stream is active.
+func (s *Store) loadInactive(ctx context.Context, id event.StreamID, trange ...time.Time) ([]event.Envelope, bool, error) {
+	s.mu.RLock()
+	st, ok := s.states[id.String()]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+
+	switch st.status {
+	case event.StreamArchived:
+		envs, err := st.cold.Load(ctx, id, trange...)
+		return envs, true, err
+	default:
+		return nil, true, nil
+	}
+}
+
+// loadStreamInactive loads the given versioned stream if it's not active.
+// The returned flag is false if the stream is active.
+func (s *Store) loadStreamInactive(ctx context.Context, id event.StreamID, vrange ...event.Version) (*sourcing.Stream, bool, error) {
+	s.mu.RLock()
+	st, ok := s.states[id.String()]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+
+	switch st.status {
+	case event.StreamArchived:
+		cold, ok := st.cold.(sourcing.Store)
+		if !ok {
+			return nil, true, event_errors.Err(event.ErrLoadEventFailed, id.String(), event.ErrStreamArchived)
+		}
+		stm, err := cold.LoadStream(ctx, id, vrange...)
+		return stm, true, err
+	default:
+		return sourcing.NewStream(id, nil), true, nil
+	}
+}
+
+// Status implements event.StreamLifecycle.
+func (s *Store) Status(ctx context.Context, id event.StreamID) (event.StreamStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.status(id.String()), nil
+}
+
+// SoftDelete implements event.StreamLifecycle.
+func (s *Store) SoftDelete(ctx context.Context, id event.StreamID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.status(id.String()) {
+	case event.StreamActive:
+		s.states[id.String()] = &streamState{status: event.StreamSoftDeleted}
+		return nil
+	case event.StreamSoftDeleted:
+		return nil
+	case event.StreamArchived:
+		return event_errors.Err(event.ErrStreamLifecycleFailed, id.String(), event.ErrStreamArchived)
+	default:
+		return event_errors.Err(event.ErrStreamLifecycleFailed, id.String(), event.ErrStreamDeleted)
+	}
+}
+
+// Restore implements event.StreamLifecycle.
+func (s *Store) Restore(ctx context.Context, id event.StreamID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.status(id.String()) {
+	case event.StreamActive:
+		return nil
+	case event.StreamSoftDeleted:
+		delete(s.states, id.String())
+		return nil
+	case event.StreamArchived:
+		return event_errors.Err(event.ErrStreamLifecycleFailed, id.String(), event.ErrStreamArchived)
+	default:
+		return event_errors.Err(event.ErrStreamLifecycleFailed, id.String(), event.ErrStreamDeleted)
+	}
+}
+
+// HardDelete implements event.StreamLifecycle.
+//
+// Note that the events of an archived stream are left as is in the cold store.
+//
+// The event.StreamTombstone isn't registered in the event registry, as the in-memory store is the only one
+// that implements the stream lifecycle. Consumers that deserialize it (ex: when copying the stream to a file or SQL store)
+// must register it, ex: event.NewRegister("").Set(event.StreamTombstone{}).
+func (s *Store) HardDelete(ctx context.Context, id event.StreamID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.status(id.String()) == event.StreamDeleted {
+		return nil
+	}
+
+	var opts []event.EnvelopeOption
+	if db := s.db[id.String()]; len(db) > 0 && !db[len(db)-1].Version().IsZero() {
+		opts = append(opts, event.WithVersionIncr(db[len(db)-1].Version().Incr(), 1, event.VersionSeqDiffPart))
+	}
+	tombstone := event.Wrap(ctx, id, []any{&event.StreamTombstone{}}, opts...)
+
+	events := s.db[id.String()]
+	s.db[id.String()] = nil
+	if err := s.append(ctx, id, tombstone); err != nil {
+		s.db[id.String()] = events
+		return event_errors.Err(event.ErrStreamLifecycleFailed, id.String(), err)
+	}
+	s.states[id.String()] = &streamState{status: event.StreamDeleted}
+	if len(events) > 0 {
+		s.markGaps(id.String())
+	}
+
+	s.notify(id, tombstone)
+
+	return nil
+}
+
+// TruncateBefore implements event.StreamLifecycle.
+func (s *Store) TruncateBefore(ctx context.Context, id event.StreamID, ver event.Version) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkActive(id.String()); err != nil {
+		return event_errors.Err(event.ErrStreamLifecycleFailed, id.String(), err)
+	}
+
+	envs := s.db[id.String()]
+	if len(envs) == 0 {
+		return nil
+	}
+
+	// keep the last record as the stream head
+	if last := envs[len(envs)-1].Version().Trunc(); ver.Trunc().After(last) {
+		ver = last
+	}
+
+	fenvs := make([]event.Envelope, 0, len(envs))
+	for _, env := range envs {
+		if env.Version().Trunc().Before(ver.Trunc()) {
+			continue
+		}
+		fenvs = append(fenvs, env)
+	}
+	if len(fenvs) < len(envs) {
+		s.markGaps(id.String())
+	}
+	s.db[id.String()] = fenvs
+
+	return nil
+}
+
+// archivingKey marks the context of an ongoing Archive call with the archiving store.
+type archivingKey struct{}
+
+// archivingToItself reports whether the given context belongs to an Archive call of the current store,
+// which means that the cold store relies on the current one (ex: a decorator of it).
+func (s *Store) archivingToItself(ctx context.Context) bool {
+	st, _ := ctx.Value(archivingKey{}).(*Store)
+	return st == s
+}
+
+// Archive implements event.StreamLifecycle.
+//
+// Events are copied to the cold store record by record while the store lock is held, thus the cold store
+// must neither be the current store nor rely on it. Cold stores that append to the current one (ex: decorators of it)
+// are detected using the context, thus they must pass it through. The move is idempotent: if it fails midway,
+// the stream is left active and records already found in the cold store are skipped when retrying.
+func (s *Store) Archive(ctx context.Context, id event.StreamID, cold event.Store) error {
+	if cold == nil || cold == event.Store(s) {
+		return event_errors.Err(event.ErrStreamLifecycleFailed, id.String(), "invalid cold store")
+	}
+	ctx = context.WithValue(ctx, archivingKey{}, s)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkActive(id.String()); err != nil {
+		return event_errors.Err(event.ErrStreamLifecycleFailed, id.String(), err)
+	}
+
+	// group events by record before appending them. Events are copied as the cold store overrides their global versions.
+	records := make([][]event.Envelope, 0)
+	for _, env := range s.db[id.String()] {
+		cp := event.CopyEnvelope(env)
+		if l := len(records); l > 0 && records[l-1][0].GlobalVersion().Trunc().Equal(env.GlobalVersion().Trunc()) {
+			records[l-1] = append(records[l-1], cp)
+			continue
+		}
+		records = append(records, []event.Envelope{cp})
+	}
+
+	for _, record := range records {
+		if err := cold.Append(ctx, id, record); err != nil {
+			// the record is already archived by a previous failed attempt
+			if errors.Is(err, event.ErrAppendEventsConflict) {
+				continue
+			}
+			return event_errors.Err(event.ErrStreamLifecycleFailed, id.String(), err)
+		}
+	}
+
+	delete(s.db, id.String())
+	s.states[id.String()] = &streamState{status: event.StreamArchived, cold: cold}
+	s.markGaps(id.String())
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/ln80/event-store/event"
+)
+
+var (
+	_ event.StreamMetadataStore = &Store{}
+	_ event.RetentionCompactor  = &Store{}
+)
+
+// SetStreamMetadata implements event.StreamMetadataStore.
+func (s *Store) SetStreamMetadata(ctx context.Context, id event.StreamID, md event.StreamMetadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	md.ACL = slices.Clone(md.ACL)
+	md.Properties = maps.Clone(md.Properties)
+	s.metadata[id.String()] = md
+
+	return nil
+}
+
+// GetStreamMetadata implements event.StreamMetadataStore.
+func (s *Store) GetStreamMetadata(ctx context.Context, id event.StreamID) (event.StreamMetadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	md := s.metadata[id.String()]
+	md.ACL = slices.Clone(md.ACL)
+	md.Properties = maps.Clone(md.Properties)
+
+	return md, nil
+}
+
+// CompactRetention implements event.RetentionCompactor.
+func (s *Store) CompactRetention(ctx context.Context, at time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for stmID, md := range s.metadata {
+		envs := s.db[stmID]
+		if !md.HasRetention() || len(envs) == 0 {
+			continue
+		}
+
+		fenvs := md.Retain(envs, at)
+		if len(fenvs) == len(envs) {
+			continue
+		}
+
+		count += len(envs) - len(fenvs)
+		s.db[stmID] = slices.Clone(fenvs)
+		s.markGaps(stmID)
+	}
+
+	return count, nil
+}
+
+// retain applies the stream retention policy to the given loaded events, the store lock must be held by the caller.
+func (s *Store) retain(id event.StreamID, envs []event.Envelope, at time.Time) []event.Envelope {
+	md, ok := s.metadata[id.String()]
+	if !ok {
+		return envs
+	}
+	return md.Retain(envs, at)
+}
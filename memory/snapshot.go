@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ln80/event-store/event"
+	event_errors "github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/event/sourcing"
+)
+
+// SnapshotStore implements sourcing.SnapshotStore interface. mainly used for testing purposes.
+//
+// Snapshots are serialized using the given event serializer, and only the latest snapshot
+// is kept per stream.
+type SnapshotStore struct {
+	serializer event.Serializer
+	db         map[string][]byte
+	mu         sync.RWMutex
+}
+
+// interface safe-guards
+var _ sourcing.SnapshotStore = &SnapshotStore{}
+
+// NewSnapshotStore returns an in-memory snapshot store.
+func NewSnapshotStore(ser event.Serializer) *SnapshotStore {
+	return &SnapshotStore{
+		serializer: ser,
+		db:         make(map[string][]byte),
+	}
+}
+
+// SaveSnapshot implements sourcing.SnapshotStore interface.
+func (s *SnapshotStore) SaveSnapshot(ctx context.Context, snap sourcing.Snapshot) error {
+	env := event.Wrap(ctx, snap.StreamID, []any{snap.State}, func(env event.RWEnvelope) {
+		env.SetVersion(snap.Version)
+		if !snap.At.IsZero() {
+			env.SetAt(snap.At)
+		}
+		env.SetDests(nil)
+	})
+	if len(env) == 0 {
+		return event_errors.Err(sourcing.ErrSaveSnapshotFailed, snap.StreamID.String(), event.ErrMarshalEmptyEvent)
+	}
+
+	b, err := s.serializer.MarshalEvent(ctx, env[0])
+	if err != nil {
+		return event_errors.Err(sourcing.ErrSaveSnapshotFailed, snap.StreamID.String(), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, err := s.load(ctx, snap.StreamID); err == nil && last != nil && last.Version.After(snap.Version) {
+		return nil
+	}
+	s.db[snap.StreamID.String()] = b
+
+	return nil
+}
+
+// LoadSnapshot implements sourcing.SnapshotStore interface.
+func (s *SnapshotStore) LoadSnapshot(ctx context.Context, id event.StreamID) (*sourcing.Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap, err := s.load(ctx, id)
+	if err != nil {
+		return nil, event_errors.Err(sourcing.ErrLoadSnapshotFailed, id.String(), err)
+	}
+
+	return snap, nil
+}
+
+func (s *SnapshotStore) load(ctx context.Context, id event.StreamID) (*sourcing.Snapshot, error) {
+	b, ok := s.db[id.String()]
+	if !ok {
+		return nil, nil
+	}
+
+	env, err := s.serializer.UnmarshalEvent(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	state := env.Event()
+	if state == nil {
+		return nil, event.ErrNotFoundInRegistry
+	}
+
+	return &sourcing.Snapshot{
+		StreamID: id,
+		Version:  env.Version(),
+		State:    state,
+		At:       env.At(),
+	}, nil
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/sourcing"
+	"github.com/ln80/event-store/json"
+)
+
+type accountState struct {
+	ID      string
+	Balance int
+}
+
+func TestSnapshotStore(t *testing.T) {
+	ctx := context.Background()
+
+	event.NewRegister("").Set(accountState{})
+
+	store := NewSnapshotStore(json.NewEventSerializer(""))
+
+	streamID := event.NewStreamID(event.UID().String(), "account", "1")
+
+	snap, err := store.LoadSnapshot(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if snap != nil {
+		t.Fatalf("expect snapshot be nil, got %v", snap)
+	}
+
+	ver := event.VersionMin.Add(3, 1).EOF()
+	if err := store.SaveSnapshot(ctx, sourcing.Snapshot{
+		StreamID: streamID,
+		Version:  ver,
+		State:    accountState{ID: "1", Balance: 100},
+	}); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// an older snapshot is ignored
+	if err := store.SaveSnapshot(ctx, sourcing.Snapshot{
+		StreamID: streamID,
+		Version:  event.VersionMin.EOF(),
+		State:    accountState{ID: "1", Balance: 10},
+	}); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	snap, err = store.LoadSnapshot(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := ver, snap.Version; !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	state, ok := snap.State.(*accountState)
+	if !ok {
+		t.Fatalf("expect snapshot state type be %T, got %T", &accountState{}, snap.State)
+	}
+	if want, got := 100, state.Balance; want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	// state must be registered
+	type unregisteredState struct{ Val string }
+	streamID2 := event.NewStreamID(event.UID().String(), "account", "2")
+	if err := store.SaveSnapshot(ctx, sourcing.Snapshot{
+		StreamID: streamID2,
+		Version:  ver,
+		State:    unregisteredState{Val: "val"},
+	}); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if _, err := store.LoadSnapshot(ctx, streamID2); !errors.Is(err, sourcing.ErrLoadSnapshotFailed) {
+		t.Fatalf("expect %v, %v be equals", sourcing.ErrLoadSnapshotFailed, err)
+	}
+}
@@ -2,42 +2,97 @@ package memory
 
 import (
 	"context"
+	"encoding/base64"
+	"slices"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/ln80/event-store/event"
 	event_errors "github.com/ln80/event-store/event/errors"
 	"github.com/ln80/event-store/event/sourcing"
+	"github.com/ln80/event-store/json"
 )
 
+// StoreConfig presents the in-memory event store configuration.
+type StoreConfig struct {
+	// OnAppend, if defined, is called with the chunk of events to append once their global versions are set,
+	// and right before updating the store state. The append operation fails if OnAppend returns an error.
+	// It mainly allows other store implementations to rely on the in-memory store as an index.
+	OnAppend func(ctx context.Context, id event.StreamID, events []event.Envelope) error
+
+	// SubscriptionBufferSize defines the count of records buffered per subscription.
+	// The subscription falls back to catch-up mode once its buffer is full.
+	SubscriptionBufferSize int
+
+	// SizeLimit defines the maximum size of appended records and events. No limit is applied by default.
+	SizeLimit event.SizeLimit
+
+	// Serializer is used to measure the size of appended records and events, JSON is used by default.
+	Serializer event.Serializer
+
+	// HashChain enables the hash chaining of records per global stream, hashes are computed using the Serializer.
+	HashChain bool
+}
+
 // Store implements different event store interface. mainly used for testing purposes
 type Store struct {
 	db          map[string][]event.Envelope
 	checkpoints map[string]event.Version // track global stream version
 	mu          sync.RWMutex
-}
 
-// Query implements event.StreamQuerier.
-func (s *Store) Query(ctx context.Context, id event.StreamID, q event.StreamQuery) (*event.StreamQueryResult, error) {
-	// TODO: implement or not?
-	panic("unimplemented")
+	subscriptions map[string][]*subscription // per global stream
+
+	states map[string]*streamState // per stream lifecycle status
+
+	metadata map[string]event.StreamMetadata // per stream metadata
+
+	chains map[string]string // track global stream last record hash
+
+	unindexed map[string]map[string]struct{} // per global stream events appended without index
+
+	gaps map[string]bool // global streams with removed records, ex: purged once expired
+
+	cfg *StoreConfig
 }
 
 // interface safe-guards
 var (
-	_ event.Store          = &Store{}
-	_ sourcing.Store       = &Store{}
-	_ event.StreamReplayer = &Store{}
-	_ event.StreamQuerier  = &Store{}
+	_ event.Store               = &Store{}
+	_ sourcing.Store            = &Store{}
+	_ sourcing.MultiStreamStore = &Store{}
+	_ event.StreamReplayer      = &Store{}
+	_ event.StreamQuerier       = &Store{}
+	_ event.StreamSubscriber    = &Store{}
+	_ event.ExpiryPurger        = &Store{}
+	_ event.ChainVerifier       = &Store{}
 )
 
 // NewEventStore return in-memory event store implementation
-func NewEventStore() *Store {
+func NewEventStore(opts ...func(*StoreConfig)) *Store {
+	cfg := &StoreConfig{
+		SubscriptionBufferSize: 100,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	if cfg.Serializer == nil {
+		cfg.Serializer = json.NewEventSerializer("")
+	}
+
 	return &Store{
-		db:          make(map[string][]event.Envelope),
-		checkpoints: make(map[string]event.Version),
+		db:            make(map[string][]event.Envelope),
+		checkpoints:   make(map[string]event.Version),
+		subscriptions: make(map[string][]*subscription),
+		states:        make(map[string]*streamState),
+		metadata:      make(map[string]event.StreamMetadata),
+		chains:        make(map[string]string),
+		unindexed:     make(map[string]map[string]struct{}),
+		gaps:          make(map[string]bool),
+		cfg:           cfg,
 	}
 }
 
@@ -45,15 +100,62 @@ func (s *Store) Append(ctx context.Context, id event.StreamID, events []event.En
 	if len(events) == 0 {
 		return nil
 	}
+	// the store lock is already held by Archive
+	if s.archivingToItself(ctx) {
+		return event_errors.Err(event.ErrAppendEventsFailed, id.String(), "cold store relies on the archived store")
+	}
+
+	cfg := &event.AppendConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	cfg.Trace(ctx, events)
+
+	if err := event.CheckSizeLimit(ctx, s.cfg.Serializer, id, events, s.cfg.SizeLimit); err != nil {
+		return err
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// init stream's cache db
-	if _, ok := s.db[id.String()]; !ok {
-		s.db[id.String()] = make([]event.Envelope, 0)
+	if err := s.checkActive(id.String()); err != nil {
+		return err
+	}
+
+	rollback := s.keep(id)
+	if err := s.append(ctx, id, events); err != nil {
+		return err
+	}
+	if err := s.addToTx(ctx, id, cfg); err != nil {
+		rollback()
+		return err
+	}
+	s.skipIndex(id, events, cfg)
+	s.notify(id, events)
+
+	return nil
+}
+
+// AppendRecovered appends the given record as is, without checking the idempotency, the size limit or the stream status.
+// It allows other store implementations to rebuild the index from their durable records (ex: at startup),
+// which must be appended in their original order. OnAppend is still called, and subscriptions aren't notified.
+func (s *Store) AppendRecovered(ctx context.Context, id event.StreamID, events []event.Envelope) error {
+	if len(events) == 0 {
+		return nil
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.appendRecord(ctx, id, events)
+}
+
+// append saves the given record, the store lock must be held by the caller.
+// It's up to the caller to notify subscriptions once the record is appended.
+func (s *Store) append(ctx context.Context, id event.StreamID, events []event.Envelope) error {
 	// check idempotency
 	for _, evt := range events {
 		for _, env := range s.db[id.String()] {
@@ -63,6 +165,16 @@ func (s *Store) Append(ctx context.Context, id event.StreamID, events []event.En
 		}
 	}
 
+	return s.appendRecord(ctx, id, events)
+}
+
+// appendRecord sets the global versions of the given record and saves it, the store lock must be held by the caller.
+func (s *Store) appendRecord(ctx context.Context, id event.StreamID, events []event.Envelope) error {
+	// init stream's cache db
+	if _, ok := s.db[id.String()]; !ok {
+		s.db[id.String()] = make([]event.Envelope, 0)
+	}
+
 	// init global stream version
 	if _, ok := s.checkpoints[id.GlobalID()]; !ok {
 		s.checkpoints[id.GlobalID()] = event.VersionZero
@@ -91,14 +203,35 @@ func (s *Store) Append(ctx context.Context, id event.StreamID, events []event.En
 		}
 	}
 
+	hash := s.chains[id.GlobalID()]
+	if s.cfg.HashChain {
+		var err error
+		if hash, err = event.Chain(ctx, s.cfg.Serializer, hash, events); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.OnAppend != nil {
+		if err := s.cfg.OnAppend(ctx, id, events); err != nil {
+			return err
+		}
+	}
+
 	s.db[id.String()] = append(s.db[id.String()], events...)
 
 	s.checkpoints[id.GlobalID()] = gVer
+	if s.cfg.HashChain {
+		s.chains[id.GlobalID()] = hash
+	}
 
 	return nil
 }
 
 func (s *Store) Load(ctx context.Context, id event.StreamID, trange ...time.Time) ([]event.Envelope, error) {
+	if envs, ok, err := s.loadInactive(ctx, id, trange...); ok {
+		return envs, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -109,9 +242,13 @@ func (s *Store) Load(ctx context.Context, id event.StreamID, trange ...time.Time
 
 	l := len(trange)
 
+	now := time.Now().UTC()
+
+	envs = s.retain(id, envs, now)
+
 	fenvs := make([]event.Envelope, 0)
 	for _, env := range envs {
-		if env.TTL() > time.Duration(0) && env.At().Add(env.TTL()).Before(time.Now().UTC()) {
+		if event.Expired(env, now) {
 			continue
 		}
 		if l != 0 {
@@ -141,28 +278,150 @@ func (s *Store) AppendToStream(ctx context.Context, chunk sourcing.Stream, opts
 		return err
 	}
 
-	// make sure stream sequence is valid, no gaps are introduced
-	lastVersion := event.VersionZero
+	cfg := &event.AppendConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	cfg.Trace(ctx, chunk.Unwrap())
+
+	if err := event.CheckSizeLimit(ctx, s.cfg.Serializer, chunk.ID(), chunk.Unwrap(), s.cfg.SizeLimit); err != nil {
+		return err
+	}
+
+	// hold the lock until the chunk is appended, to make sure the expected version is still valid
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkSequence(chunk, cfg); err != nil {
+		return err
+	}
+
+	rollback := s.keep(chunk.ID())
+	if err := s.append(ctx, chunk.ID(), chunk.Unwrap()); err != nil {
+		return err
+	}
+	if err := s.addToTx(ctx, chunk.ID(), cfg); err != nil {
+		rollback()
+		return err
+	}
+	s.skipIndex(chunk.ID(), chunk.Unwrap(), cfg)
+	s.notify(chunk.ID(), chunk.Unwrap())
+
+	return nil
+}
+
+// AppendToStreams implements sourcing.MultiStreamStore.
+//
+// Note that OnAppend hook is called per chunk, whereas AddToTx items are executed once all chunks are appended.
+// A failure rolls back the in-memory state, but it's up to the hook and items to roll back their own side effects.
+func (s *Store) AppendToStreams(ctx context.Context, chunks []sourcing.Stream, opts ...func(*event.AppendConfig)) error {
+	if err := sourcing.ValidateChunks(chunks); err != nil {
+		return err
+	}
+
+	cfg := &event.AppendConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	fchunks := make([]sourcing.Stream, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.Empty() {
+			continue
+		}
+		cfg.Trace(ctx, chunk.Unwrap())
+		if err := event.CheckSizeLimit(ctx, s.cfg.Serializer, chunk.ID(), chunk.Unwrap(), s.cfg.SizeLimit); err != nil {
+			return err
+		}
+		fchunks = append(fchunks, chunk)
+	}
+	if len(fchunks) == 0 {
+		return nil
+	}
+	if cfg.ExpectedVersion != nil {
+		return event_errors.Err(event.ErrUnsupportedAppendOption, fchunks[0].ID().String(), "expected version is not supported by multi-stream appends")
+	}
+
 	s.mu.Lock()
-	db, ok := s.db[chunk.ID().String()]
-	s.mu.Unlock()
-	if ok {
+	defer s.mu.Unlock()
+
+	// check all chunks before writing any of them
+	for _, chunk := range fchunks {
+		if err := s.checkSequence(chunk, cfg); err != nil {
+			return err
+		}
+	}
+
+	// keep the current state to roll back in case of failure
+	ids := make([]event.StreamID, 0, len(fchunks))
+	for _, chunk := range fchunks {
+		ids = append(ids, chunk.ID())
+	}
+	rollback := s.keep(ids...)
+
+	for _, chunk := range fchunks {
+		if err := s.append(ctx, chunk.ID(), chunk.Unwrap()); err != nil {
+			rollback()
+			return event_errors.Err(event.ErrAppendEventsFailed, chunk.ID().String(), err)
+		}
+	}
+	if err := s.addToTx(ctx, fchunks[0].ID(), cfg); err != nil {
+		rollback()
+		return err
+	}
+
+	for _, chunk := range fchunks {
+		s.skipIndex(chunk.ID(), chunk.Unwrap(), cfg)
+		s.notify(chunk.ID(), chunk.Unwrap())
+	}
+
+	return nil
+}
+
+// checkSequence makes sure the given chunk directly follows the stream current version.
+// In the case of an expected version option, the chunk versions are rebased accordingly.
+// The store lock must be held by the caller.
+func (s *Store) checkSequence(chunk sourcing.Stream, cfg *event.AppendConfig) error {
+	if err := s.checkActive(chunk.ID().String()); err != nil {
+		return err
+	}
+
+	lastVersion := event.VersionZero
+	if db, ok := s.db[chunk.ID().String()]; ok && len(db) > 0 {
 		lastVersion = db[len(db)-1].Version()
 	}
+
+	if ev := cfg.ExpectedVersion; ev != nil {
+		if err := ev.Check(chunk.ID().String(), lastVersion); err != nil {
+			return err
+		}
+		if err := event.RebaseVersions(chunk.Unwrap(), lastVersion); err != nil {
+			return event_errors.Err(event.ErrAppendEventsFailed, chunk.ID().String(), err)
+		}
+	}
+
 	firstVersion := chunk.Unwrap()[0].Version()
 	if !firstVersion.Next(lastVersion) {
-		// if firstVersion.After(lastVersion) || !firstVersion.Next(lastVersion) {
 		return event_errors.Err(
 			event.ErrAppendEventsConflict, chunk.ID().String(),
 			"invalid sequence "+lastVersion.String()+" "+firstVersion.String(),
 		)
 	}
 
-	// perform the default append
-	return s.Append(ctx, chunk.ID(), chunk.Unwrap(), opts...)
+	return nil
 }
 
 func (s *Store) LoadStream(ctx context.Context, id event.StreamID, vrange ...event.Version) (*sourcing.Stream, error) {
+	if stm, ok, err := s.loadStreamInactive(ctx, id, vrange...); ok {
+		return stm, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -173,10 +432,14 @@ func (s *Store) LoadStream(ctx context.Context, id event.StreamID, vrange ...eve
 
 	l := len(vrange)
 
+	now := time.Now().UTC()
+
+	envs = s.retain(id, envs, now)
+
 	// filter stream based on range boundaries
 	fenvs := make([]event.Envelope, 0)
 	for _, env := range envs {
-		if env.TTL() > time.Duration(0) && env.At().Add(env.TTL()).Before(time.Now().UTC()) {
+		if event.Expired(env, now) {
 			continue
 		}
 		if l != 0 {
@@ -197,31 +460,12 @@ func (s *Store) LoadStream(ctx context.Context, id event.StreamID, vrange ...eve
 }
 
 func (s *Store) Replay(ctx context.Context, id event.StreamID, q event.StreamReplayQuery, h event.StreamReplayHandler) error {
-	// resolve sub streams
-	streamIDs := []string{}
-	for k := range s.db {
-		if strings.HasPrefix(k, id.String()) {
-			streamIDs = append(streamIDs, k)
-		}
-	}
-	if len(streamIDs) == 0 {
+	// aggregate sub streams's events in a global one, sorted by global version
+	envs, gaps := s.globalEvents(id)
+	if len(envs) == 0 {
 		return nil
 	}
 
-	// aggregate sub streams's events in a global one
-	envs := []event.Envelope{}
-	for _, stmID := range streamIDs {
-		envs = append(envs, s.db[stmID]...)
-	}
-
-	// default order is ASC
-	// slices.SortFunc(envs, func(a, b event.Envelope) int {
-	// 	return a.GlobalVersion().Compare(b.GlobalVersion())
-	// })
-	sort.Slice(envs, func(i, j int) bool {
-		return envs[i].GlobalVersion().Compare(envs[j].GlobalVersion()) <= 0
-	})
-
 	// prepare query params
 	q.Build()
 
@@ -235,8 +479,12 @@ func (s *Store) Replay(ctx context.Context, id event.StreamID, q event.StreamRep
 		v.GlobalStream = id.Global()
 		v.SkipTimeStamp = false
 		v.Boundaries = vb
+		// records might be purged, or hidden if their stream is soft deleted
+		v.AllowRecordGaps = gaps
 	}
 
+	now := time.Now().UTC()
+
 	cur := event.NewCursor(id.String())
 
 	fenvs := []event.Envelope{}
@@ -248,6 +496,11 @@ func (s *Store) Replay(ctx context.Context, id event.StreamID, q event.StreamRep
 		if ignore {
 			continue
 		}
+		// expired events are still part of the stream sequence until purged,
+		// thus they are only skipped once validated.
+		if event.Expired(env, now) {
+			continue
+		}
 		fenvs = append(fenvs, env)
 	}
 
@@ -260,19 +513,17 @@ func (s *Store) Replay(ctx context.Context, id event.StreamID, q event.StreamRep
 		})
 	}
 
+	records := uint(0)
+	lastRecord := event.VersionZero
 	for _, env := range fenvs {
-		// Trunc the stream based on order direction and record limit.
-		// Note that record sequences are consecutive at the integer part level
-		if q.RecordLimit > 0 {
-			if q.Order == event.StreamOrderDESC {
-				if env.GlobalVersion().Before(fenvs[0].GlobalVersion().Drop(uint64(q.RecordLimit), 0)) {
-					break
-				}
-			} else {
-				if env.GlobalVersion().Trunc().After(event.VersionZero.Add(uint64(q.RecordLimit), 0)) {
-					break
-				}
+		// Trunc the stream based on the record limit.
+		// Note that records are not necessarily consecutive once the expired ones are purged.
+		if record := env.GlobalVersion().Trunc(); !record.Equal(lastRecord) {
+			if q.RecordLimit > 0 && records == q.RecordLimit {
+				break
 			}
+			records++
+			lastRecord = record
 		}
 
 		// Call handler for each event
@@ -285,3 +536,199 @@ func (s *Store) Replay(ctx context.Context, id event.StreamID, q event.StreamRep
 
 	return nil
 }
+
+// Verify implements event.ChainVerifier.
+//
+// Records are verified as stored, including the ones hidden on read, ex: expired, not retained or soft deleted.
+func (s *Store) Verify(ctx context.Context, id event.StreamID, vrange ...event.Version) error {
+	return event.VerifyChain(ctx, storedRecords{s}, s.cfg.Serializer, id, vrange...)
+}
+
+// storedRecords replays the records of a global stream as stored, regardless of the read filters.
+// It only supports the ascending order, as required by the hash chain verification.
+type storedRecords struct {
+	s *Store
+}
+
+// Replay implements event.StreamReplayer.
+func (r storedRecords) Replay(ctx context.Context, id event.StreamID, q event.StreamReplayQuery, h event.StreamReplayHandler) error {
+	q.Build()
+
+	r.s.mu.RLock()
+	envs := []event.Envelope{}
+	for k, stm := range r.s.db {
+		if matchStream(id.String(), k) {
+			envs = append(envs, stm...)
+		}
+	}
+	r.s.mu.RUnlock()
+
+	sort.Slice(envs, func(i, j int) bool {
+		return envs[i].GlobalVersion().Before(envs[j].GlobalVersion())
+	})
+
+	records := uint(0)
+	lastRecord := event.VersionZero
+	for _, env := range envs {
+		if env.GlobalVersion().Before(q.From) {
+			continue
+		}
+		if env.GlobalVersion().After(q.To) {
+			break
+		}
+		if record := env.GlobalVersion().Trunc(); !record.Equal(lastRecord) {
+			if records == q.RecordLimit {
+				break
+			}
+			records++
+			lastRecord = record
+		}
+		if err := h(ctx, event.StreamData{Type: event.StreamDataTypeRecord, Value: env}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Query implements event.StreamQuerier.
+//
+// Events are sorted by global version; the record limit applies to records
+// (i.e. the integer part of the global version) that contain at least one matching event.
+// The returned cursor is opaque and is only set if more records remain to be queried.
+func (s *Store) Query(ctx context.Context, id event.StreamID, q event.StreamQuery) (*event.StreamQueryResult, error) {
+	q.Build()
+
+	var after *event.Version
+	if q.Cursor != nil {
+		ver, err := decodeCursor(*q.Cursor)
+		if err != nil {
+			return nil, event_errors.Err(event.ErrInvalidCursor, id.String(), err)
+		}
+		after = &ver
+	}
+
+	envs, _ := s.globalEvents(id)
+	if q.Order == event.StreamOrderDESC {
+		slices.Reverse(envs)
+	}
+	if filtered(q) {
+		envs = s.indexedEvents(id, envs)
+	}
+
+	now := time.Now().UTC()
+
+	result := &event.StreamQueryResult{
+		Events: make([]event.Envelope, 0),
+	}
+	records := 0
+	lastRecord := event.VersionZero
+	for _, env := range envs {
+		record := env.GlobalVersion().Trunc()
+		if after != nil {
+			if q.Order == event.StreamOrderDESC && !record.Before(*after) {
+				continue
+			}
+			if q.Order != event.StreamOrderDESC && !record.After(*after) {
+				continue
+			}
+		}
+		if event.Expired(env, now) {
+			continue
+		}
+		if !q.From.IsZero() && env.At().Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && env.At().After(q.To) {
+			continue
+		}
+		if len(q.Users) > 0 && !slices.Contains(q.Users, env.User()) {
+			continue
+		}
+		if len(q.Types) > 0 && !slices.Contains(q.Types, env.Type()) {
+			continue
+		}
+		if len(q.IPAddrs) > 0 && !slices.Contains(q.IPAddrs, env.IPAddr()) {
+			continue
+		}
+		if len(q.CorrelationIDs) > 0 && !slices.Contains(q.CorrelationIDs, env.CorrelationID()) {
+			continue
+		}
+		if len(q.CausationIDs) > 0 && !slices.Contains(q.CausationIDs, env.CausationID()) {
+			continue
+		}
+		if len(q.TraceIDs) > 0 && !slices.Contains(q.TraceIDs, env.TraceID()) {
+			continue
+		}
+
+		if !record.Equal(lastRecord) {
+			// the limit is reached while there are still matching records;
+			// return a cursor pointing to the last returned record.
+			if records == q.RecordLimit {
+				cursor := encodeCursor(lastRecord)
+				result.Cursor = &cursor
+				break
+			}
+			records++
+			lastRecord = record
+		}
+
+		result.Events = append(result.Events, env)
+	}
+
+	return result, nil
+}
+
+// PurgeExpired implements event.ExpiryPurger.
+func (s *Store) PurgeExpired(ctx context.Context, at time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for stmID, envs := range s.db {
+		fenvs := make([]event.Envelope, 0, len(envs))
+		for i := 0; i < len(envs); {
+			// lookup the record boundaries, events of a record are appended together
+			record := envs[i].GlobalVersion().Trunc()
+			j := i
+			expired := true
+			for j < len(envs) && envs[j].GlobalVersion().Trunc().Equal(record) {
+				expired = expired && event.Expired(envs[j], at)
+				j++
+			}
+			// keep the last record as the stream head
+			if expired && j < len(envs) {
+				count += j - i
+				s.markGaps(stmID)
+			} else {
+				fenvs = append(fenvs, envs[i:j]...)
+			}
+			i = j
+		}
+		s.db[stmID] = fenvs
+	}
+
+	return count, nil
+}
+
+// markGaps records that the global stream of the given stream has missing records.
+// The store lock must be held by the caller.
+func (s *Store) markGaps(stmID string) {
+	if id, err := event.ParseStreamID(stmID); err == nil {
+		s.gaps[id.GlobalID()] = true
+	}
+}
+
+// encodeCursor returns an opaque cursor based on the given record version.
+func encodeCursor(ver event.Version) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(ver.String()))
+}
+
+// decodeCursor parses the record version from the given cursor.
+func decodeCursor(cursor string) (event.Version, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return event.VersionZero, err
+	}
+	return event.ParseVersion(string(b))
+}
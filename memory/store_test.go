@@ -2,9 +2,16 @@ package memory
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/ln80/event-store/event"
+	event_errors "github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/event/sourcing"
 	"github.com/ln80/event-store/eventtest"
+	"github.com/ln80/event-store/json"
 )
 
 func TestEventStore(t *testing.T) {
@@ -18,4 +25,424 @@ func TestEventStore(t *testing.T) {
 
 		opt.SupportOrderDESC = true
 	})
+	eventtest.TestEventStreamQuerier(t, ctx, NewEventStore(), func(opt *eventtest.TestEventStreamQuerierOptions) {
+		opt.SupportOrderDESC = true
+	})
+	eventtest.TestEventStreamSubscriber(t, ctx, NewEventStore())
+	eventtest.TestEventStoreExpiry(t, ctx, NewEventStore())
+	eventtest.TestEventMultiStreamStore(t, ctx, NewEventStore())
+	eventtest.TestEventStreamLifecycle(t, ctx, NewEventStore(), NewEventStore())
+	eventtest.TestEventStreamRetention(t, ctx, NewEventStore())
+	eventtest.TestEventChainVerifier(t, ctx, NewEventStore(func(sc *StoreConfig) {
+		sc.HashChain = true
+	}))
+
+	limit := event.SizeLimit{Record: 4096, Event: 1024}
+	eventtest.TestEventStoreSizeLimit(t, ctx, NewEventStore(func(sc *StoreConfig) {
+		sc.SizeLimit = limit
+	}), limit)
+}
+
+func TestEventStore_AppendToStreamsRollback(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	errMock := errors.New("on append mock error")
+	globalID := event.UID().String()
+	failOn := event.NewStreamID(globalID, "B")
+
+	store := NewEventStore(func(sc *StoreConfig) {
+		sc.OnAppend = func(ctx context.Context, id event.StreamID, events []event.Envelope) error {
+			if id.String() == failOn.String() {
+				return errMock
+			}
+			return nil
+		}
+	})
+
+	chunkA := sourcing.Wrap(ctx, event.NewStreamID(globalID, "A"), event.VersionZero, eventtest.GenEvents(2))
+	chunkB := sourcing.Wrap(ctx, failOn, event.VersionZero, eventtest.GenEvents(2))
+	err := store.AppendToStreams(ctx, []sourcing.Stream{chunkA, chunkB})
+	if want, got := errMock, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// the first chunk is rolled back
+	stm, err := store.LoadStream(ctx, chunkA.ID())
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if !stm.Empty() {
+		t.Fatalf("expect stream %s be empty", chunkA.ID())
+	}
+
+	// the global stream sequence starts from scratch
+	failOn = event.NewStreamID(globalID, "none")
+	if err := store.AppendToStreams(ctx, []sourcing.Stream{chunkA}); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := event.VersionMin, chunkA.Unwrap()[0].GlobalVersion(); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestEventStore_Archive(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	errMock := errors.New("on append mock error")
+	appends, failOn := 0, 2
+	cold := NewEventStore(func(sc *StoreConfig) {
+		sc.OnAppend = func(ctx context.Context, id event.StreamID, events []event.Envelope) error {
+			if appends++; appends == failOn {
+				return errMock
+			}
+			return nil
+		}
+	})
+	store := NewEventStore()
+
+	globalID := event.UID().String()
+	other, streamID := event.NewStreamID(globalID, "A"), event.NewStreamID(globalID, "B")
+	if err := store.Append(ctx, other, event.Wrap(ctx, other, eventtest.GenEvents(1))); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	envs := make([]event.Envelope, 0)
+	for i := 0; i < 3; i++ {
+		record := event.Wrap(ctx, streamID, eventtest.GenEvents(2))
+		if err := store.Append(ctx, streamID, record); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		envs = append(envs, record...)
+	}
+	gvers := make([]event.Version, 0, len(envs))
+	for _, env := range envs {
+		gvers = append(gvers, env.GlobalVersion())
+	}
+
+	// the store can't be archived to itself, even if decorated
+	for _, cold := range []event.Store{store, struct{ event.Store }{store}} {
+		if err := store.Archive(ctx, streamID, cold); !errors.Is(err, event.ErrStreamLifecycleFailed) {
+			t.Fatalf("expect %v error to occur, got: %v", event.ErrStreamLifecycleFailed, err)
+		}
+	}
+
+	// a failed archive leaves the stream active and untouched
+	if err := store.Archive(ctx, streamID, cold); !errors.Is(err, errMock) {
+		t.Fatalf("expect %v error to occur, got: %v", errMock, err)
+	}
+	if status, _ := store.Status(ctx, streamID); status != event.StreamActive {
+		t.Fatalf("expect %v, %v be equals", event.StreamActive, status)
+	}
+	for i, env := range envs {
+		if want, got := gvers[i], env.GlobalVersion(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	// retrying the archive skips the already archived records
+	if err := store.Archive(ctx, streamID, cold); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	renvs, err := cold.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := len(envs), len(renvs); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	for i, env := range envs {
+		if want, got := env.ID(), renvs[i].ID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
+
+func TestEventStore_HardDeleteTombstone(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store := NewEventStore()
+
+	streamID := event.NewStreamID(event.UID().String())
+	if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(1))); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := store.HardDelete(ctx, streamID); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	envs := make([]event.Envelope, 0)
+	if err := store.Replay(ctx, streamID, event.StreamReplayQuery{}, func(ctx context.Context, data event.StreamData) error {
+		envs = append(envs, data.Value.(event.Envelope))
+		return nil
+	}); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 1, len(envs); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	// the tombstone must be registered to be deserialized
+	namespace := "tombstone" + event.UID().String()
+	ser := json.NewEventSerializer(namespace)
+	b, err := ser.MarshalEventBatch(ctx, envs)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	renvs, err := ser.UnmarshalEventBatch(ctx, b)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if evt := renvs[0].Event(); evt != nil {
+		t.Fatalf("expect event be nil, got %T", evt)
+	}
+	event.NewRegister(namespace).Set(event.StreamTombstone{})
+	renvs, err = ser.UnmarshalEventBatch(ctx, b)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if _, ok := renvs[0].Event().(*event.StreamTombstone); !ok {
+		t.Fatalf("expect tombstone be found, got %T", renvs[0].Event())
+	}
+}
+
+func TestEventStore_RecordGaps(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store := NewEventStore()
+
+	streamID := event.NewStreamID(event.UID().String())
+	for i := 0; i < 3; i++ {
+		if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(2))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	}
+
+	replay := func() error {
+		return store.Replay(ctx, streamID, event.StreamReplayQuery{}, func(ctx context.Context, data event.StreamData) error {
+			return nil
+		})
+	}
+
+	// simulate a lost record
+	envs := store.db[streamID.String()]
+	store.db[streamID.String()] = append(envs[:2:2], envs[4:]...)
+
+	// gaps are only allowed once records are removed on purpose
+	if err := replay(); !errors.Is(err, event.ErrInvalidStream) {
+		t.Fatalf("expect %v error to occur, got: %v", event.ErrInvalidStream, err)
+	}
+
+	store.markGaps(streamID.String())
+	if err := replay(); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+}
+
+func TestEventStore_VerifyChain(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	errMock := errors.New("on append mock error")
+	globalID := event.UID().String()
+	streamID := event.NewStreamID(globalID, "A")
+	failOn := event.NewStreamID(globalID, "C")
+
+	store := NewEventStore(func(sc *StoreConfig) {
+		sc.HashChain = true
+		sc.OnAppend = func(ctx context.Context, id event.StreamID, events []event.Envelope) error {
+			if id.String() == failOn.String() {
+				return errMock
+			}
+			return nil
+		}
+	})
+
+	records := [][]event.Envelope{
+		event.Wrap(ctx, streamID, eventtest.GenEvents(2)),
+		event.Wrap(ctx, streamID, eventtest.GenEvents(2)),
+		event.Wrap(ctx, streamID, eventtest.GenEvents(2)),
+	}
+	for _, envs := range records[:2] {
+		if err := store.Append(ctx, streamID, envs); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	}
+
+	// a failed multi-stream append doesn't alter the chain
+	err := store.AppendToStreams(ctx, []sourcing.Stream{
+		sourcing.Wrap(ctx, event.NewStreamID(globalID, "B"), event.VersionZero, eventtest.GenEvents(1)),
+		sourcing.Wrap(ctx, failOn, event.VersionZero, eventtest.GenEvents(1)),
+	})
+	if !errors.Is(err, errMock) {
+		t.Fatalf("expect %v error to occur, got: %v", errMock, err)
+	}
+	if err := store.Append(ctx, streamID, records[2]); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := store.Verify(ctx, streamID); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// tamper with the second record
+	records[1][0].(event.RWEnvelope).SetUser("mallory")
+
+	err = store.Verify(ctx, streamID)
+	if !errors.Is(err, event.ErrHashChainBroken) {
+		t.Fatalf("expect %v error to occur, got: %v", event.ErrHashChainBroken, err)
+	}
+	ok, brk := event_errors.ErrAs[event.ChainBreak](err)
+	if !ok {
+		t.Fatalf("expect chain break details, got: %v", err)
+	}
+	if want, got := records[1][0].GlobalVersion().Trunc(), brk.Record; !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// the link to the predecessor of the first record in range is not checked
+	if err := store.Verify(ctx, streamID, records[2][0].GlobalVersion()); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+}
+
+func TestEventStore_VerifyChainWithHiddenRecords(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store := NewEventStore(func(sc *StoreConfig) {
+		sc.HashChain = true
+	})
+
+	globalID := event.UID().String()
+	streamA, streamB, streamC := event.NewStreamID(globalID, "A"), event.NewStreamID(globalID, "B"), event.NewStreamID(globalID, "C")
+
+	// a partially expired record
+	envs := event.Wrap(ctx, streamA, eventtest.GenEvents(2))
+	envs[0].(event.RWEnvelope).SetTTL(time.Microsecond)
+	if err := store.Append(ctx, streamA, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	// a soft deleted stream
+	if err := store.Append(ctx, streamB, event.Wrap(ctx, streamB, eventtest.GenEvents(1))); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := store.SoftDelete(ctx, streamB); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	// records out of the retention policy, but not yet compacted
+	for i := 0; i < 3; i++ {
+		if err := store.Append(ctx, streamC, event.Wrap(ctx, streamC, eventtest.GenEvents(1))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	}
+	if err := store.SetStreamMetadata(ctx, streamC, event.StreamMetadata{MaxCount: 1}); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := store.Append(ctx, streamA, event.Wrap(ctx, streamA, eventtest.GenEvents(1))); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	time.Sleep(10 * time.Microsecond)
+
+	// hidden records would be reported as broken links using the store replay
+	err := event.VerifyChain(ctx, store, store.cfg.Serializer, event.NewStreamID(globalID))
+	if !errors.Is(err, event.ErrHashChainBroken) {
+		t.Fatalf("expect %v error to occur, got: %v", event.ErrHashChainBroken, err)
+	}
+
+	if err := store.Verify(ctx, event.NewStreamID(globalID)); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+}
+
+func TestEventStore_SubscribeLagging(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := NewEventStore(func(sc *StoreConfig) {
+		sc.SubscriptionBufferSize = 1
+	})
+
+	streamID := event.NewStreamID(event.UID().String())
+
+	var (
+		mu      sync.Mutex
+		events  = make([]event.Envelope, 0)
+		lagging = 0
+	)
+	caughtUp := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	done := make(chan error, 1)
+
+	go func() {
+		done <- store.Subscribe(ctx, streamID, event.VersionZero, func(ctx context.Context, data event.StreamData) error {
+			switch data.Type {
+			case event.StreamDataTypeRecord:
+				// block the consumer on the first record to make it fall behind
+				<-unblock
+				mu.Lock()
+				events = append(events, data.Value.(event.Envelope))
+				mu.Unlock()
+			case event.StreamDataTypeContinue:
+				select {
+				case caughtUp <- struct{}{}:
+				default:
+				}
+			case event.StreamDataTypeLagging:
+				mu.Lock()
+				lagging++
+				mu.Unlock()
+			}
+			return nil
+		})
+	}()
+
+	<-caughtUp
+
+	for i := 0; i < 10; i++ {
+		if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(1))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	}
+	close(unblock)
+
+	deadline := time.After(5 * time.Second)
+	for loop := true; loop; {
+		mu.Lock()
+		l := len(events)
+		mu.Unlock()
+		if l >= 10 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("expect events count be %d, got %d", 10, l)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	if lagging == 0 {
+		t.Fatal("expect lagging signal be received")
+	}
+	if want, got := 10, len(events); want != got {
+		t.Fatalf("expect events count be %d, got %d", want, got)
+	}
+	for i, env := range events {
+		if want, got := event.VersionMin.Add(uint64(i), 0), env.GlobalVersion(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
 }
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ln80/event-store/event"
+)
+
+// subscription presents a live subscription to a global stream or one of its sub-streams.
+type subscription struct {
+	streamID string
+	records  chan []event.Envelope
+	// lagging is set once the subscription buffer is full and some records have been dropped.
+	lagging atomic.Bool
+}
+
+// match returns true if the given stream belongs to the subscription stream.
+func (sub *subscription) match(streamID string) bool {
+	return matchStream(sub.streamID, streamID)
+}
+
+// matchStream returns true if the given stream is the same or a sub-stream of the parent one.
+func matchStream(parent, streamID string) bool {
+	return streamID == parent || strings.HasPrefix(streamID, parent+event.StreamIDPartsDelimiter)
+}
+
+// notify pushes the appended record to the matching subscriptions without blocking.
+// It must be called while holding the store lock.
+func (s *Store) notify(id event.StreamID, events []event.Envelope) {
+	for _, sub := range s.subscriptions[id.GlobalID()] {
+		if !sub.match(id.String()) {
+			continue
+		}
+		select {
+		case sub.records <- events:
+		default:
+			sub.lagging.Store(true)
+		}
+	}
+}
+
+// Subscribe implements event.StreamSubscriber.
+//
+// The subscription starts by catching up from the given global version; it then tails the newly appended records.
+// A slow consumer whose buffer is full is switched back to catch-up mode, and receives a lagging signal.
+func (s *Store) Subscribe(ctx context.Context, id event.StreamID, from event.Version, h event.StreamReplayHandler) error {
+	if from.IsZero() {
+		from = event.VersionMin
+	}
+
+	sub := &subscription{
+		streamID: id.String(),
+		records:  make(chan []event.Envelope, s.cfg.SubscriptionBufferSize),
+	}
+
+	// register the subscription before catching up to make sure no record is missed.
+	s.mu.Lock()
+	s.subscriptions[id.GlobalID()] = append(s.subscriptions[id.GlobalID()], sub)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.subscriptions[id.GlobalID()] = slices.DeleteFunc(s.subscriptions[id.GlobalID()], func(stored *subscription) bool {
+			return stored == sub
+		})
+	}()
+
+	last := event.VersionZero
+	deliver := func(env event.Envelope) error {
+		// records might be received twice, during catch-up and from the subscription buffer.
+		if !last.IsZero() && !env.GlobalVersion().After(last) {
+			return nil
+		}
+		if env.GlobalVersion().Before(from) {
+			return nil
+		}
+		if err := h(ctx, event.StreamData{Type: event.StreamDataTypeRecord, Value: env}); err != nil {
+			return err
+		}
+		last = env.GlobalVersion()
+		return nil
+	}
+
+	for {
+		// catch-up mode
+		envs, _ := s.globalEvents(id)
+		for _, env := range envs {
+			if err := deliver(env); err != nil {
+				return err
+			}
+		}
+		if err := h(ctx, event.StreamData{Type: event.StreamDataTypeContinue, Value: last}); err != nil {
+			return err
+		}
+
+		// live mode
+		if err := s.tail(ctx, sub, deliver); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err := h(ctx, event.StreamData{Type: event.StreamDataTypeLagging, Value: last}); err != nil {
+			return err
+		}
+	}
+}
+
+// tail delivers buffered records until the context is canceled or the subscription is lagging.
+func (s *Store) tail(ctx context.Context, sub *subscription, deliver func(env event.Envelope) error) error {
+	for {
+		if sub.lagging.Load() {
+			// drop the buffer as records are going to be delivered in catch-up mode.
+			sub.lagging.Store(false)
+			for len(sub.records) > 0 {
+				<-sub.records
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case envs := <-sub.records:
+			for _, env := range envs {
+				if err := deliver(env); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// globalEvents returns the events of the given stream and its sub-streams, sorted by global version.
+// Streams retention policies are enforced, even before records are compacted.
+// It also reports whether the global stream might have gaps, i.e. removed or hidden records.
+func (s *Store) globalEvents(id event.StreamID) (envs []event.Envelope, gaps bool) {
+	now := time.Now().UTC()
+
+	s.mu.RLock()
+	envs = []event.Envelope{}
+	gaps = s.gaps[id.GlobalID()]
+	for k, stm := range s.db {
+		if !matchStream(id.String(), k) {
+			continue
+		}
+		// soft deleted streams are hidden
+		if s.status(k) == event.StreamSoftDeleted {
+			gaps = gaps || len(stm) > 0
+			continue
+		}
+		if md, ok := s.metadata[k]; ok && md.HasRetention() {
+			retained := md.Retain(stm, now)
+			gaps = gaps || len(retained) < len(stm)
+			stm = retained
+		}
+		envs = append(envs, stm...)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(envs, func(i, j int) bool {
+		return envs[i].GlobalVersion().Before(envs[j].GlobalVersion())
+	})
+
+	return envs, gaps
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ln80/event-store/event"
+	event_errors "github.com/ln80/event-store/event/errors"
+)
+
+// TxItem presents a custom write operation to execute along with the appended events.
+// It's the only kind of item supported by the in-memory store in event.AppendConfig.AddToTx.
+//
+// Items are executed once the events are appended, while the store lock is held; thus they must not call the store.
+// The append is rolled back if an item returns an error, but it's up to the item to roll back its own side effects.
+type TxItem func(ctx context.Context) error
+
+// addToTx executes the caller items returned by AddToTx, the store lock must be held by the caller.
+func (s *Store) addToTx(ctx context.Context, id event.StreamID, cfg *event.AppendConfig) error {
+	if cfg.AddToTx == nil {
+		return nil
+	}
+	for _, item := range cfg.AddToTx(ctx) {
+		var err error
+		switch it := item.(type) {
+		case TxItem:
+			err = it(ctx)
+		case func(context.Context) error:
+			err = it(ctx)
+		default:
+			return event_errors.Err(event.ErrUnsupportedAppendOption, id.String(), fmt.Sprintf("unsupported tx item %T", item))
+		}
+		if err != nil {
+			return event_errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+		}
+	}
+	return nil
+}
+
+// keep keeps the current state of the given streams and returns a function that restores it.
+// The streams must belong to the same global stream, and the store lock must be held by the caller.
+func (s *Store) keep(ids ...event.StreamID) (rollback func()) {
+	globalID := ids[0].GlobalID()
+	checkpoint, hasCheckpoint := s.checkpoints[globalID]
+	chain := s.chains[globalID]
+	dbs := make(map[string][]event.Envelope, len(ids))
+	for _, id := range ids {
+		if db, ok := s.db[id.String()]; ok {
+			dbs[id.String()] = db
+		}
+	}
+
+	return func() {
+		for _, id := range ids {
+			if db, ok := dbs[id.String()]; ok {
+				s.db[id.String()] = db
+			} else {
+				delete(s.db, id.String())
+			}
+		}
+		if hasCheckpoint {
+			s.checkpoints[globalID] = checkpoint
+		} else {
+			delete(s.checkpoints, globalID)
+		}
+		if s.cfg.HashChain {
+			s.chains[globalID] = chain
+		}
+	}
+}
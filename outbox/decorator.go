@@ -0,0 +1,50 @@
+package outbox
+
+import (
+	"context"
+
+	es "github.com/ln80/event-store"
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/sourcing"
+)
+
+// Decorator records the appended events in the outbox, in the same transaction as the append.
+//
+// The outbox items are added to the event.AppendConfig.AddToTx option, thus messages are recorded
+// if and only if the events are appended. The wrapped event store must support the items returned by the outbox,
+// ex: sql.Store along with sql.OutboxStore, or memory.Store along with MemoryStore.
+type Decorator struct {
+	outbox TxStore
+	es.EventStore
+}
+
+// NewDecorator returns an event store decorator that records appended events in the given outbox.
+func NewDecorator(store es.EventStore, outbox TxStore) *Decorator {
+	return &Decorator{
+		outbox:     outbox,
+		EventStore: store,
+	}
+}
+
+func (d *Decorator) Append(ctx context.Context, id event.StreamID, events []event.Envelope, optFns ...func(*event.AppendConfig)) error {
+	return d.EventStore.Append(ctx, id, events, d.addToTx(events, optFns)...)
+}
+
+func (d *Decorator) AppendToStream(ctx context.Context, chunk sourcing.Stream, optFns ...func(opt *event.AppendConfig)) error {
+	return d.EventStore.AppendToStream(ctx, chunk, d.addToTx(chunk.Unwrap(), optFns)...)
+}
+
+// addToTx returns the given append options along with an extra one that adds the outbox items
+// to the caller ones, if any.
+func (d *Decorator) addToTx(events []event.Envelope, optFns []func(*event.AppendConfig)) []func(*event.AppendConfig) {
+	return append(optFns[:len(optFns):len(optFns)], func(cfg *event.AppendConfig) {
+		addToTx := cfg.AddToTx
+		cfg.AddToTx = func(ctx context.Context) []any {
+			var items []any
+			if addToTx != nil {
+				items = addToTx(ctx)
+			}
+			return append(items, d.outbox.TxItems(ctx, newMessages(events)...)...)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/eventtest"
+	"github.com/ln80/event-store/memory"
+)
+
+func TestDecorator(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	outbox := NewMemoryStore()
+	store := NewDecorator(memory.NewEventStore(), outbox)
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, eventtest.GenEvents(2))
+	if err := store.Append(ctx, streamID, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 1, outbox.Len(); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	// messages are not recorded if the append fails
+	if err := store.Append(ctx, streamID, envs); !errors.Is(err, event.ErrAppendEventsConflict) {
+		t.Fatalf("expect %v error to occur, got: %v", event.ErrAppendEventsConflict, err)
+	}
+	if want, got := 1, outbox.Len(); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	// the append is rolled back along with the messages if a caller tx item fails
+	errTx := errors.New("tx item failure")
+	err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(2)), func(ac *event.AppendConfig) {
+		ac.AddToTx = func(ctx context.Context) []any {
+			return []any{memory.TxItem(func(ctx context.Context) error { return errTx })}
+		}
+	})
+	if !errors.Is(err, errTx) {
+		t.Fatalf("expect %v error to occur, got: %v", errTx, err)
+	}
+	if want, got := 1, outbox.Len(); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	loaded, err := store.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := len(envs), len(loaded); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	// caller tx items are kept along with the outbox ones
+	called := false
+	if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(2)), func(ac *event.AppendConfig) {
+		ac.AddToTx = func(ctx context.Context) []any {
+			return []any{memory.TxItem(func(ctx context.Context) error { called = true; return nil })}
+		}
+	}); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if !called {
+		t.Fatal("expect caller tx item be called")
+	}
+	if want, got := 2, outbox.Len(); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+}
@@ -0,0 +1,211 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ln80/event-store/event"
+)
+
+// Message presents an event to publish to a single destination.
+type Message struct {
+	ID            string
+	Dest          string
+	Event         event.Envelope
+	Attempts      int
+	NextAttemptAt time.Time
+}
+
+// newMessages returns a message per event destination.
+func newMessages(events []event.Envelope) []Message {
+	msgs := make([]Message, 0)
+	for _, env := range events {
+		for _, dest := range env.Dests() {
+			msgs = append(msgs, Message{
+				ID:    env.ID() + "/" + dest,
+				Dest:  dest,
+				Event: env,
+			})
+		}
+	}
+	return msgs
+}
+
+// DeadLetter presents a message that can't be published, along with the original error.
+type DeadLetter struct {
+	Message
+	Err error
+	At  time.Time
+}
+
+// Store presents the outbox of messages waiting to be published.
+type Store interface {
+	// Add records the given messages. Adding an already recorded message is a no-op.
+	Add(ctx context.Context, msgs ...Message) error
+	// Due returns, in the recording order, the messages whose next attempt is due at the given time.
+	Due(ctx context.Context, now time.Time, limit int) ([]Message, error)
+	// Ack removes the given messages from the outbox.
+	Ack(ctx context.Context, ids ...string) error
+	// Retry updates the message attempts and the next attempt time.
+	Retry(ctx context.Context, msg Message) error
+}
+
+// TxStore presents an outbox able to record messages in the event store transaction.
+type TxStore interface {
+	Store
+	// TxItems returns the items to add to the event store transaction (see event.AppendConfig.AddToTx)
+	// in order to record the given messages.
+	TxItems(ctx context.Context, msgs ...Message) []any
+}
+
+// DeadLetterStore presents the storage of poison messages.
+type DeadLetterStore interface {
+	// Put records the given dead letter.
+	Put(ctx context.Context, letter DeadLetter) error
+	// List returns the dead letters of the given destination.
+	List(ctx context.Context, dest string) ([]DeadLetter, error)
+}
+
+// MemoryStore implements Store in memory, mainly used for testing purposes.
+type MemoryStore struct {
+	mu   sync.Mutex
+	msgs []Message
+}
+
+var _ TxStore = &MemoryStore{}
+
+// NewMemoryStore returns an in-memory outbox.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		msgs: make([]Message, 0),
+	}
+}
+
+// Add implements Store interface.
+func (s *MemoryStore) Add(ctx context.Context, msgs ...Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, msg := range msgs {
+		if s.indexOf(msg.ID) != -1 {
+			continue
+		}
+		s.msgs = append(s.msgs, msg)
+	}
+
+	return nil
+}
+
+// TxItems implements TxStore interface.
+// It returns an item supported by the in-memory event store, which records the messages once the events are appended.
+func (s *MemoryStore) TxItems(ctx context.Context, msgs ...Message) []any {
+	if len(msgs) == 0 {
+		return nil
+	}
+	return []any{func(ctx context.Context) error {
+		return s.Add(ctx, msgs...)
+	}}
+}
+
+// Due implements Store interface.
+func (s *MemoryStore) Due(ctx context.Context, now time.Time, limit int) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msgs := make([]Message, 0)
+	for _, msg := range s.msgs {
+		if limit > 0 && len(msgs) == limit {
+			break
+		}
+		if msg.NextAttemptAt.After(now) {
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, nil
+}
+
+// Ack implements Store interface.
+func (s *MemoryStore) Ack(ctx context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if i := s.indexOf(id); i != -1 {
+			s.msgs = append(s.msgs[:i], s.msgs[i+1:]...)
+		}
+	}
+
+	return nil
+}
+
+// Retry implements Store interface.
+func (s *MemoryStore) Retry(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.indexOf(msg.ID); i != -1 {
+		s.msgs[i] = msg
+	}
+
+	return nil
+}
+
+// Len returns the count of messages waiting to be published.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.msgs)
+}
+
+func (s *MemoryStore) indexOf(id string) int {
+	for i, msg := range s.msgs {
+		if msg.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// MemoryDeadLetterStore implements DeadLetterStore in memory, mainly used for testing purposes.
+type MemoryDeadLetterStore struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+var _ DeadLetterStore = &MemoryDeadLetterStore{}
+
+// NewMemoryDeadLetterStore returns an in-memory dead-letter store.
+func NewMemoryDeadLetterStore() *MemoryDeadLetterStore {
+	return &MemoryDeadLetterStore{
+		letters: make([]DeadLetter, 0),
+	}
+}
+
+// Put implements DeadLetterStore interface.
+func (s *MemoryDeadLetterStore) Put(ctx context.Context, letter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.letters = append(s.letters, letter)
+
+	return nil
+}
+
+// List implements DeadLetterStore interface.
+func (s *MemoryDeadLetterStore) List(ctx context.Context, dest string) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters := make([]DeadLetter, 0)
+	for _, letter := range s.letters {
+		if letter.Dest == dest {
+			letters = append(letters, letter)
+		}
+	}
+
+	return letters, nil
+}
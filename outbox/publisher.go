@@ -0,0 +1,234 @@
+package outbox
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/logger"
+)
+
+var (
+	ErrSinkNotFound = errors.New("sink not found")
+)
+
+const (
+	DefaultMaxAttempts  int           = 5
+	DefaultBatchSize    int           = 100
+	DefaultPollInterval time.Duration = time.Second
+)
+
+// Sink delivers events to a single destination (ex: a message broker topic).
+type Sink interface {
+	Send(ctx context.Context, env event.Envelope) error
+}
+
+// SinkFunc is an adapter to use ordinary functions as sinks.
+type SinkFunc func(ctx context.Context, env event.Envelope) error
+
+// Send implements Sink interface.
+func (f SinkFunc) Send(ctx context.Context, env event.Envelope) error {
+	return f(ctx, env)
+}
+
+// DestinationErrors contains the publishing errors per destination, in the dispatch order.
+type DestinationErrors map[string][]error
+
+// Error implements error interface.
+func (e DestinationErrors) Error() string {
+	dests := make([]string, 0, len(e))
+	for dest := range e {
+		dests = append(dests, dest)
+	}
+	sort.Strings(dests)
+
+	strs := make([]string, 0, len(dests))
+	for _, dest := range dests {
+		for _, err := range e[dest] {
+			strs = append(strs, dest+": "+err.Error())
+		}
+	}
+	return strings.Join(strs, ", ")
+}
+
+// Unwrap returns the destination errors.
+func (e DestinationErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, derrs := range e {
+		errs = append(errs, derrs...)
+	}
+	return errs
+}
+
+// DefaultBackoff returns an exponential delay starting from 100ms, and capped to 1 minute.
+func DefaultBackoff(attempt int) time.Duration {
+	d := 100 * time.Millisecond
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= time.Minute {
+			return time.Minute
+		}
+	}
+	return d
+}
+
+// PublisherConfig presents the outbox publisher configuration.
+type PublisherConfig struct {
+	// MaxAttempts defines the count of attempts after which a message is moved to the dead-letter store.
+	MaxAttempts int
+	// Backoff returns the delay to wait before the next attempt.
+	Backoff func(attempt int) time.Duration
+	// BatchSize defines the max count of messages dispatched at once.
+	BatchSize int
+	// PollInterval defines the duration to wait between two dispatches when running in background.
+	PollInterval time.Duration
+}
+
+// Publisher implements event.Publisher on top of an outbox.
+//
+// Publish only records a message per event destination in the outbox, while Dispatch (or Run) delivers
+// the recorded messages to the destinations' sinks. Failed messages are retried with backoff,
+// and moved to the dead-letter store once the max attempts is reached.
+// Delivery is at-least-once, and the ordering is best-effort.
+type Publisher struct {
+	outbox      Store
+	deadLetters DeadLetterStore
+	sinks       map[string]Sink
+
+	cfg *PublisherConfig
+}
+
+var _ event.Publisher = &Publisher{}
+
+// NewPublisher returns an outbox publisher that dispatches messages to the given sinks, which are indexed by destination.
+func NewPublisher(outbox Store, deadLetters DeadLetterStore, sinks map[string]Sink, opts ...func(*PublisherConfig)) *Publisher {
+	cfg := &PublisherConfig{
+		MaxAttempts:  DefaultMaxAttempts,
+		Backoff:      DefaultBackoff,
+		BatchSize:    DefaultBatchSize,
+		PollInterval: DefaultPollInterval,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	if sinks == nil {
+		sinks = make(map[string]Sink)
+	}
+
+	return &Publisher{
+		outbox:      outbox,
+		deadLetters: deadLetters,
+		sinks:       sinks,
+		cfg:         cfg,
+	}
+}
+
+// Publish implements event.Publisher interface.
+// It records the events to publish in the outbox.
+func (p *Publisher) Publish(ctx context.Context, events []event.Envelope) error {
+	msgs := newMessages(events)
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if err := p.outbox.Add(ctx, msgs...); err != nil {
+		return errors.Err(event.ErrPublishEventFailed, events[0].StreamID(), err)
+	}
+
+	return nil
+}
+
+// Run keeps dispatching the outbox messages until the context is canceled.
+// Dispatch errors are logged, and it returns a nil error once the context is canceled.
+func (p *Publisher) Run(ctx context.Context) error {
+	for {
+		if err := p.Dispatch(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logger.FromContext(ctx).WithName("outbox").Error(err, "Dispatch outbox messages failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(p.cfg.PollInterval):
+		}
+	}
+}
+
+// Dispatch delivers the due outbox messages to their sinks.
+//
+// It returns an event.ErrPublishEventFailed error which wraps DestinationErrors if some messages fail.
+func (p *Publisher) Dispatch(ctx context.Context) error {
+	msgs, err := p.outbox.Due(ctx, time.Now(), p.cfg.BatchSize)
+	if err != nil {
+		return errors.Err(event.ErrPublishEventFailed, "", err)
+	}
+
+	derrs := make(DestinationErrors)
+	for _, msg := range msgs {
+		if err := p.dispatch(ctx, msg); err != nil {
+			derrs[msg.Dest] = append(derrs[msg.Dest], err)
+		}
+	}
+
+	if len(derrs) > 0 {
+		return errors.Err(event.ErrPublishEventFailed, "", derrs)
+	}
+
+	return nil
+}
+
+// dispatch delivers the given message, and updates the outbox based on the result.
+func (p *Publisher) dispatch(ctx context.Context, msg Message) error {
+	sink, ok := p.sinks[msg.Dest]
+	if !ok {
+		// no need to retry, the message is a poison one.
+		return p.kill(ctx, msg, errors.Err(ErrSinkNotFound, msg.Event.StreamID(), msg.Dest))
+	}
+
+	msg.Attempts++
+	sendErr := sink.Send(ctx, msg.Event)
+	if sendErr == nil {
+		return p.outbox.Ack(ctx, msg.ID)
+	}
+
+	if msg.Attempts >= p.cfg.MaxAttempts {
+		return p.kill(ctx, msg, sendErr)
+	}
+
+	msg.NextAttemptAt = time.Now().Add(p.cfg.Backoff(msg.Attempts))
+	if err := p.outbox.Retry(ctx, msg); err != nil {
+		return err
+	}
+
+	return sendErr
+}
+
+// kill moves the given message to the dead-letter store.
+func (p *Publisher) kill(ctx context.Context, msg Message, cause error) error {
+	logger.FromContext(ctx).WithName("outbox").Error(cause, "Move message to dead-letter store",
+		"id", msg.ID,
+		"attempts", msg.Attempts)
+
+	if err := p.deadLetters.Put(ctx, DeadLetter{
+		Message: msg,
+		Err:     cause,
+		At:      time.Now(),
+	}); err != nil {
+		return err
+	}
+	if err := p.outbox.Ack(ctx, msg.ID); err != nil {
+		return err
+	}
+
+	return cause
+}
@@ -0,0 +1,261 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ln80/event-store/event"
+	event_errors "github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/eventtest"
+	"github.com/ln80/event-store/memory"
+)
+
+// recordSink is a sink that records the delivered events.
+type recordSink struct {
+	mu     sync.Mutex
+	events []event.Envelope
+	err    error
+}
+
+func (s *recordSink) Send(ctx context.Context, env event.Envelope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, env)
+	return nil
+}
+
+func TestPublisher(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	outbox := NewMemoryStore()
+	deadLetters := NewMemoryDeadLetterStore()
+
+	sink := &recordSink{}
+	pub := NewPublisher(outbox, deadLetters, map[string]Sink{
+		eventtest.Dest2: sink,
+	})
+
+	store := NewDecorator(memory.NewEventStore(), outbox)
+
+	streamID := event.NewStreamID(event.UID().String())
+
+	// only half of generated events are publishable
+	if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(10))); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 5, outbox.Len(); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	// recording the same events twice is a no-op
+	envs, err := store.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := pub.Publish(ctx, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 5, outbox.Len(); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	if err := pub.Dispatch(ctx); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 0, outbox.Len(); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if want, got := 5, len(sink.events); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	for i, env := range sink.events {
+		if want, got := envs[i*2].ID(), env.ID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
+
+func TestPublisher_RetryAndDeadLetter(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	outbox := NewMemoryStore()
+	deadLetters := NewMemoryDeadLetterStore()
+
+	errSink := errors.New("sink failure")
+	okSink := &recordSink{}
+	koSink := &recordSink{err: errSink}
+
+	pub := NewPublisher(outbox, deadLetters, map[string]Sink{
+		"ok": okSink,
+		"ko": koSink,
+	}, func(pc *PublisherConfig) {
+		pc.MaxAttempts = 3
+		pc.Backoff = func(attempt int) time.Duration { return 0 }
+	})
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, []any{eventtest.Event1{Val: "1"}})
+	envs[0].(event.RWEnvelope).SetDests([]string{"ok", "ko", "unknown"})
+
+	if err := pub.Publish(ctx, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		err := pub.Dispatch(ctx)
+		if want, got := event.ErrPublishEventFailed, err; !errors.Is(got, want) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := errSink, err; !errors.Is(got, want) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		ok, derrs := event_errors.ErrAs[DestinationErrors](err)
+		if !ok {
+			t.Fatalf("expect err be DestinationErrors, got %T", err)
+		}
+		if _, ok := derrs["ok"]; ok {
+			t.Fatal("expect destination 'ok' has no error")
+		}
+		if want, got := 1, len(derrs["ko"]); want != got {
+			t.Fatalf("expect %d, %d be equals", want, got)
+		}
+		if want, got := errSink, derrs["ko"][0]; !errors.Is(got, want) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if i == 0 {
+			if want, got := ErrSinkNotFound, derrs["unknown"][0]; !errors.Is(got, want) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+	}
+
+	// nothing is left in the outbox
+	if want, got := 0, outbox.Len(); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if err := pub.Dispatch(ctx); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	if want, got := 1, len(okSink.events); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	letters, err := deadLetters.List(ctx, "ko")
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 1, len(letters); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if want, got := 3, letters[0].Attempts; want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if want, got := errSink, letters[0].Err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := envs[0].ID(), letters[0].Event.ID(); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	letters, err = deadLetters.List(ctx, "unknown")
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 1, len(letters); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+}
+
+func TestPublisher_DestinationErrors(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	errSink := errors.New("sink failure")
+	pub := NewPublisher(NewMemoryStore(), NewMemoryDeadLetterStore(), map[string]Sink{
+		eventtest.Dest2: &recordSink{err: errSink},
+	})
+
+	streamID := event.NewStreamID(event.UID().String())
+	if err := pub.Publish(ctx, event.Wrap(ctx, streamID, eventtest.GenEvents(4))); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// all errors of the same destination are kept
+	ok, derrs := event_errors.ErrAs[DestinationErrors](pub.Dispatch(ctx))
+	if !ok {
+		t.Fatal("expect err be DestinationErrors")
+	}
+	if want, got := 2, len(derrs[eventtest.Dest2]); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if want, got := 2, len(derrs.Unwrap()); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+}
+
+func TestPublisher_Backoff(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	outbox := NewMemoryStore()
+
+	sink := &recordSink{err: errors.New("sink failure")}
+	pub := NewPublisher(outbox, NewMemoryDeadLetterStore(), map[string]Sink{
+		eventtest.Dest2: sink,
+	}, func(pc *PublisherConfig) {
+		pc.Backoff = func(attempt int) time.Duration { return time.Hour }
+	})
+
+	streamID := event.NewStreamID(event.UID().String())
+	if err := pub.Publish(ctx, event.Wrap(ctx, streamID, eventtest.GenEvents(1))); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	if want, got := event.ErrPublishEventFailed, pub.Dispatch(ctx); !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// the message is not due yet
+	sink.err = nil
+	if err := pub.Dispatch(ctx); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 1, outbox.Len(); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if want, got := 0, len(sink.events); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	tcs := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 100 * time.Millisecond},
+		{attempt: 2, want: 200 * time.Millisecond},
+		{attempt: 4, want: 800 * time.Millisecond},
+		{attempt: 100, want: time.Minute},
+	}
+	for _, tc := range tcs {
+		if want, got := tc.want, DefaultBackoff(tc.attempt); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
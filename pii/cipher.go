@@ -0,0 +1,90 @@
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/ln80/event-store/event/errors"
+)
+
+var (
+	ErrEncryptFailed = errors.New("encrypt data failed")
+	ErrDecryptFailed = errors.New("decrypt data failed")
+)
+
+// encryptedPrefix marks encrypted values, it allows to tell apart plain values
+// appended before enabling the crypto-shredding.
+const encryptedPrefix = "pii:v1:"
+
+// errKeyMismatch indicates that a value was encrypted using another key of the subject,
+// i.e. the subject was forgotten and a new key was created afterward.
+var errKeyMismatch = errors.New("subject key mismatch")
+
+func isEncrypted(str string) bool {
+	return strings.HasPrefix(str, encryptedPrefix)
+}
+
+// keyID returns a short fingerprint of the given key, embedded in encrypted values to detect key changes.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// encrypt uses AES-GCM to encrypt the given plain text, and returns a base64 encoded value
+// prefixed with the key ID, ex: "pii:v1:<key ID>:<base64>".
+func encrypt(key []byte, plain []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	b := gcm.Seal(nonce, nonce, plain, nil)
+
+	return encryptedPrefix + keyID(key) + ":" + base64.RawStdEncoding.EncodeToString(b), nil
+}
+
+// decrypt reverts encrypt. It returns errKeyMismatch if the value was encrypted using another key.
+func decrypt(key []byte, str string) ([]byte, error) {
+	kid, data, ok := strings.Cut(strings.TrimPrefix(str, encryptedPrefix), ":")
+	if !ok {
+		return nil, errors.New("invalid encrypted value")
+	}
+	if kid != keyID(key) {
+		return nil, errKeyMismatch
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < gcm.NonceSize() {
+		return nil, errors.New("invalid encrypted value")
+	}
+
+	nonce, b := b[:gcm.NonceSize()], b[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, b, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
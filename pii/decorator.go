@@ -0,0 +1,296 @@
+package pii
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	es "github.com/ln80/event-store"
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/event/sourcing"
+)
+
+var (
+	ErrSubjectIDNotFound = errors.New("subject ID not found")
+	ErrReservedPrefix    = errors.New("personal data starts with the reserved encryption prefix")
+)
+
+// Decorator implements crypto-shredding on top of an event store.
+//
+// Personal data fields are encrypted on append using their subject key, and decrypted on load, replay and query.
+// Once a subject is forgotten, its key is deleted, and its personal data fields are redacted on read,
+// while the rest of the events remains readable. Encrypted values embed the ID of their key, thus data encrypted
+// before forgetting the subject remain redacted even if a new key is created afterward.
+// Only string and []byte fields are supported as personal data. Values starting with the reserved encryption prefix
+// are rejected on append, as they can't be told apart from encrypted ones.
+type Decorator struct {
+	keys KeyStore
+	es.EventStore
+}
+
+// NewDecorator returns an event store decorator that encrypts personal data using the given key store.
+func NewDecorator(store es.EventStore, keys KeyStore) *Decorator {
+	return &Decorator{
+		keys:       keys,
+		EventStore: store,
+	}
+}
+
+// Forget deletes the subject key, which makes its personal data unreadable.
+func (d *Decorator) Forget(ctx context.Context, subjectID string) error {
+	return d.keys.DeleteKey(ctx, subjectID)
+}
+
+func (d *Decorator) Append(ctx context.Context, id event.StreamID, events []event.Envelope, optFns ...func(*event.AppendConfig)) error {
+	envs, err := d.encrypt(ctx, events)
+	if err != nil {
+		return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+	}
+
+	return d.EventStore.Append(ctx, id, envs, optFns...)
+}
+
+func (d *Decorator) AppendToStream(ctx context.Context, chunk sourcing.Stream, optFns ...func(opt *event.AppendConfig)) error {
+	envs, err := d.encrypt(ctx, chunk.Unwrap())
+	if err != nil {
+		return errors.Err(event.ErrAppendEventsFailed, chunk.ID().String(), err)
+	}
+
+	return d.EventStore.AppendToStream(ctx, *sourcing.NewStream(chunk.ID(), envs), optFns...)
+}
+
+func (d *Decorator) Load(ctx context.Context, id event.StreamID, trange ...time.Time) ([]event.Envelope, error) {
+	envs, err := d.EventStore.Load(ctx, id, trange...)
+	if err != nil {
+		return nil, err
+	}
+
+	envs, err = d.decrypt(ctx, envs)
+	if err != nil {
+		return nil, errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+
+	return envs, nil
+}
+
+func (d *Decorator) LoadStream(ctx context.Context, id event.StreamID, vrange ...event.Version) (*sourcing.Stream, error) {
+	stm, err := d.EventStore.LoadStream(ctx, id, vrange...)
+	if err != nil {
+		return nil, err
+	}
+
+	envs, err := d.decrypt(ctx, stm.Unwrap())
+	if err != nil {
+		return nil, errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+
+	return sourcing.NewStream(id, envs), nil
+}
+
+func (d *Decorator) Replay(ctx context.Context, id event.StreamID, q event.StreamReplayQuery, h event.StreamReplayHandler) error {
+	return d.EventStore.Replay(ctx, id, q, func(ctx context.Context, data event.StreamData) error {
+		if env, ok := data.Value.(event.Envelope); ok && data.Type == event.StreamDataTypeRecord {
+			envs, err := d.decrypt(ctx, []event.Envelope{env})
+			if err != nil {
+				return errors.Err(event.ErrLoadEventFailed, id.String(), err)
+			}
+			data.Value = envs[0]
+		}
+		return h(ctx, data)
+	})
+}
+
+func (d *Decorator) Query(ctx context.Context, id event.StreamID, q event.StreamQuery) (*event.StreamQueryResult, error) {
+	result, err := d.EventStore.Query(ctx, id, q)
+	if err != nil {
+		return nil, err
+	}
+
+	envs, err := d.decrypt(ctx, result.Events)
+	if err != nil {
+		return nil, errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+	result.Events = envs
+
+	return result, nil
+}
+
+// encrypt returns a copy of the given envelopes where personal data fields are encrypted.
+func (d *Decorator) encrypt(ctx context.Context, envs []event.Envelope) (event.Stream, error) {
+	keys := make(map[string][]byte)
+
+	fn := func(subjectID string, f reflect.Value, t tag) error {
+		plain, ok := fieldBytes(f)
+		if !ok || len(plain) == 0 {
+			return nil
+		}
+		if isEncrypted(string(plain)) {
+			return errors.Err(ErrReservedPrefix, "", encryptedPrefix)
+		}
+		if subjectID == "" {
+			return ErrSubjectIDNotFound
+		}
+
+		key, ok := keys[subjectID]
+		if !ok {
+			var err error
+			key, err = d.keys.GetOrCreateKey(ctx, subjectID)
+			if err != nil {
+				return err
+			}
+			keys[subjectID] = key
+		}
+
+		str, err := encrypt(key, plain)
+		if err != nil {
+			return errors.Err(ErrEncryptFailed, "", err)
+		}
+		setField(f, str)
+
+		return nil
+	}
+
+	return transformEnvelopes(envs, fn)
+}
+
+// decrypt returns a copy of the given envelopes where personal data fields are decrypted,
+// or redacted if the subject is forgotten.
+func (d *Decorator) decrypt(ctx context.Context, envs []event.Envelope) (event.Stream, error) {
+	keys := make(map[string][]byte)
+
+	fn := func(subjectID string, f reflect.Value, t tag) error {
+		b, ok := fieldBytes(f)
+		if !ok || !isEncrypted(string(b)) {
+			return nil
+		}
+
+		key, ok := keys[subjectID]
+		if !ok {
+			var err error
+			key, err = d.keys.GetKey(ctx, subjectID)
+			if err != nil && !errors.ErrIs(err, ErrKeyNotFound) {
+				return err
+			}
+			keys[subjectID] = key
+		}
+		// the subject is forgotten
+		if key == nil {
+			setField(f, t.replace)
+			return nil
+		}
+
+		plain, err := decrypt(key, string(b))
+		// the subject was forgotten, then got a new key
+		if errors.ErrIs(err, errKeyMismatch) {
+			setField(f, t.replace)
+			return nil
+		}
+		if err != nil {
+			return errors.Err(ErrDecryptFailed, "", err)
+		}
+		setField(f, string(plain))
+
+		return nil
+	}
+
+	return transformEnvelopes(envs, fn)
+}
+
+func transformEnvelopes(envs []event.Envelope, fn fieldFunc) (event.Stream, error) {
+	result := make(event.Stream, len(envs))
+	for i, env := range envs {
+		// avoid nesting envelopes
+		base := env
+		if w, ok := env.(*envelope); ok {
+			base = w.Envelope
+		}
+
+		evt, ok, err := transform(env.Event(), fn)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			result[i] = env
+			continue
+		}
+		result[i] = &envelope{Envelope: base, evt: evt}
+	}
+
+	return result, nil
+}
+
+func fieldBytes(f reflect.Value) ([]byte, bool) {
+	switch {
+	case f.Kind() == reflect.String:
+		return []byte(f.String()), true
+	case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Uint8:
+		return f.Bytes(), true
+	}
+	return nil, false
+}
+
+func setField(f reflect.Value, str string) {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(str)
+	case reflect.Slice:
+		if str == "" {
+			f.SetBytes(nil)
+			return
+		}
+		f.SetBytes([]byte(str))
+	}
+}
+
+// envelope overrides the event of the wrapped envelope.
+type envelope struct {
+	event.Envelope
+	evt any
+}
+
+var _ event.GlobalVersionSetter = &envelope{}
+var _ event.TraceIDSetter = &envelope{}
+
+// Event implements event.Envelope interface.
+func (e *envelope) Event() any {
+	return e.evt
+}
+
+// SetGlobalVersion implements event.GlobalVersionSetter interface.
+// It sets the global version of the wrapped envelope.
+func (e *envelope) SetGlobalVersion(v event.Version) event.Envelope {
+	event.MustGlobalVersionSetter(e.Envelope).SetGlobalVersion(v)
+	return e
+}
+
+// SetTraceID implements event.TraceIDSetter interface.
+// It sets the trace ID of the wrapped envelope if supported.
+func (e *envelope) SetTraceID(id string) event.Envelope {
+	if env, ok := e.Envelope.(event.TraceIDSetter); ok {
+		env.SetTraceID(id)
+	}
+	return e
+}
+
+// SetVersion sets the version of the wrapped envelope if supported.
+// It allows stores to rebase the chunk versions in the case of an expected version append option.
+func (e *envelope) SetVersion(v event.Version) event.Envelope {
+	if env, ok := e.Envelope.(interface {
+		SetVersion(v event.Version) event.Envelope
+	}); ok {
+		env.SetVersion(v)
+	}
+	return e
+}
+
+// SetMetadata sets the metadata of the wrapped envelope if supported.
+// It allows other decorators and stores to attach metadata, ex: signatures and chain hashes.
+func (e *envelope) SetMetadata(md map[string]string) event.Envelope {
+	if env, ok := e.Envelope.(interface {
+		SetMetadata(md map[string]string) event.Envelope
+	}); ok {
+		env.SetMetadata(md)
+	}
+	return e
+}
@@ -0,0 +1,261 @@
+package pii
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/eventtest"
+	"github.com/ln80/event-store/file"
+	"github.com/ln80/event-store/json"
+	"github.com/ln80/event-store/memory"
+)
+
+type Address struct {
+	Street string `pii:"data"`
+	City   string
+}
+
+type User struct {
+	ID       string   `pii:"subjectID"`
+	Email    string   `pii:"data"`
+	BirthDay string   `pii:"data,replace=00-00"`
+	Address  *Address `pii:"dive"`
+	Avatar   []byte   `pii:"data"`
+}
+
+type UserRegistered struct {
+	User    User `pii:"dive"`
+	Referer User `pii:"dive"`
+	Plan    string
+}
+
+func TestDecorator(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	eventtest.TestEventLoggingStore(t, ctx, NewDecorator(memory.NewEventStore(), NewMemoryKeyStore()))
+	eventtest.TestEventSourcingStore(t, ctx, NewDecorator(memory.NewEventStore(), NewMemoryKeyStore()))
+	eventtest.TestEventStreamReplayer(t, ctx, NewDecorator(memory.NewEventStore(), NewMemoryKeyStore()), func(opt *eventtest.TestEventStreamReplayerOptions) {
+		opt.SupportOrderDESC = true
+	})
+	eventtest.TestEventStreamQuerier(t, ctx, NewDecorator(memory.NewEventStore(), NewMemoryKeyStore()), func(opt *eventtest.TestEventStreamQuerierOptions) {
+		opt.SupportOrderDESC = true
+	})
+}
+
+func TestDecorator_Forget(t *testing.T) {
+	ctx := context.Background()
+
+	event.NewRegister("").Set(UserRegistered{})
+
+	store := memory.NewEventStore()
+	d := NewDecorator(store, NewMemoryKeyStore())
+
+	streamID := event.NewStreamID(event.UID().String())
+
+	evt := &UserRegistered{
+		User: User{
+			ID:       "user-1",
+			Email:    "user-1@example.com",
+			BirthDay: "01-01",
+			Address:  &Address{Street: "1 main street", City: "Paris"},
+			Avatar:   []byte("avatar"),
+		},
+		Referer: User{
+			ID:    "user-2",
+			Email: "user-2@example.com",
+		},
+		Plan: "premium",
+	}
+	envs := event.Wrap(ctx, streamID, []any{evt})
+	if err := d.Append(ctx, streamID, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// the appended event must be left untouched
+	if want, got := "user-1@example.com", evt.User.Email; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := "1 main street", evt.User.Address.Street; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// personal data are encrypted at rest
+	renvs, err := store.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	stored := renvs[0].Event().(*UserRegistered)
+	for _, str := range []string{stored.User.Email, stored.User.BirthDay, stored.User.Address.Street, stored.Referer.Email, string(stored.User.Avatar)} {
+		if !isEncrypted(str) {
+			t.Fatalf("expect %s be encrypted", str)
+		}
+	}
+	if want, got := "Paris", stored.User.Address.City; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// personal data are decrypted on load
+	renvs, err = d.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if !eventtest.CmpEnv(envs[0], renvs[0]) {
+		t.Fatalf("event data altered %v %v", eventtest.FormatEnv(envs[0]), eventtest.FormatEnv(renvs[0]))
+	}
+
+	if err := d.Forget(ctx, "user-1"); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// forgotten subject data are redacted while the rest of the event remains readable
+	renvs, err = d.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	want := &UserRegistered{
+		User: User{
+			ID:       "user-1",
+			Email:    "",
+			BirthDay: "00-00",
+			Address:  &Address{Street: "", City: "Paris"},
+			Avatar:   nil,
+		},
+		Referer: User{
+			ID:    "user-2",
+			Email: "user-2@example.com",
+		},
+		Plan: "premium",
+	}
+	if got := renvs[0].Event(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %+v, %+v be equals", want, got)
+	}
+
+	// replay is shredding-aware as well
+	err = d.Replay(ctx, streamID, event.StreamReplayQuery{}, func(ctx context.Context, data event.StreamData) error {
+		evt := data.Value.(event.Envelope).Event().(*UserRegistered)
+		if want, got := "00-00", evt.User.BirthDay; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := "user-2@example.com", evt.Referer.Email; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// a new key is created for the forgotten subject, while its former data remain redacted
+	evt2 := &UserRegistered{User: User{ID: "user-1", Email: "new-user-1@example.com"}, Plan: "free"}
+	if err := d.Append(ctx, streamID, event.Wrap(ctx, streamID, []any{evt2})); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	renvs, err = d.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 2, len(renvs); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if got := renvs[0].Event(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %+v, %+v be equals", want, got)
+	}
+	if got := renvs[1].Event(); !reflect.DeepEqual(evt2, got) {
+		t.Fatalf("expect %+v, %+v be equals", evt2, got)
+	}
+}
+
+func TestDecorator_WithReservedPrefix(t *testing.T) {
+	ctx := context.Background()
+
+	event.NewRegister("").Set(UserRegistered{})
+
+	d := NewDecorator(memory.NewEventStore(), NewMemoryKeyStore())
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, []any{&UserRegistered{User: User{ID: "user-1", Email: encryptedPrefix + "x:y"}}})
+
+	err := d.Append(ctx, streamID, envs)
+	if want, got := event.ErrAppendEventsFailed, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := ErrReservedPrefix, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestDecorator_WithMissingSubject(t *testing.T) {
+	ctx := context.Background()
+
+	d := NewDecorator(memory.NewEventStore(), NewMemoryKeyStore())
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, []any{&UserRegistered{User: User{Email: "anonymous@example.com"}}})
+
+	err := d.Append(ctx, streamID, envs)
+	if want, got := event.ErrAppendEventsFailed, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := ErrSubjectIDNotFound, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestDecorator_AtRest(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	store, err := file.NewEventStore(ctx, dir, json.NewEventSerializer(""))
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	defer store.Close()
+
+	d := NewDecorator(store, NewMemoryKeyStore())
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, eventtest.GenEvents(2))
+	if err := d.Append(ctx, streamID, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// the plain personal data must not be found on disk
+	plain := envs[0].Event().(*eventtest.Event2).LongText
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	for _, entry := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if bytes.Contains(b, []byte(plain)) {
+			t.Fatalf("expect personal data be encrypted in %s", entry.Name())
+		}
+		if !strings.Contains(string(b), encryptedPrefix) {
+			t.Fatalf("expect encrypted data be found in %s", entry.Name())
+		}
+	}
+
+	renvs, err := d.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	for i, env := range envs {
+		if !eventtest.CmpEnv(env, renvs[i]) {
+			t.Fatalf("event %d data altered %v %v", i, eventtest.FormatEnv(env), eventtest.FormatEnv(renvs[i]))
+		}
+	}
+}
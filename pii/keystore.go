@@ -0,0 +1,82 @@
+package pii
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+
+	"github.com/ln80/event-store/event/errors"
+)
+
+var (
+	ErrKeyNotFound = errors.New("subject key not found")
+)
+
+// KeySize is the size in bytes of the generated data keys (AES-256).
+const KeySize = 32
+
+// KeyStore manages the encryption keys of data subjects.
+type KeyStore interface {
+	// GetOrCreateKey returns the subject key, it creates a new one if not found.
+	GetOrCreateKey(ctx context.Context, subjectID string) ([]byte, error)
+	// GetKey returns the subject key, or ErrKeyNotFound if not found or already deleted.
+	GetKey(ctx context.Context, subjectID string) ([]byte, error)
+	// DeleteKey deletes the subject key. Deleting a missing key is a no-op.
+	DeleteKey(ctx context.Context, subjectID string) error
+}
+
+// MemoryKeyStore implements KeyStore in memory, mainly used for testing purposes.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string][]byte
+}
+
+var _ KeyStore = &MemoryKeyStore{}
+
+// NewMemoryKeyStore returns an in-memory key store.
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		keys: make(map[string][]byte),
+	}
+}
+
+// GetOrCreateKey implements KeyStore interface.
+func (s *MemoryKeyStore) GetOrCreateKey(ctx context.Context, subjectID string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.keys[subjectID]; ok {
+		return key, nil
+	}
+
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	s.keys[subjectID] = key
+
+	return key, nil
+}
+
+// GetKey implements KeyStore interface.
+func (s *MemoryKeyStore) GetKey(ctx context.Context, subjectID string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[subjectID]
+	if !ok {
+		return nil, errors.Err(ErrKeyNotFound, "", subjectID)
+	}
+
+	return key, nil
+}
+
+// DeleteKey implements KeyStore interface.
+func (s *MemoryKeyStore) DeleteKey(ctx context.Context, subjectID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, subjectID)
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package pii
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	sensitive "github.com/ln80/struct-sensitive"
+)
+
+// Personal data fields are defined using the struct-sensitive tags, the same ones used by the AVRO schema.
+//
+// The supported tag names are:
+// - subjectID: the field value identifies the data subject (aka the encryption key) of the struct;
+// - data: the field contains personal data, it's encrypted using the subject key. The 'replace' option
+// defines the value used once the subject is forgotten, ex: `pii:"data,replace=00-00"`;
+// - dive: the field is a nested struct (or a pointer/slice of) which contains personal data.
+// A nested struct uses the subject of its parent unless it defines its own one.
+const (
+	tagSubjectID = "subjectID"
+	tagData      = "data"
+	tagDive      = "dive"
+)
+
+type tag struct {
+	kind    string
+	replace string
+}
+
+func parseTag(st reflect.StructTag) (tag, bool) {
+	p := sensitive.ParseTag(st)
+	if p == nil {
+		return tag{}, false
+	}
+
+	t := tag{kind: string(p.Name)}
+	if v, ok := p.Options["replace"]; ok {
+		t.replace = fmt.Sprint(v)
+	}
+
+	return t, true
+}
+
+var sensitiveTypes sync.Map // map[reflect.Type]bool
+
+// hasPersonalData returns true if the given type contains personal data fields.
+func hasPersonalData(t reflect.Type) bool {
+	if v, ok := sensitiveTypes.Load(t); ok {
+		return v.(bool)
+	}
+
+	ok := lookupPersonalData(t, map[reflect.Type]bool{})
+	sensitiveTypes.Store(t, ok)
+
+	return ok
+}
+
+func lookupPersonalData(t reflect.Type, visited map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tg, ok := parseTag(f.Tag)
+		if !ok {
+			continue
+		}
+		switch tg.kind {
+		case tagData:
+			return true
+		case tagDive:
+			if lookupPersonalData(f.Type, visited) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// fieldFunc processes a personal data field of the given subject.
+type fieldFunc func(subjectID string, f reflect.Value, t tag) error
+
+// transform returns a copy of the given event where the personal data fields are processed using fn.
+// The given event is left untouched. The event is returned as is if it does not contain personal data,
+// in such a case, the returned flag is false.
+func transform(evt any, fn fieldFunc) (any, bool, error) {
+	if evt == nil || !hasPersonalData(reflect.TypeOf(evt)) {
+		return evt, false, nil
+	}
+
+	v := reflect.ValueOf(evt)
+	switch {
+	case v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() == reflect.Struct:
+		cp := reflect.New(v.Elem().Type())
+		cp.Elem().Set(v.Elem())
+		if err := walk(cp.Elem(), "", fn); err != nil {
+			return nil, false, err
+		}
+		return cp.Interface(), true, nil
+
+	case v.Kind() == reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		if err := walk(cp, "", fn); err != nil {
+			return nil, false, err
+		}
+		return cp.Interface(), true, nil
+	}
+
+	return evt, false, nil
+}
+
+// walk processes the fields of the given addressable struct value.
+func walk(v reflect.Value, subjectID string, fn fieldFunc) error {
+	t := v.Type()
+
+	// resolve the struct subject first
+	for i := 0; i < t.NumField(); i++ {
+		if tg, ok := parseTag(t.Field(i).Tag); ok && tg.kind == tagSubjectID {
+			if f := v.Field(i); f.Kind() == reflect.String && f.String() != "" {
+				subjectID = f.String()
+			}
+			break
+		}
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		tg, ok := parseTag(t.Field(i).Tag)
+		if !ok {
+			continue
+		}
+		f := v.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+		switch tg.kind {
+		case tagData:
+			if err := fn(subjectID, f, tg); err != nil {
+				return err
+			}
+		case tagDive:
+			if err := dive(f, subjectID, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// dive copies the nested value before processing it, to make sure the original event is left untouched.
+func dive(f reflect.Value, subjectID string, fn fieldFunc) error {
+	switch f.Kind() {
+	case reflect.Struct:
+		return walk(f, subjectID, fn)
+
+	case reflect.Pointer:
+		if f.IsNil() {
+			return nil
+		}
+		cp := reflect.New(f.Type().Elem())
+		cp.Elem().Set(f.Elem())
+		if err := dive(cp.Elem(), subjectID, fn); err != nil {
+			return err
+		}
+		f.Set(cp)
+
+	case reflect.Slice:
+		if f.IsNil() {
+			return nil
+		}
+		cp := reflect.MakeSlice(f.Type(), f.Len(), f.Len())
+		reflect.Copy(cp, f)
+		for i := 0; i < cp.Len(); i++ {
+			if err := dive(cp.Index(i), subjectID, fn); err != nil {
+				return err
+			}
+		}
+		f.Set(cp)
+
+	case reflect.Array:
+		for i := 0; i < f.Len(); i++ {
+			if err := dive(f.Index(i), subjectID, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package projection
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
+)
+
+var (
+	ErrLoadCheckpointFailed  = errors.New("load checkpoint failed")
+	ErrSaveCheckpointFailed  = errors.New("save checkpoint failed")
+	ErrResetCheckpointFailed = errors.New("reset checkpoint failed")
+)
+
+// CheckpointStore persists the global version of the last processed event per projection.
+type CheckpointStore interface {
+	// Load returns the projection checkpoint, or a zero version if the projection has never run.
+	Load(ctx context.Context, projection string) (event.Version, error)
+	// Save stores the projection checkpoint.
+	Save(ctx context.Context, projection string, ver event.Version) error
+	// Reset removes the projection checkpoint.
+	Reset(ctx context.Context, projection string) error
+}
+
+// MemoryCheckpointStore implements CheckpointStore in memory, mainly used for testing purposes.
+type MemoryCheckpointStore struct {
+	mu          sync.RWMutex
+	checkpoints map[string]event.Version
+}
+
+var _ CheckpointStore = &MemoryCheckpointStore{}
+
+// NewMemoryCheckpointStore returns an in-memory checkpoint store.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{
+		checkpoints: make(map[string]event.Version),
+	}
+}
+
+// Load implements CheckpointStore interface.
+func (s *MemoryCheckpointStore) Load(ctx context.Context, projection string) (event.Version, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.checkpoints[projection], nil
+}
+
+// Save implements CheckpointStore interface.
+func (s *MemoryCheckpointStore) Save(ctx context.Context, projection string, ver event.Version) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints[projection] = ver
+
+	return nil
+}
+
+// Reset implements CheckpointStore interface.
+func (s *MemoryCheckpointStore) Reset(ctx context.Context, projection string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.checkpoints, projection)
+
+	return nil
+}
+
+// FileCheckpointStore implements CheckpointStore on top of local disk.
+// Each checkpoint is saved in a dedicated file, and is atomically replaced on save.
+type FileCheckpointStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+var _ CheckpointStore = &FileCheckpointStore{}
+
+// NewFileCheckpointStore returns a file-based checkpoint store that persists checkpoints in the given directory.
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &FileCheckpointStore{dir: dir}, nil
+}
+
+func (s *FileCheckpointStore) path(projection string) string {
+	return filepath.Join(s.dir, url.PathEscape(projection)+".checkpoint")
+}
+
+// Load implements CheckpointStore interface.
+func (s *FileCheckpointStore) Load(ctx context.Context, projection string) (event.Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path(projection))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return event.VersionZero, nil
+		}
+		return event.VersionZero, errors.Err(ErrLoadCheckpointFailed, "", err)
+	}
+
+	ver, err := event.ParseVersion(strings.TrimSpace(string(b)))
+	if err != nil {
+		return event.VersionZero, errors.Err(ErrLoadCheckpointFailed, "", err)
+	}
+
+	return ver, nil
+}
+
+// Save implements CheckpointStore interface.
+func (s *FileCheckpointStore) Save(ctx context.Context, projection string, ver event.Version) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(projection)
+
+	// write to a temporary file then rename it to avoid partially written checkpoints
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Err(ErrSaveCheckpointFailed, "", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(ver.String()); err != nil {
+		_ = tmp.Close()
+		return errors.Err(ErrSaveCheckpointFailed, "", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return errors.Err(ErrSaveCheckpointFailed, "", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Err(ErrSaveCheckpointFailed, "", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Err(ErrSaveCheckpointFailed, "", err)
+	}
+
+	return nil
+}
+
+// Reset implements CheckpointStore interface.
+func (s *FileCheckpointStore) Reset(ctx context.Context, projection string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(projection)); err != nil && !os.IsNotExist(err) {
+		return errors.Err(ErrResetCheckpointFailed, "", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package projection
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+)
+
+func TestCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+
+	fstore, err := NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	tcs := []struct {
+		name  string
+		store CheckpointStore
+	}{
+		{name: "memory", store: NewMemoryCheckpointStore()},
+		{name: "file", store: fstore},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			name := "projection/" + event.UID().String()
+
+			ver, err := tc.store.Load(ctx, name)
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if !ver.IsZero() {
+				t.Fatalf("expect checkpoint be zero, got %v", ver)
+			}
+
+			for _, want := range []event.Version{
+				event.VersionMin.EOF(),
+				event.VersionMin.Add(10, 2).EOF(),
+			} {
+				if err := tc.store.Save(ctx, name, want); err != nil {
+					t.Fatal("expect err be nil, got", err)
+				}
+				got, err := tc.store.Load(ctx, name)
+				if err != nil {
+					t.Fatal("expect err be nil, got", err)
+				}
+				if want.String() != got.String() {
+					t.Fatalf("expect %v, %v be equals", want, got)
+				}
+			}
+
+			if err := tc.store.Reset(ctx, name); err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			// reset must be idempotent
+			if err := tc.store.Reset(ctx, name); err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+
+			ver, err = tc.store.Load(ctx, name)
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if !ver.IsZero() {
+				t.Fatalf("expect checkpoint be zero, got %v", ver)
+			}
+		})
+	}
+}
+
+func TestFileCheckpointStore_Reopen(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+
+	store, err := NewFileCheckpointStore(dir)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	want := event.VersionMin.Add(5, 0).EOF()
+	if err := store.Save(ctx, "projection", want); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	store, err = NewFileCheckpointStore(dir)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	got, err := store.Load(ctx, "projection")
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want.String() != got.String() {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
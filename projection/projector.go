@@ -0,0 +1,212 @@
+package projection
+
+import (
+	"context"
+	"time"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/logger"
+)
+
+var (
+	ErrProjectionFailed = errors.New("projection failed")
+)
+
+const (
+	DefaultRecordLimit  uint          = 100
+	DefaultPollInterval time.Duration = time.Second
+)
+
+// Handler processes the events of a single record (aka chunk of events sharing the same
+// integer part of the global version) in order to maintain a read model.
+//
+// The record checkpoint is saved once the handler succeeds. Therefore, a record might be
+// processed more than once in the case of failure, and the handler must be idempotent.
+type Handler func(ctx context.Context, events []event.Envelope) error
+
+// ProjectorConfig presents the projector configuration.
+type ProjectorConfig struct {
+	// RecordLimit defines the max count of records replayed in a single call.
+	RecordLimit uint
+	// PollInterval defines the duration to wait for new events once the projection has caught up.
+	PollInterval time.Duration
+	// OnRebuild, if defined, is called before rebuilding the projection from zero.
+	// It mainly allows to clear the read model state.
+	OnRebuild func(ctx context.Context) error
+}
+
+// Projector maintains a projection by replaying a stream, and tracking the last processed
+// global version in a CheckpointStore. It resumes from the saved checkpoint after restart.
+//
+// Note that the stream global version is used as checkpoint. Therefore, the projection stream
+// is usually a global stream.
+type Projector struct {
+	name        string
+	streamID    event.StreamID
+	replayer    event.StreamReplayer
+	checkpoints CheckpointStore
+	handler     Handler
+
+	cfg *ProjectorConfig
+}
+
+// NewProjector returns a projector identified by the given name, which processes the given stream events.
+func NewProjector(name string, streamID event.StreamID, replayer event.StreamReplayer, checkpoints CheckpointStore, h Handler, opts ...func(*ProjectorConfig)) *Projector {
+	cfg := &ProjectorConfig{
+		RecordLimit:  DefaultRecordLimit,
+		PollInterval: DefaultPollInterval,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	if cfg.RecordLimit == 0 {
+		cfg.RecordLimit = DefaultRecordLimit
+	}
+
+	return &Projector{
+		name:        name,
+		streamID:    streamID,
+		replayer:    replayer,
+		checkpoints: checkpoints,
+		handler:     h,
+		cfg:         cfg,
+	}
+}
+
+// Name returns the projection name.
+func (p *Projector) Name() string {
+	return p.name
+}
+
+// Checkpoint returns the global version of the last processed event.
+func (p *Projector) Checkpoint(ctx context.Context) (event.Version, error) {
+	return p.checkpoints.Load(ctx, p.name)
+}
+
+// Run keeps the projection up to date until the context is canceled.
+// It returns a nil error once the context is canceled.
+func (p *Projector) Run(ctx context.Context) error {
+	for {
+		if err := p.CatchUp(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(p.cfg.PollInterval):
+		}
+	}
+}
+
+// CatchUp processes the stream events from the last checkpoint to the current end of the stream.
+func (p *Projector) CatchUp(ctx context.Context) error {
+	ver, err := p.checkpoints.Load(ctx, p.name)
+	if err != nil {
+		return errors.Err(ErrProjectionFailed, p.streamID.String(), err)
+	}
+
+	for {
+		from := event.VersionMin
+		if !ver.IsZero() {
+			from = ver.Trunc().Add(1, 0)
+		}
+
+		count, last, err := p.replay(ctx, from)
+		if err != nil {
+			return errors.Err(ErrProjectionFailed, p.streamID.String(), err)
+		}
+
+		if count > 0 {
+			logger.FromContext(ctx).WithName("projection").V(1).Info("Projection records processed",
+				"projection", p.name,
+				"count", count,
+				"checkpoint", last.String())
+
+			ver = last
+		}
+
+		if count < p.cfg.RecordLimit {
+			return nil
+		}
+	}
+}
+
+// Rebuild resets the projection checkpoint and processes the stream events from zero.
+func (p *Projector) Rebuild(ctx context.Context) error {
+	if p.cfg.OnRebuild != nil {
+		if err := p.cfg.OnRebuild(ctx); err != nil {
+			return errors.Err(ErrProjectionFailed, p.streamID.String(), err)
+		}
+	}
+
+	if err := p.checkpoints.Reset(ctx, p.name); err != nil {
+		return errors.Err(ErrProjectionFailed, p.streamID.String(), err)
+	}
+
+	return p.CatchUp(ctx)
+}
+
+// replay processes a page of records starting from the given version.
+// It returns the count of processed records and the global version of the last processed event.
+func (p *Projector) replay(ctx context.Context, from event.Version) (uint, event.Version, error) {
+	var (
+		count uint
+		last  = event.VersionZero
+		batch = make([]event.Envelope, 0)
+	)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := p.handler(ctx, batch); err != nil {
+			return err
+		}
+		ver := batch[len(batch)-1].GlobalVersion()
+		if err := p.checkpoints.Save(ctx, p.name, ver); err != nil {
+			return err
+		}
+		count++
+		last = ver
+		batch = make([]event.Envelope, 0)
+		return nil
+	}
+
+	err := p.replayer.Replay(ctx, p.streamID, event.StreamReplayQuery{
+		From:        from,
+		RecordLimit: p.cfg.RecordLimit,
+		Order:       event.StreamOrderASC,
+	}, func(ctx context.Context, data event.StreamData) error {
+		if data.Type != event.StreamDataTypeRecord {
+			return nil
+		}
+		env, ok := data.Value.(event.Envelope)
+		if !ok {
+			return nil
+		}
+		if len(batch) > 0 && !env.GlobalVersion().Trunc().Equal(batch[0].GlobalVersion().Trunc()) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		batch = append(batch, env)
+		return nil
+	})
+	if err != nil {
+		return count, last, err
+	}
+
+	if err := flush(); err != nil {
+		return count, last, err
+	}
+
+	return count, last, nil
+}
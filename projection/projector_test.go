@@ -0,0 +1,240 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/eventtest"
+	"github.com/ln80/event-store/memory"
+)
+
+// recorder is a read model that records the processed batches.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]event.Envelope
+}
+
+func (r *recorder) handle(ctx context.Context, events []event.Envelope) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batches = append(r.batches, events)
+	return nil
+}
+
+func (r *recorder) count() (records, events int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, b := range r.batches {
+		events += len(b)
+	}
+	return len(r.batches), events
+}
+
+func appendRecords(t *testing.T, ctx context.Context, store event.Store, streamID event.StreamID, sizes ...int) {
+	t.Helper()
+
+	for _, size := range sizes {
+		if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(size))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	}
+}
+
+func TestProjector(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store := memory.NewEventStore()
+	streamID := event.NewStreamID(event.UID().String())
+
+	appendRecords(t, ctx, store, streamID, 3, 1, 2, 5, 1)
+
+	dir := t.TempDir()
+	checkpoints, err := NewFileCheckpointStore(dir)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	r := &recorder{}
+	// use a small record limit to force paging
+	opt := func(pc *ProjectorConfig) {
+		pc.RecordLimit = 2
+	}
+	p := NewProjector("read-model", streamID, store, checkpoints, r.handle, opt)
+
+	if err := p.CatchUp(ctx); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// handler is called once per record
+	if records, events := r.count(); records != 5 || events != 12 {
+		t.Fatalf("expect records and events count be %d, %d, got %d, %d", 5, 12, records, events)
+	}
+	for i, batch := range r.batches {
+		for _, env := range batch {
+			if want, got := event.VersionMin.Add(uint64(i), 0), env.GlobalVersion().Trunc(); !want.Equal(got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+		if !batch[len(batch)-1].GlobalVersion().EOF().Equal(batch[len(batch)-1].GlobalVersion()) {
+			t.Fatalf("expect batch be a complete record")
+		}
+	}
+
+	ver, err := p.Checkpoint(ctx)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := event.VersionMin.Add(4, 0), ver; !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// catching up again is a no-op
+	if err := p.CatchUp(ctx); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 5, len(r.batches); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	t.Run("resume after restart", func(t *testing.T) {
+		appendRecords(t, ctx, store, streamID, 2, 2)
+
+		checkpoints, err := NewFileCheckpointStore(dir)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		r := &recorder{}
+		p := NewProjector("read-model", streamID, store, checkpoints, r.handle, opt)
+
+		if err := p.CatchUp(ctx); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if records, events := r.count(); records != 2 || events != 4 {
+			t.Fatalf("expect records and events count be %d, %d, got %d, %d", 2, 4, records, events)
+		}
+		if want, got := event.VersionMin.Add(5, 0), r.batches[0][0].GlobalVersion(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("rebuild from zero", func(t *testing.T) {
+		r := &recorder{}
+		rebuilt := false
+		p := NewProjector("read-model", streamID, store, checkpoints, r.handle, opt, func(pc *ProjectorConfig) {
+			pc.OnRebuild = func(ctx context.Context) error {
+				rebuilt = true
+				return nil
+			}
+		})
+
+		if err := p.Rebuild(ctx); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if !rebuilt {
+			t.Fatal("expect OnRebuild be called")
+		}
+		if records, events := r.count(); records != 7 || events != 16 {
+			t.Fatalf("expect records and events count be %d, %d, got %d, %d", 7, 16, records, events)
+		}
+	})
+}
+
+func TestProjector_WithHandlerFailure(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store := memory.NewEventStore()
+	streamID := event.NewStreamID(event.UID().String())
+
+	appendRecords(t, ctx, store, streamID, 1, 1, 1)
+
+	checkpoints := NewMemoryCheckpointStore()
+
+	errFailure := errors.New("handler failure")
+	calls := 0
+	p := NewProjector("read-model", streamID, store, checkpoints, func(ctx context.Context, events []event.Envelope) error {
+		calls++
+		if calls == 2 {
+			return errFailure
+		}
+		return nil
+	})
+
+	err := p.CatchUp(ctx)
+	if want, got := ErrProjectionFailed, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := errFailure, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// the checkpoint points to the last successfully processed record
+	ver, err := p.Checkpoint(ctx)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := event.VersionMin, ver; !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// retry resumes from the failed record
+	if err := p.CatchUp(ctx); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 4, calls; want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+}
+
+func TestProjector_Run(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := memory.NewEventStore()
+	streamID := event.NewStreamID(event.UID().String())
+
+	appendRecords(t, ctx, store, streamID, 2)
+
+	r := &recorder{}
+	p := NewProjector("read-model", streamID, store, NewMemoryCheckpointStore(), r.handle, func(pc *ProjectorConfig) {
+		pc.PollInterval = 5 * time.Millisecond
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Run(ctx)
+	}()
+
+	appendRecords(t, ctx, store, streamID, 1, 3)
+
+	deadline := time.After(5 * time.Second)
+	for {
+		if records, _ := r.count(); records == 3 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("expect projection be up to date")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if _, events := r.count(); events != 6 {
+		t.Fatalf("expect %d, %d be equals", 6, events)
+	}
+}
@@ -0,0 +1,345 @@
+package signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"time"
+
+	es "github.com/ln80/event-store"
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/event/sourcing"
+)
+
+var (
+	ErrSignFailed       = errors.New("sign event failed")
+	ErrSignatureInvalid = errors.New("invalid event signature")
+)
+
+const (
+	// MetadataSignatureKey is the metadata key of the base64-encoded event signature.
+	MetadataSignatureKey = "es.sig"
+	// MetadataKeyIDKey is the metadata key of the signing key ID.
+	MetadataKeyIDKey = "es.sig.kid"
+	// MetadataFlagKey is the metadata key set on read to flag events with an invalid signature.
+	MetadataFlagKey = "es.sig.invalid"
+)
+
+// unsignedMetadata lists the metadata keys excluded from the signed payload: the signature ones,
+// and the hash chaining ones which are set by the store once events are signed.
+var unsignedMetadata = map[string]struct{}{
+	MetadataSignatureKey:       {},
+	MetadataKeyIDKey:           {},
+	MetadataFlagKey:            {},
+	event.MetadataChainHashKey: {},
+	event.MetadataChainPrevKey: {},
+}
+
+// VerifyPolicy defines how events with an invalid signature are handled on read.
+type VerifyPolicy string
+
+const (
+	// PolicyReject fails the read operation.
+	PolicyReject VerifyPolicy = "reject"
+	// PolicyFlag returns the event flagged using MetadataFlagKey, see Flagged.
+	PolicyFlag VerifyPolicy = "flag"
+	// PolicyIgnore returns the event as is.
+	PolicyIgnore VerifyPolicy = "ignore"
+)
+
+// DecoratorConfig presents the signing decorator configuration.
+type DecoratorConfig struct {
+	// Policy defines how invalid signatures are handled on read, PolicyReject is used by default.
+	Policy VerifyPolicy
+
+	// AllowUnsigned considers unsigned events (ex: appended before signing was enabled) as valid.
+	AllowUnsigned bool
+}
+
+// Decorator signs events on top of an event store.
+//
+// Events are signed on append using Ed25519, the signature and the key ID are stored in the envelope metadata.
+// Signatures are verified on load, replay and query according to the configured policy.
+//
+// The signed payload is a canonical JSON encoding of the envelope, independent of the store serializer.
+// Versions and the hash chaining metadata are excluded, as they might be set by the store once the event is signed.
+// Note that events upcasted on read no longer match their signature.
+type Decorator struct {
+	keys  KeyResolver
+	keyID string
+	cfg   *DecoratorConfig
+	es.EventStore
+}
+
+// NewDecorator returns an event store decorator that signs events using the key identified by the given key ID.
+func NewDecorator(store es.EventStore, keys KeyResolver, keyID string, opts ...func(*DecoratorConfig)) *Decorator {
+	cfg := &DecoratorConfig{
+		Policy: PolicyReject,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	return &Decorator{
+		keys:       keys,
+		keyID:      keyID,
+		cfg:        cfg,
+		EventStore: store,
+	}
+}
+
+// Flagged returns true if the event signature is flagged as invalid on read.
+func Flagged(env event.Envelope) bool {
+	return env.Metadata()[MetadataFlagKey] == "true"
+}
+
+func (d *Decorator) Append(ctx context.Context, id event.StreamID, events []event.Envelope, optFns ...func(*event.AppendConfig)) error {
+	if err := d.sign(ctx, events, optFns...); err != nil {
+		return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+	}
+
+	return d.EventStore.Append(ctx, id, events, optFns...)
+}
+
+func (d *Decorator) AppendToStream(ctx context.Context, chunk sourcing.Stream, optFns ...func(opt *event.AppendConfig)) error {
+	if err := d.sign(ctx, chunk.Unwrap(), optFns...); err != nil {
+		return errors.Err(event.ErrAppendEventsFailed, chunk.ID().String(), err)
+	}
+
+	return d.EventStore.AppendToStream(ctx, chunk, optFns...)
+}
+
+func (d *Decorator) Load(ctx context.Context, id event.StreamID, trange ...time.Time) ([]event.Envelope, error) {
+	envs, err := d.EventStore.Load(ctx, id, trange...)
+	if err != nil {
+		return nil, err
+	}
+
+	envs, err = d.verify(ctx, envs)
+	if err != nil {
+		return nil, errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+
+	return envs, nil
+}
+
+func (d *Decorator) LoadStream(ctx context.Context, id event.StreamID, vrange ...event.Version) (*sourcing.Stream, error) {
+	stm, err := d.EventStore.LoadStream(ctx, id, vrange...)
+	if err != nil {
+		return nil, err
+	}
+
+	envs, err := d.verify(ctx, stm.Unwrap())
+	if err != nil {
+		return nil, errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+
+	return sourcing.NewStream(id, envs), nil
+}
+
+func (d *Decorator) Replay(ctx context.Context, id event.StreamID, q event.StreamReplayQuery, h event.StreamReplayHandler) error {
+	return d.EventStore.Replay(ctx, id, q, func(ctx context.Context, data event.StreamData) error {
+		if env, ok := data.Value.(event.Envelope); ok && data.Type == event.StreamDataTypeRecord {
+			envs, err := d.verify(ctx, []event.Envelope{env})
+			if err != nil {
+				return errors.Err(event.ErrLoadEventFailed, id.String(), err)
+			}
+			data.Value = envs[0]
+		}
+		return h(ctx, data)
+	})
+}
+
+func (d *Decorator) Query(ctx context.Context, id event.StreamID, q event.StreamQuery) (*event.StreamQueryResult, error) {
+	result, err := d.EventStore.Query(ctx, id, q)
+	if err != nil {
+		return nil, err
+	}
+
+	envs, err := d.verify(ctx, result.Events)
+	if err != nil {
+		return nil, errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+	result.Events = envs
+
+	return result, nil
+}
+
+// sign sets the signature and the key ID in the metadata of the given envelopes.
+func (d *Decorator) sign(ctx context.Context, envs []event.Envelope, optFns ...func(*event.AppendConfig)) error {
+	if len(envs) == 0 {
+		return nil
+	}
+
+	// trace IDs are set by the store if missing, which would invalidate signatures
+	cfg := &event.AppendConfig{}
+	for _, opt := range optFns {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	cfg.Trace(ctx, envs)
+
+	key, err := d.keys.PrivateKey(ctx, d.keyID)
+	if err != nil {
+		return err
+	}
+
+	for _, env := range envs {
+		rwEnv, ok := env.(interface {
+			event.Envelope
+			SetMetadata(md map[string]string) event.Envelope
+		})
+		if !ok {
+			return errors.Err(ErrSignFailed, env.StreamID(), "envelope metadata is read-only")
+		}
+
+		b, err := d.payload(env)
+		if err != nil {
+			return errors.Err(ErrSignFailed, env.StreamID(), err)
+		}
+
+		md := maps.Clone(env.Metadata())
+		if md == nil {
+			md = make(map[string]string)
+		}
+		md[MetadataSignatureKey] = base64.StdEncoding.EncodeToString(ed25519.Sign(key, b))
+		md[MetadataKeyIDKey] = d.keyID
+		rwEnv.SetMetadata(md)
+	}
+
+	return nil
+}
+
+// verify checks the signature of the given envelopes, and applies the verify policy to the invalid ones.
+func (d *Decorator) verify(ctx context.Context, envs []event.Envelope) ([]event.Envelope, error) {
+	if d.cfg.Policy == PolicyIgnore {
+		return envs, nil
+	}
+
+	keys := make(map[string]ed25519.PublicKey)
+
+	result := make([]event.Envelope, len(envs))
+	for i, env := range envs {
+		valid, err := d.valid(ctx, env, keys)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case valid:
+			result[i] = env
+		case d.cfg.Policy == PolicyFlag:
+			result[i] = &flagged{Envelope: env}
+		default:
+			return nil, errors.Err(ErrSignatureInvalid, env.StreamID(), env.ID())
+		}
+	}
+
+	return result, nil
+}
+
+// valid returns true if the envelope signature is valid. Unknown key IDs make the signature invalid.
+func (d *Decorator) valid(ctx context.Context, env event.Envelope, keys map[string]ed25519.PublicKey) (bool, error) {
+	md := env.Metadata()
+	sig, ok := md[MetadataSignatureKey]
+	if !ok {
+		return d.cfg.AllowUnsigned, nil
+	}
+
+	keyID := md[MetadataKeyIDKey]
+	key, ok := keys[keyID]
+	if !ok {
+		var err error
+		key, err = d.keys.PublicKey(ctx, keyID)
+		if err != nil && !errors.ErrIs(err, ErrKeyNotFound) {
+			return false, err
+		}
+		keys[keyID] = key
+	}
+	if key == nil {
+		return false, nil
+	}
+
+	bsig, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return false, nil
+	}
+	b, err := d.payload(env)
+	if err != nil {
+		return false, err
+	}
+
+	return ed25519.Verify(key, b, bsig), nil
+}
+
+// payload returns the signed payload of the given envelope.
+func (d *Decorator) payload(env event.Envelope) ([]byte, error) {
+	var md map[string]string
+	for k, v := range env.Metadata() {
+		if _, ok := unsignedMetadata[k]; ok {
+			continue
+		}
+		if md == nil {
+			md = make(map[string]string)
+		}
+		md[k] = v
+	}
+
+	return json.Marshal(signedPayload{
+		StreamID:      env.StreamID(),
+		ID:            env.ID(),
+		Type:          env.Type(),
+		At:            env.At().UnixNano(),
+		TTL:           env.TTL(),
+		User:          env.User(),
+		IPAddr:        env.IPAddr(),
+		CorrelationID: env.CorrelationID(),
+		CausationID:   env.CausationID(),
+		TraceID:       env.TraceID(),
+		Dests:         env.Dests(),
+		Metadata:      md,
+		Data:          env.Event(),
+	})
+}
+
+// signedPayload presents the envelope attributes covered by the signature.
+// Unlike event serializers, it doesn't depend on the event registry (ex: event revisions),
+// and the JSON encoding sorts map keys, which makes it deterministic.
+// Empty destinations and metadata are omitted, as serializers don't distinguish them from nil ones.
+type signedPayload struct {
+	StreamID      string
+	ID            string
+	Type          string
+	At            int64
+	TTL           time.Duration
+	User          string
+	IPAddr        string
+	CorrelationID string
+	CausationID   string
+	TraceID       string
+	Dests         []string          `json:",omitempty"`
+	Metadata      map[string]string `json:",omitempty"`
+	Data          any
+}
+
+// flagged marks the wrapped envelope signature as invalid.
+type flagged struct {
+	event.Envelope
+}
+
+// Metadata implements event.Envelope interface.
+func (e *flagged) Metadata() map[string]string {
+	md := maps.Clone(e.Envelope.Metadata())
+	if md == nil {
+		md = make(map[string]string)
+	}
+	md[MetadataFlagKey] = "true"
+	return md
+}
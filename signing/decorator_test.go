@@ -0,0 +1,230 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/eventtest"
+	"github.com/ln80/event-store/file"
+	"github.com/ln80/event-store/json"
+	"github.com/ln80/event-store/memory"
+)
+
+func TestDecorator(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	keys := NewMemoryKeyResolver()
+	if _, err := keys.GenerateKey("key-1"); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	eventtest.TestEventLoggingStore(t, ctx, NewDecorator(memory.NewEventStore(), keys, "key-1"))
+	eventtest.TestEventSourcingStore(t, ctx, NewDecorator(memory.NewEventStore(), keys, "key-1"))
+	eventtest.TestEventStreamReplayer(t, ctx, NewDecorator(memory.NewEventStore(), keys, "key-1"), func(opt *eventtest.TestEventStreamReplayerOptions) {
+		opt.SupportOrderDESC = true
+	})
+	eventtest.TestEventStreamQuerier(t, ctx, NewDecorator(memory.NewEventStore(), keys, "key-1"), func(opt *eventtest.TestEventStreamQuerierOptions) {
+		opt.SupportOrderDESC = true
+	})
+}
+
+func TestDecorator_Verify(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	keys := NewMemoryKeyResolver()
+	for _, keyID := range []string{"key-1", "key-2"} {
+		if _, err := keys.GenerateKey(keyID); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	}
+
+	store := memory.NewEventStore()
+	d := NewDecorator(store, keys, "key-1")
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, eventtest.GenEvents(3))
+	if err := d.Append(ctx, streamID, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	renvs, err := store.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	for _, env := range renvs {
+		if want, got := "key-1", env.Metadata()[MetadataKeyIDKey]; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if env.Metadata()[MetadataSignatureKey] == "" {
+			t.Fatal("expect signature be set")
+		}
+	}
+
+	// events signed using a rotated key remain valid
+	if _, err := NewDecorator(store, keys, "key-2").Load(ctx, streamID); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// tamper with the second event
+	renvs[1].(event.RWEnvelope).SetUser("mallory")
+
+	t.Run("reject policy", func(t *testing.T) {
+		_, err := d.Load(ctx, streamID)
+		if !errors.Is(err, ErrSignatureInvalid) {
+			t.Fatalf("expect %v error to occur, got: %v", ErrSignatureInvalid, err)
+		}
+		err = d.Replay(ctx, event.NewStreamID(streamID.GlobalID()), event.StreamReplayQuery{}, func(ctx context.Context, data event.StreamData) error {
+			return nil
+		})
+		if !errors.Is(err, ErrSignatureInvalid) {
+			t.Fatalf("expect %v error to occur, got: %v", ErrSignatureInvalid, err)
+		}
+	})
+
+	t.Run("flag policy", func(t *testing.T) {
+		d := NewDecorator(store, keys, "key-1", func(cfg *DecoratorConfig) {
+			cfg.Policy = PolicyFlag
+		})
+		renvs, err := d.Load(ctx, streamID)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		for i, env := range renvs {
+			if want, got := i == 1, Flagged(env); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+	})
+
+	t.Run("ignore policy", func(t *testing.T) {
+		d := NewDecorator(store, keys, "key-1", func(cfg *DecoratorConfig) {
+			cfg.Policy = PolicyIgnore
+		})
+		renvs, err := d.Load(ctx, streamID)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := len(envs), len(renvs); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		streamID := event.NewStreamID(event.UID().String())
+		if err := d.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(1))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if _, err := NewDecorator(store, NewMemoryKeyResolver(), "key-1").Load(ctx, streamID); !errors.Is(err, ErrSignatureInvalid) {
+			t.Fatalf("expect %v error to occur, got: %v", ErrSignatureInvalid, err)
+		}
+		if err := NewDecorator(store, NewMemoryKeyResolver(), "key-1").Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(1))); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("expect %v error to occur, got: %v", ErrKeyNotFound, err)
+		}
+	})
+
+	t.Run("unsigned events", func(t *testing.T) {
+		streamID := event.NewStreamID(event.UID().String())
+		if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(1))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if _, err := d.Load(ctx, streamID); !errors.Is(err, ErrSignatureInvalid) {
+			t.Fatalf("expect %v error to occur, got: %v", ErrSignatureInvalid, err)
+		}
+		d := NewDecorator(store, keys, "key-1", func(cfg *DecoratorConfig) {
+			cfg.AllowUnsigned = true
+		})
+		if _, err := d.Load(ctx, streamID); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	})
+}
+
+func TestDecorator_Payload(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	keys := NewMemoryKeyResolver()
+	if _, err := keys.GenerateKey("key-1"); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// hash chaining metadata are set by the store once events are signed
+	store := memory.NewEventStore(func(sc *memory.StoreConfig) {
+		sc.HashChain = true
+	})
+	d := NewDecorator(store, keys, "key-1")
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, eventtest.GenEvents(2), event.WithMetadata(map[string]string{"es.custom": "value"}))
+	if err := d.Append(ctx, streamID, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	renvs, err := d.Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if renvs[1].Metadata()[event.MetadataChainHashKey] == "" {
+		t.Fatal("expect chain hash be set")
+	}
+
+	// other metadata are signed, regardless of their prefix
+	md := maps.Clone(renvs[0].Metadata())
+	md["es.custom"] = "tampered"
+	renvs[0].(event.RWEnvelope).SetMetadata(md)
+	if _, err := d.Load(ctx, streamID); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("expect %v error to occur, got: %v", ErrSignatureInvalid, err)
+	}
+}
+
+func TestDecorator_WithFileStore(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	keys := NewMemoryKeyResolver()
+	if _, err := keys.GenerateKey("key-1"); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	dir := t.TempDir()
+	store, err := file.NewEventStore(ctx, dir, json.NewEventSerializer(""))
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, eventtest.GenEvents(2))
+	// empty destinations are not persisted by serializers
+	for _, env := range envs {
+		env.(event.RWEnvelope).SetDests([]string{})
+	}
+	if err := NewDecorator(store, keys, "key-1").Append(ctx, streamID, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// verify events once reloaded from the segments
+	store, err = file.NewEventStore(ctx, dir, json.NewEventSerializer(""))
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	defer store.Close()
+
+	renvs, err := NewDecorator(store, keys, "key-1").Load(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := len(envs), len(renvs); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
@@ -0,0 +1,89 @@
+package signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"sync"
+
+	"github.com/ln80/event-store/event/errors"
+)
+
+var (
+	ErrKeyNotFound = errors.New("signing key not found")
+)
+
+// KeyResolver resolves the Ed25519 keys by their key ID.
+type KeyResolver interface {
+	// PrivateKey returns the signing key, or ErrKeyNotFound if not found.
+	PrivateKey(ctx context.Context, keyID string) (ed25519.PrivateKey, error)
+	// PublicKey returns the verification key, or ErrKeyNotFound if not found.
+	PublicKey(ctx context.Context, keyID string) (ed25519.PublicKey, error)
+}
+
+// MemoryKeyResolver implements KeyResolver in memory, mainly used for testing purposes.
+type MemoryKeyResolver struct {
+	mu      sync.RWMutex
+	private map[string]ed25519.PrivateKey
+	public  map[string]ed25519.PublicKey
+}
+
+var _ KeyResolver = &MemoryKeyResolver{}
+
+// NewMemoryKeyResolver returns an in-memory key resolver.
+func NewMemoryKeyResolver() *MemoryKeyResolver {
+	return &MemoryKeyResolver{
+		private: make(map[string]ed25519.PrivateKey),
+		public:  make(map[string]ed25519.PublicKey),
+	}
+}
+
+// GenerateKey generates a new key pair identified by the given key ID, and returns its public key.
+func (r *MemoryKeyResolver) GenerateKey(keyID string) (ed25519.PublicKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.private[keyID] = priv
+	r.public[keyID] = pub
+
+	return pub, nil
+}
+
+// AddPublicKey registers a verification-only key, ex: a key of another service.
+func (r *MemoryKeyResolver) AddPublicKey(keyID string, pub ed25519.PublicKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.public[keyID] = pub
+}
+
+// PrivateKey implements KeyResolver interface.
+func (r *MemoryKeyResolver) PrivateKey(ctx context.Context, keyID string) (ed25519.PrivateKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.private[keyID]
+	if !ok {
+		return nil, errors.Err(ErrKeyNotFound, "", keyID)
+	}
+
+	return key, nil
+}
+
+// PublicKey implements KeyResolver interface.
+func (r *MemoryKeyResolver) PublicKey(ctx context.Context, keyID string) (ed25519.PublicKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.public[keyID]
+	if !ok {
+		return nil, errors.Err(ErrKeyNotFound, "", keyID)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,63 @@
+package sql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect presents the database-specific details required by the event store.
+// It allows to avoid any strong coupling to a specific database driver.
+type Dialect struct {
+	// Name of the dialect, it's mainly used for logging purposes.
+	Name string
+	// BlobType is the column type used to store serialized events.
+	BlobType string
+	// Placeholder returns the bind parameter for the given position (starting from 1).
+	Placeholder func(pos int) string
+	// IsUniqueViolation reports whether the given error is a unique constraint violation.
+	IsUniqueViolation func(err error) bool
+}
+
+var (
+	// SQLite dialect, it's the default one.
+	SQLite = Dialect{
+		Name:     "sqlite",
+		BlobType: "BLOB",
+		Placeholder: func(pos int) string {
+			return "?"
+		},
+		IsUniqueViolation: func(err error) bool {
+			return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+		},
+	}
+
+	// Postgres dialect.
+	Postgres = Dialect{
+		Name:     "postgres",
+		BlobType: "BYTEA",
+		Placeholder: func(pos int) string {
+			return "$" + strconv.Itoa(pos)
+		},
+		IsUniqueViolation: func(err error) bool {
+			return err != nil && (strings.Contains(err.Error(), "SQLSTATE 23505") ||
+				strings.Contains(err.Error(), "duplicate key value violates unique constraint"))
+		},
+	}
+)
+
+// rebind replaces '?' bind parameters in the given query with the dialect ones.
+func (d Dialect) rebind(query string) string {
+	var (
+		b   strings.Builder
+		pos int
+	)
+	for _, r := range query {
+		if r == '?' {
+			pos++
+			b.WriteString(d.Placeholder(pos))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,24 @@
+module github.com/ln80/event-store/sql
+
+go 1.25.0
+
+require (
+	github.com/ln80/event-store v0.7.0
+	github.com/mattn/go-sqlite3 v1.14.33
+)
+
+require (
+	github.com/Masterminds/semver/v3 v3.5.0 // indirect
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/zerologr v1.2.3 // indirect
+	github.com/mattn/go-colorable v0.1.15 // indirect
+	github.com/mattn/go-isatty v0.0.22 // indirect
+	github.com/rs/xid v1.6.0 // indirect
+	github.com/rs/zerolog v1.35.1 // indirect
+	golang.org/x/sys v0.45.0 // indirect
+)
+
+// Requires the root module release shipped along with this module: tag the
+// root module (vX.Y.Z) before sql/vX.Y.Z. The replace directive only applies
+// when developing in this repository.
+replace github.com/ln80/event-store => ../
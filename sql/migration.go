@@ -0,0 +1,134 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/logger"
+)
+
+var (
+	ErrMigrationFailed = errors.New("schema migration failed")
+)
+
+// migration presents a versioned list of schema statements.
+// Migrations are immutable once released; schema changes must be added as new migrations.
+type migration struct {
+	version    int64
+	statements func(t tables, d Dialect) []string
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		statements: func(t tables, d Dialect) []string {
+			return []string{
+				`CREATE TABLE ` + t.globalStreams + ` (
+					id TEXT NOT NULL PRIMARY KEY,
+					record BIGINT NOT NULL
+				)`,
+				`CREATE TABLE ` + t.events + ` (
+					global_stream_id TEXT NOT NULL,
+					global_version TEXT NOT NULL,
+					stream_id TEXT NOT NULL,
+					version TEXT,
+					id TEXT NOT NULL,
+					type TEXT NOT NULL,
+					at BIGINT NOT NULL,
+					user_id TEXT NOT NULL,
+					ip_addr TEXT NOT NULL,
+					expires_at BIGINT,
+					data ` + d.BlobType + ` NOT NULL,
+					PRIMARY KEY (global_stream_id, global_version)
+				)`,
+				`CREATE UNIQUE INDEX ` + t.events + `_stream_version ON ` + t.events + ` (stream_id, version)`,
+				`CREATE UNIQUE INDEX ` + t.events + `_stream_event ON ` + t.events + ` (stream_id, id)`,
+				`CREATE INDEX ` + t.events + `_stream_at ON ` + t.events + ` (stream_id, at)`,
+			}
+		},
+	},
+	{
+		version: 2,
+		statements: func(t tables, d Dialect) []string {
+			return []string{
+				`ALTER TABLE ` + t.events + ` ADD COLUMN correlation_id TEXT NOT NULL DEFAULT ''`,
+				`ALTER TABLE ` + t.events + ` ADD COLUMN causation_id TEXT NOT NULL DEFAULT ''`,
+				`ALTER TABLE ` + t.events + ` ADD COLUMN trace_id TEXT NOT NULL DEFAULT ''`,
+				`CREATE INDEX ` + t.events + `_correlation ON ` + t.events + ` (global_stream_id, correlation_id)`,
+			}
+		},
+	},
+	{
+		version: 3,
+		statements: func(t tables, d Dialect) []string {
+			return []string{
+				`CREATE TABLE ` + t.outbox + ` (
+					id TEXT NOT NULL PRIMARY KEY,
+					dest TEXT NOT NULL,
+					recorded_at BIGINT NOT NULL,
+					seq INTEGER NOT NULL,
+					attempts INTEGER NOT NULL,
+					next_attempt_at BIGINT NOT NULL,
+					data ` + d.BlobType + ` NOT NULL
+				)`,
+				`CREATE INDEX ` + t.outbox + `_due ON ` + t.outbox + ` (next_attempt_at)`,
+			}
+		},
+	},
+}
+
+// Migrate applies the missing schema migrations. It's safe to call it multiple times.
+func (s *Store) Migrate(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithName("sql").WithValues("dialect", s.cfg.Dialect.Name)
+
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.tables.migrations+` (
+		version BIGINT NOT NULL PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`); err != nil {
+		return errors.Err(ErrMigrationFailed, "", err)
+	}
+
+	applied := make(map[int64]struct{})
+	rows, err := s.db.QueryContext(ctx, `SELECT version FROM `+s.tables.migrations)
+	if err != nil {
+		return errors.Err(ErrMigrationFailed, "", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return errors.Err(ErrMigrationFailed, "", err)
+		}
+		applied[v] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Err(ErrMigrationFailed, "", err)
+	}
+	rows.Close()
+
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+
+		if err := s.withTx(ctx, func(tx *sql.Tx) error {
+			for _, stmt := range m.statements(s.tables, s.cfg.Dialect) {
+				if _, err := tx.ExecContext(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			_, err := tx.ExecContext(ctx, s.rebind(`INSERT INTO `+s.tables.migrations+` (version, applied_at) VALUES (?, ?)`),
+				m.version, time.Now().UTC().UnixNano())
+			return err
+		}); err != nil {
+			return errors.Err(ErrMigrationFailed, "", fmt.Errorf("version %d: %w", m.version, err))
+		}
+
+		log.V(1).Info("Schema migration applied", "version", m.version)
+	}
+
+	return nil
+}
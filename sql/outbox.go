@@ -0,0 +1,145 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ln80/event-store/outbox"
+)
+
+// OutboxStore implements outbox.TxStore using a table of the event store database.
+//
+// Messages are recorded in the same transaction as the appended events, using the items returned by TxItems.
+// The outbox table is created by the event store schema migrations.
+type OutboxStore struct {
+	store *Store
+}
+
+var _ outbox.TxStore = &OutboxStore{}
+
+// NewOutboxStore returns an outbox that shares the database, the serializer and the tables of the given event store.
+func NewOutboxStore(store *Store) *OutboxStore {
+	return &OutboxStore{
+		store: store,
+	}
+}
+
+// TxItems implements outbox.TxStore interface.
+func (o *OutboxStore) TxItems(ctx context.Context, msgs ...outbox.Message) []any {
+	if len(msgs) == 0 {
+		return nil
+	}
+	return []any{TxItem(func(ctx context.Context, tx *sql.Tx) error {
+		return o.add(ctx, tx, msgs)
+	})}
+}
+
+// Add implements outbox.Store interface.
+func (o *OutboxStore) Add(ctx context.Context, msgs ...outbox.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	return o.store.withTx(ctx, func(tx *sql.Tx) error {
+		return o.add(ctx, tx, msgs)
+	})
+}
+
+// add inserts the given messages in the current transaction, already recorded messages are skipped.
+func (o *OutboxStore) add(ctx context.Context, tx *sql.Tx, msgs []outbox.Message) error {
+	s := o.store
+
+	stmt := s.rebind(`INSERT INTO ` + s.tables.outbox + `
+		(id, dest, recorded_at, seq, attempts, next_attempt_at, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`)
+	at := time.Now().UTC().UnixNano()
+	for i, msg := range msgs {
+		b, err := s.serializer.MarshalEvent(ctx, msg.Event)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, stmt,
+			msg.ID, msg.Dest, at, i, msg.Attempts, unixNano(msg.NextAttemptAt), b,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Due implements outbox.Store interface.
+func (o *OutboxStore) Due(ctx context.Context, now time.Time, limit int) ([]outbox.Message, error) {
+	s := o.store
+
+	query := `SELECT id, dest, attempts, next_attempt_at, data FROM ` + s.tables.outbox + `
+		WHERE next_attempt_at <= ? ORDER BY recorded_at, seq`
+	args := []any{now.UnixNano()}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	msgs := make([]outbox.Message, 0)
+	for rows.Next() {
+		var (
+			msg           outbox.Message
+			nextAttemptAt int64
+			b             []byte
+		)
+		if err := rows.Scan(&msg.ID, &msg.Dest, &msg.Attempts, &nextAttemptAt, &b); err != nil {
+			return nil, err
+		}
+		if msg.Event, err = s.serializer.UnmarshalEvent(ctx, b); err != nil {
+			return nil, err
+		}
+		if nextAttemptAt != 0 {
+			msg.NextAttemptAt = time.Unix(0, nextAttemptAt)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, rows.Err()
+}
+
+// Ack implements outbox.Store interface.
+func (o *OutboxStore) Ack(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	s := o.store
+
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	_, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM `+s.tables.outbox+`
+		WHERE id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)`), args...)
+
+	return err
+}
+
+// Retry implements outbox.Store interface.
+func (o *OutboxStore) Retry(ctx context.Context, msg outbox.Message) error {
+	s := o.store
+
+	_, err := s.db.ExecContext(ctx, s.rebind(`UPDATE `+s.tables.outbox+`
+		SET attempts = ?, next_attempt_at = ? WHERE id = ?`), msg.Attempts, unixNano(msg.NextAttemptAt), msg.ID)
+
+	return err
+}
+
+// unixNano returns the given time in nanoseconds, or zero if the time is zero.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
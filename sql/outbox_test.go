@@ -0,0 +1,88 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/eventtest"
+	"github.com/ln80/event-store/outbox"
+)
+
+func TestOutboxStore(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store, _ := newTestStore(t, ctx)
+	ob := NewOutboxStore(store)
+	es := outbox.NewDecorator(store, ob)
+
+	streamID := event.NewStreamID(event.UID().String())
+	envs := event.Wrap(ctx, streamID, eventtest.GenEvents(4))
+	if err := es.Append(ctx, streamID, envs); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	msgs, err := ob.Due(ctx, time.Now(), 0)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 2, len(msgs); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	for i, msg := range msgs {
+		if want, got := envs[i*2].ID(), msg.Event.ID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := envs[i*2].GlobalVersion(), msg.Event.GlobalVersion(); !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	// messages are not recorded if the append fails
+	if err := es.Append(ctx, streamID, envs); !errors.Is(err, event.ErrAppendEventsConflict) {
+		t.Fatalf("expect %v error to occur, got: %v", event.ErrAppendEventsConflict, err)
+	}
+	if msgs, _ = ob.Due(ctx, time.Now(), 0); len(msgs) != 2 {
+		t.Fatalf("expect %d, %d be equals", 2, len(msgs))
+	}
+
+	// messages are rolled back along with the events
+	errTx := errors.New("tx item failure")
+	err = es.Append(ctx, streamID, event.Wrap(ctx, streamID, eventtest.GenEvents(2)), func(ac *event.AppendConfig) {
+		ac.AddToTx = func(ctx context.Context) []any {
+			return []any{func(ctx context.Context, tx *sql.Tx) error { return errTx }}
+		}
+	})
+	if !errors.Is(err, errTx) {
+		t.Fatalf("expect %v error to occur, got: %v", errTx, err)
+	}
+	if msgs, _ = ob.Due(ctx, time.Now(), 0); len(msgs) != 2 {
+		t.Fatalf("expect %d, %d be equals", 2, len(msgs))
+	}
+
+	// retried messages aren't due until their next attempt
+	msg := msgs[0]
+	msg.Attempts++
+	msg.NextAttemptAt = time.Now().Add(time.Hour)
+	if err := ob.Retry(ctx, msg); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if msgs, _ = ob.Due(ctx, time.Now(), 0); len(msgs) != 1 {
+		t.Fatalf("expect %d, %d be equals", 1, len(msgs))
+	}
+	if want, got := envs[2].ID(), msgs[0].Event.ID(); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	if err := ob.Ack(ctx, msg.ID, msgs[0].ID); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if msgs, _ = ob.Due(ctx, time.Now().Add(2*time.Hour), 0); len(msgs) != 0 {
+		t.Fatalf("expect %d, %d be equals", 0, len(msgs))
+	}
+}
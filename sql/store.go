@@ -0,0 +1,669 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/errors"
+	"github.com/ln80/event-store/event/sourcing"
+)
+
+// Statement presents a write operation to execute in the same transaction as the appended events.
+// It's one of the supported items returned by event.AppendConfig.AddToTx.
+type Statement struct {
+	Query string
+	Args  []any
+}
+
+// TxItem presents a custom write operation to execute in the same transaction as the appended events.
+// It's one of the supported items returned by event.AppendConfig.AddToTx.
+type TxItem func(ctx context.Context, tx *sql.Tx) error
+
+// StoreConfig presents the SQL event store configuration.
+type StoreConfig struct {
+	Dialect Dialect
+	// TablePrefix is prepended to the event store table names.
+	TablePrefix string
+	// SkipMigration disables applying schema migrations at the store creation.
+	SkipMigration bool
+	// SizeLimit defines the maximum size of appended records and events. No limit is applied by default.
+	SizeLimit event.SizeLimit
+}
+
+// tables presents the resolved table names.
+type tables struct {
+	migrations, globalStreams, events, outbox string
+}
+
+// Store implements different event store interfaces on top of database/sql.
+//
+// Events are stored individually, each row contains the serialized envelope as well as the columns
+// required for filtering. Optimistic concurrency is enforced using unique constraints on
+// (stream ID, version) and (stream ID, event ID).
+// The global version is assigned per global stream (aka tenant) by incrementing a per-record counter
+// in the same transaction, which makes records consecutive within a global stream.
+type Store struct {
+	db         *sql.DB
+	serializer event.Serializer
+	tables     tables
+
+	cfg *StoreConfig
+}
+
+// interface safe-guards
+var (
+	_ event.Store          = &Store{}
+	_ sourcing.Store       = &Store{}
+	_ event.StreamReplayer = &Store{}
+	_ event.StreamQuerier  = &Store{}
+	_ event.ExpiryPurger   = &Store{}
+)
+
+// NewEventStore returns an event store based on the given database.
+// It applies the schema migrations unless it's explicitly disabled.
+func NewEventStore(ctx context.Context, db *sql.DB, ser event.Serializer, opts ...func(*StoreConfig)) (*Store, error) {
+	cfg := &StoreConfig{
+		Dialect:     SQLite,
+		TablePrefix: "es_",
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	s := &Store{
+		db:         db,
+		serializer: ser,
+		tables: tables{
+			migrations:    cfg.TablePrefix + "migrations",
+			globalStreams: cfg.TablePrefix + "global_streams",
+			events:        cfg.TablePrefix + "events",
+			outbox:        cfg.TablePrefix + "outbox",
+		},
+		cfg: cfg,
+	}
+
+	if !cfg.SkipMigration {
+		if err := s.Migrate(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Append implements event.Store interface.
+func (s *Store) Append(ctx context.Context, id event.StreamID, events []event.Envelope, opts ...func(*event.AppendConfig)) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	cfg := &event.AppendConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	cfg.Trace(ctx, events)
+
+	if err := event.CheckSizeLimit(ctx, s.serializer, id, events, s.cfg.SizeLimit); err != nil {
+		return err
+	}
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		return s.append(ctx, tx, id, events, cfg)
+	})
+}
+
+// Load implements event.Store interface.
+func (s *Store) Load(ctx context.Context, id event.StreamID, trange ...time.Time) ([]event.Envelope, error) {
+	since, until := event.TimeRange(trange)
+
+	envs, err := s.load(ctx, `SELECT data FROM `+s.tables.events+`
+		WHERE stream_id = ? AND at >= ? AND at <= ? AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY global_version`,
+		id.String(), since.UnixNano(), until.UnixNano(), time.Now().UTC().UnixNano())
+	if err != nil {
+		return nil, errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+
+	return envs, nil
+}
+
+// AppendToStream implements sourcing.Store interface.
+func (s *Store) AppendToStream(ctx context.Context, chunk sourcing.Stream, opts ...func(*event.AppendConfig)) error {
+	if chunk.Empty() {
+		return nil
+	}
+
+	// validate the versioned chunk of events
+	if err := chunk.Validate(); err != nil {
+		return err
+	}
+
+	cfg := &event.AppendConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	cfg.Trace(ctx, chunk.Unwrap())
+
+	if err := event.CheckSizeLimit(ctx, s.serializer, chunk.ID(), chunk.Unwrap(), s.cfg.SizeLimit); err != nil {
+		return err
+	}
+
+	id := chunk.ID()
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		// make sure stream sequence is valid, no gaps are introduced.
+		// Concurrent appends are still caught by the unique constraint on (stream ID, version).
+		lastVersion := event.VersionZero
+		last, err := s.loadTx(ctx, tx, `SELECT data FROM `+s.tables.events+`
+			WHERE stream_id = ? AND version IS NOT NULL ORDER BY version DESC LIMIT 1`, id.String())
+		if err != nil {
+			return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+		}
+		if len(last) > 0 {
+			lastVersion = last[0].Version()
+		}
+		if ev := cfg.ExpectedVersion; ev != nil {
+			if err := ev.Check(id.String(), lastVersion); err != nil {
+				return err
+			}
+			if err := event.RebaseVersions(chunk.Unwrap(), lastVersion); err != nil {
+				return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+			}
+		}
+		firstVersion := chunk.Unwrap()[0].Version()
+		if !firstVersion.Next(lastVersion) {
+			return errors.Err(
+				event.ErrAppendEventsConflict, id.String(),
+				"invalid sequence "+lastVersion.String()+" "+firstVersion.String(),
+			)
+		}
+
+		return s.append(ctx, tx, id, chunk.Unwrap(), cfg)
+	})
+}
+
+// LoadStream implements sourcing.Store interface.
+func (s *Store) LoadStream(ctx context.Context, id event.StreamID, vrange ...event.Version) (*sourcing.Stream, error) {
+	from, to := event.VersionRange(vrange)
+
+	envs, err := s.load(ctx, `SELECT data FROM `+s.tables.events+`
+		WHERE stream_id = ? AND version >= ? AND version <= ? AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY version`,
+		id.String(), versionKey(from), versionKey(to), time.Now().UTC().UnixNano())
+	if err != nil {
+		return nil, errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+
+	return sourcing.NewStream(id, envs), nil
+}
+
+// Replay implements event.StreamReplayer interface.
+func (s *Store) Replay(ctx context.Context, id event.StreamID, q event.StreamReplayQuery, h event.StreamReplayHandler) error {
+	q.Build()
+
+	cond := `global_stream_id = ? AND global_version >= ? AND global_version <= ?`
+	args := []any{id.GlobalID(), versionKey(q.From), versionKey(q.To)}
+	if !id.Global() {
+		cond += ` AND ` + streamCond
+		args = append(args, id.String(), subStreamsPattern(id))
+	}
+
+	// expired events are loaded to validate the stream sequence,
+	// though the record limit only applies to records with at least one alive event.
+	query := s.selectRecords(cond, cond+` AND (expires_at IS NULL OR expires_at > ?)`, q.Order)
+	args = append(append(args, args...), time.Now().UTC().UnixNano(), q.RecordLimit)
+
+	envs, err := s.load(ctx, query, args...)
+	if err != nil {
+		return errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+
+	// validate the stream sequence in ascending order
+	if q.Order == event.StreamOrderDESC {
+		slices.Reverse(envs)
+	}
+
+	// prepare stream validation boundaries
+	vb := event.ValidationBoundaries{
+		From: q.From,
+		To:   q.To,
+	}
+	vb.Build()
+	vOpt := func(v *event.Validation) {
+		v.GlobalStream = true
+		// sub-streams of a global stream are not necessarily consecutive.
+		v.SkipVersion = !id.Global()
+		v.SkipTimeStamp = false
+		v.Boundaries = vb
+		// expired records might be already purged
+		v.AllowRecordGaps = true
+	}
+
+	now := time.Now().UTC()
+
+	cur := event.NewCursor(id.GlobalID())
+	fenvs := make([]event.Envelope, 0, len(envs))
+	for _, env := range envs {
+		if _, err := event.ValidateEvent(env, cur, vOpt); err != nil {
+			return err
+		}
+		// expired events are still part of the stream sequence until purged,
+		// thus they are only skipped once validated.
+		if event.Expired(env, now) {
+			continue
+		}
+		fenvs = append(fenvs, env)
+	}
+	if q.Order == event.StreamOrderDESC {
+		slices.Reverse(fenvs)
+	}
+
+	for _, env := range fenvs {
+		if err := h(ctx, event.StreamData{
+			Type: event.StreamDataTypeRecord, Value: env,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Query implements event.StreamQuerier interface.
+//
+// Similarly to the in-memory store, the record limit applies to records that contain at least one matching event,
+// and the returned cursor is opaque and only set if more records remain to be queried.
+func (s *Store) Query(ctx context.Context, id event.StreamID, q event.StreamQuery) (*event.StreamQueryResult, error) {
+	q.Build()
+
+	cond := `global_stream_id = ? AND (expires_at IS NULL OR expires_at > ?)`
+	args := []any{id.GlobalID(), time.Now().UTC().UnixNano()}
+	if !id.Global() {
+		cond += ` AND ` + streamCond
+		args = append(args, id.String(), subStreamsPattern(id))
+	}
+	if q.Cursor != nil {
+		b, err := base64.RawURLEncoding.DecodeString(*q.Cursor)
+		if err != nil {
+			return nil, errors.Err(event.ErrInvalidCursor, id.String(), err)
+		}
+		after, err := event.ParseVersion(string(b))
+		if err != nil {
+			return nil, errors.Err(event.ErrInvalidCursor, id.String(), err)
+		}
+		if q.Order == event.StreamOrderDESC {
+			cond += ` AND global_version < ?`
+			args = append(args, versionKey(after.Trunc()))
+		} else {
+			cond += ` AND global_version >= ?`
+			args = append(args, versionKey(after.Incr()))
+		}
+	}
+	if !q.From.IsZero() {
+		cond += ` AND at >= ?`
+		args = append(args, q.From.UnixNano())
+	}
+	if !q.To.IsZero() {
+		cond += ` AND at <= ?`
+		args = append(args, q.To.UnixNano())
+	}
+	for _, f := range []struct {
+		col    string
+		values []string
+	}{
+		{"user_id", q.Users},
+		{"type", q.Types},
+		{"ip_addr", q.IPAddrs},
+		{"correlation_id", q.CorrelationIDs},
+		{"causation_id", q.CausationIDs},
+		{"trace_id", q.TraceIDs},
+	} {
+		if len(f.values) == 0 {
+			continue
+		}
+		cond += ` AND ` + f.col + ` IN (` + strings.TrimSuffix(strings.Repeat("?,", len(f.values)), ",") + `)`
+		for _, v := range f.values {
+			args = append(args, v)
+		}
+	}
+
+	// select an extra record to find out whether more records remain to be queried
+	query := s.selectRecords(cond, cond, q.Order)
+	args = append(append(args, args...), q.RecordLimit+1)
+
+	envs, err := s.load(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Err(event.ErrLoadEventFailed, id.String(), err)
+	}
+
+	result := &event.StreamQueryResult{
+		Events: limitRecords(envs, q.RecordLimit),
+	}
+	if l := len(result.Events); l < len(envs) {
+		cursor := base64.RawURLEncoding.EncodeToString([]byte(result.Events[l-1].GlobalVersion().Trunc().String()))
+		result.Cursor = &cursor
+	}
+
+	return result, nil
+}
+
+// PurgeExpired implements event.ExpiryPurger interface.
+func (s *Store) PurgeExpired(ctx context.Context, at time.Time) (int, error) {
+	type record struct {
+		globalID, streamID string
+		ver                event.Version
+	}
+
+	count := 0
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		// lookup the records that contain at least one expired event
+		rows, err := tx.QueryContext(ctx, s.rebind(`SELECT global_stream_id, stream_id, global_version FROM `+s.tables.events+`
+			WHERE expires_at IS NOT NULL AND expires_at < ?`), at.UnixNano())
+		if err != nil {
+			return err
+		}
+		records := make([]record, 0)
+		seen := make(map[record]struct{})
+		for rows.Next() {
+			var (
+				r   record
+				ver string
+			)
+			if err := rows.Scan(&r.globalID, &r.streamID, &ver); err != nil {
+				rows.Close()
+				return err
+			}
+			if r.ver, err = event.ParseVersion(ver + event.VersionSuffixNotEOF); err != nil {
+				rows.Close()
+				return err
+			}
+			r.ver = r.ver.Trunc()
+			if _, ok := seen[r]; ok {
+				continue
+			}
+			seen[r] = struct{}{}
+			records = append(records, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, r := range records {
+			from, to := versionKey(r.ver), versionKey(r.ver.Incr())
+
+			// the record is purged once all its events are expired
+			var total, alive int
+			if err := tx.QueryRowContext(ctx, s.rebind(`SELECT COUNT(*), COUNT(CASE WHEN expires_at IS NULL OR expires_at >= ? THEN 1 END)
+				FROM `+s.tables.events+` WHERE global_stream_id = ? AND global_version >= ? AND global_version < ?`),
+				at.UnixNano(), r.globalID, from, to).Scan(&total, &alive); err != nil {
+				return err
+			}
+			if alive > 0 {
+				continue
+			}
+
+			// keep the last record as the stream head
+			var next int
+			if err := tx.QueryRowContext(ctx, s.rebind(`SELECT COUNT(*) FROM `+s.tables.events+`
+				WHERE stream_id = ? AND global_version >= ?`), r.streamID, to).Scan(&next); err != nil {
+				return err
+			}
+			if next == 0 {
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM `+s.tables.events+`
+				WHERE global_stream_id = ? AND global_version >= ? AND global_version < ?`), r.globalID, from, to); err != nil {
+				return err
+			}
+			count += total
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Err(event.ErrPurgeExpiredFailed, "", err)
+	}
+
+	return count, nil
+}
+
+// append inserts the given events in the current transaction, as well as the caller items returned by AddToTx.
+func (s *Store) append(ctx context.Context, tx *sql.Tx, id event.StreamID, events []event.Envelope, cfg *event.AppendConfig) error {
+	record, err := s.nextRecord(ctx, tx, id.GlobalID())
+	if err != nil {
+		return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+	}
+
+	// foreach chunk event increment the fractional part of the global stream version
+	gVer := event.VersionZero.Add(record, 0)
+	l := len(events)
+	for i := range events {
+		if rwEnv, ok := events[i].(event.GlobalVersionSetter); ok {
+			// mark the last event of the chunk as EOF
+			if i == l-1 {
+				rwEnv.SetGlobalVersion(gVer.EOF())
+				break
+			}
+			// otherwise increment sequence for the next event
+			rwEnv.SetGlobalVersion(gVer)
+			gVer = gVer.Add(0, 1)
+		}
+	}
+
+	stmt := s.rebind(`INSERT INTO ` + s.tables.events + `
+		(global_stream_id, global_version, stream_id, version, id, type, at, user_id, ip_addr,
+		correlation_id, causation_id, trace_id, expires_at, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	for _, env := range events {
+		b, err := s.serializer.MarshalEvent(ctx, env)
+		if err != nil {
+			return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+		}
+
+		var ver, expiresAt any
+		if !env.Version().IsZero() {
+			ver = versionKey(env.Version())
+		}
+		if env.TTL() > 0 {
+			expiresAt = env.At().Add(env.TTL()).UnixNano()
+		}
+
+		if _, err := tx.ExecContext(ctx, stmt,
+			id.GlobalID(), versionKey(env.GlobalVersion()), id.String(), ver, env.ID(), env.Type(),
+			env.At().UnixNano(), env.User(), env.IPAddr(),
+			env.CorrelationID(), env.CausationID(), env.TraceID(), expiresAt, b,
+		); err != nil {
+			if s.cfg.Dialect.IsUniqueViolation(err) {
+				return errors.Err(event.ErrAppendEventsConflict, id.String(), err)
+			}
+			return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+		}
+	}
+
+	if cfg.AddToTx == nil {
+		return nil
+	}
+	for _, item := range cfg.AddToTx(ctx) {
+		var err error
+		switch it := item.(type) {
+		case Statement:
+			_, err = tx.ExecContext(ctx, s.rebind(it.Query), it.Args...)
+		case *Statement:
+			_, err = tx.ExecContext(ctx, s.rebind(it.Query), it.Args...)
+		case TxItem:
+			err = it(ctx, tx)
+		case func(context.Context, *sql.Tx) error:
+			err = it(ctx, tx)
+		default:
+			return errors.Err(event.ErrUnsupportedAppendOption, id.String(), fmt.Sprintf("unsupported tx item %T", item))
+		}
+		if err != nil {
+			return errors.Err(event.ErrAppendEventsFailed, id.String(), err)
+		}
+	}
+
+	return nil
+}
+
+// nextRecord increments and returns the record counter of the given global stream.
+// The counter is the integer part of the global stream version.
+func (s *Store) nextRecord(ctx context.Context, tx *sql.Tx, globalID string) (uint64, error) {
+	if _, err := tx.ExecContext(ctx, s.rebind(`INSERT INTO `+s.tables.globalStreams+` (id, record) VALUES (?, 0)
+		ON CONFLICT (id) DO NOTHING`), globalID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind(`UPDATE `+s.tables.globalStreams+` SET record = record + 1 WHERE id = ?`), globalID); err != nil {
+		return 0, err
+	}
+	var record uint64
+	if err := tx.QueryRowContext(ctx, s.rebind(`SELECT record FROM `+s.tables.globalStreams+` WHERE id = ?`), globalID).Scan(&record); err != nil {
+		return 0, err
+	}
+	return record, nil
+}
+
+// load executes the given query and unmarshals the selected events.
+func (s *Store) load(ctx context.Context, query string, args ...any) ([]event.Envelope, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	return s.scan(ctx, rows)
+}
+
+// loadTx is similar to load except that the query is executed within the given transaction.
+func (s *Store) loadTx(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]event.Envelope, error) {
+	rows, err := tx.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	return s.scan(ctx, rows)
+}
+
+func (s *Store) scan(ctx context.Context, rows *sql.Rows) ([]event.Envelope, error) {
+	defer rows.Close()
+
+	upcasted := false
+	envs := make([]event.Envelope, 0)
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		// upcasters might split an event into several ones
+		evts, err := event.UnmarshalEvents(ctx, s.serializer, b)
+		if err != nil {
+			return nil, err
+		}
+		upcasted = upcasted || len(evts) != 1
+		envs = append(envs, evts...)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if upcasted {
+		event.RenumberEvents(envs)
+	}
+
+	return envs, nil
+}
+
+// withTx runs the given function within a transaction. The transaction is rolled back if the function fails.
+func (s *Store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return fn(tx)
+}
+
+func (s *Store) rebind(query string) string {
+	return s.cfg.Dialect.rebind(query)
+}
+
+// versionKey returns a sortable representation of the given version.
+// Unlike Version.String, the EOF suffix is dropped to keep the lexicographic order consistent with the version order.
+func versionKey(v event.Version) string {
+	str := v.String()
+	return str[:len(str)-1]
+}
+
+// streamCond matches the events of a stream and its sub-streams, it expects the stream ID followed by subStreamsPattern.
+const streamCond = `(stream_id = ? OR stream_id LIKE ? ESCAPE '\')`
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// subStreamsPattern returns the LIKE pattern of the given stream sub-streams.
+// LIKE wildcards are escaped, as they are valid stream ID characters.
+func subStreamsPattern(id event.StreamID) string {
+	return likeEscaper.Replace(id.String()+event.StreamIDPartsDelimiter) + "%"
+}
+
+// recordKey is the SQL expression of the record an event belongs to, i.e the integer part of its global version key.
+const recordKey = `SUBSTR(global_version, 1, 20)`
+
+// selectRecords returns a query that selects the events matching the given condition, and belonging to the first records
+// that match the record condition. Records are sorted according to the given order, and limited using the last bind parameter.
+//
+// The query expects the condition args, followed by the record condition args and the limit.
+func (s *Store) selectRecords(cond, recordCond string, order event.StreamOrder) string {
+	dir := `ASC`
+	if order == event.StreamOrderDESC {
+		dir = `DESC`
+	}
+
+	return `SELECT data FROM ` + s.tables.events + `
+		WHERE ` + cond + ` AND ` + recordKey + ` IN (
+			SELECT DISTINCT ` + recordKey + ` FROM ` + s.tables.events + `
+			WHERE ` + recordCond + ` ORDER BY 1 ` + dir + ` LIMIT ?)
+		ORDER BY global_version ` + dir
+}
+
+// limitRecords keeps the events that belong to the first records according to the given limit.
+// It's mainly used to drop the extra record selected to paginate query results. Events are expected to be sorted by record.
+func limitRecords(envs []event.Envelope, limit int) []event.Envelope {
+	if limit <= 0 {
+		return envs
+	}
+	records := 0
+	last := event.VersionZero
+	for i, env := range envs {
+		if record := env.GlobalVersion().Trunc(); !record.Equal(last) {
+			if records == limit {
+				return envs[:i]
+			}
+			records++
+			last = record
+		}
+	}
+	return envs
+}
@@ -0,0 +1,365 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/ln80/event-store/event"
+	"github.com/ln80/event-store/event/sourcing"
+	"github.com/ln80/event-store/eventtest"
+	"github.com/ln80/event-store/json"
+)
+
+func newTestStore(t *testing.T, ctx context.Context, opts ...func(*StoreConfig)) (*Store, *sql.DB) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "es.db"))
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	store, err := NewEventStore(ctx, db, json.NewEventSerializer(""), opts...)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	return store, db
+}
+
+func TestEventStore(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store, _ := newTestStore(t, ctx)
+
+	eventtest.TestEventLoggingStore(t, ctx, store)
+	eventtest.TestEventSourcingStore(t, ctx, store)
+	eventtest.TestEventStreamReplayer(t, ctx, store, func(opt *eventtest.TestEventStreamReplayerOptions) {
+		opt.SupportOrderDESC = true
+	})
+	eventtest.TestEventStreamQuerier(t, ctx, store, func(opt *eventtest.TestEventStreamQuerierOptions) {
+		opt.SupportOrderDESC = true
+	})
+	eventtest.TestEventStoreExpiry(t, ctx, store)
+
+	limit := event.SizeLimit{Record: 4096, Event: 1024}
+	store, _ = newTestStore(t, ctx, func(sc *StoreConfig) {
+		sc.SizeLimit = limit
+	})
+	eventtest.TestEventStoreSizeLimit(t, ctx, store, limit)
+}
+
+func TestEventStore_Migrate(t *testing.T) {
+	ctx := context.Background()
+
+	store, db := newTestStore(t, ctx)
+
+	// migrations must be idempotent
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM es_migrations`).Scan(&count); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := len(migrations), count; want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+}
+
+func TestEventStore_GlobalVersion(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store, _ := newTestStore(t, ctx)
+
+	globalID := event.UID().String()
+	streamID1 := event.NewStreamID(globalID, "service1")
+	streamID2 := event.NewStreamID(globalID, "service2")
+
+	envs1 := event.Wrap(ctx, streamID1, eventtest.GenEvents(3))
+	if err := store.Append(ctx, streamID1, envs1); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	envs2 := event.Wrap(ctx, streamID2, eventtest.GenEvents(2))
+	if err := store.Append(ctx, streamID2, envs2); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	if want, got := event.VersionZero.Add(1, 2).EOF(), envs1[2].GlobalVersion(); want.String() != got.String() {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := event.VersionZero.Add(2, 0), envs2[0].GlobalVersion(); want.String() != got.String() {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// another global stream has its own sequence
+	streamID3 := event.NewStreamID(event.UID().String())
+	envs3 := event.Wrap(ctx, streamID3, eventtest.GenEvents(1))
+	if err := store.Append(ctx, streamID3, envs3); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := event.VersionZero.Add(1, 0).EOF(), envs3[0].GlobalVersion(); want.String() != got.String() {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestEventStore_AddToTx(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store, db := newTestStore(t, ctx)
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE accounts (id TEXT PRIMARY KEY, balance INTEGER)`); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	streamID := event.NewStreamID(event.UID().String(), "account", "1")
+
+	stm := sourcing.Wrap(ctx, streamID, event.VersionZero, eventtest.GenEvents(2))
+	if err := store.AppendToStream(ctx, stm, func(ac *event.AppendConfig) {
+		ac.AddToTx = func(ctx context.Context) []any {
+			return []any{
+				Statement{Query: `INSERT INTO accounts (id, balance) VALUES (?, ?)`, Args: []any{"1", 100}},
+				TxItem(func(ctx context.Context, tx *sql.Tx) error {
+					_, err := tx.ExecContext(ctx, `UPDATE accounts SET balance = balance + 1 WHERE id = ?`, "1")
+					return err
+				}),
+			}
+		}
+	}); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	var balance int
+	if err := db.QueryRowContext(ctx, `SELECT balance FROM accounts WHERE id = ?`, "1").Scan(&balance); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 101, balance; want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	// a failing caller item must roll back appended events
+	stm2 := sourcing.Wrap(ctx, streamID, stm.Version(), eventtest.GenEvents(2))
+	err := store.AppendToStream(ctx, stm2, func(ac *event.AppendConfig) {
+		ac.AddToTx = func(ctx context.Context) []any {
+			return []any{
+				Statement{Query: `INSERT INTO accounts (id, balance) VALUES (?, ?)`, Args: []any{"1", 0}},
+			}
+		}
+	})
+	if want, got := event.ErrAppendEventsFailed, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// an unsupported item must roll back appended events as well
+	err = store.AppendToStream(ctx, stm2, func(ac *event.AppendConfig) {
+		ac.AddToTx = func(ctx context.Context) []any {
+			return []any{"unsupported"}
+		}
+	})
+	if want, got := event.ErrUnsupportedAppendOption, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	rstm, err := store.LoadStream(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := stm.Version(), rstm.Version(); !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	if err := store.AppendToStream(ctx, stm2); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+}
+
+func TestEventStore_Query(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store, _ := newTestStore(t, ctx)
+
+	streamID := event.NewStreamID(event.UID().String())
+
+	// alternate records of matching and non-matching events
+	for i := 0; i < 6; i++ {
+		evts := eventtest.GenEvents(1)
+		if i%2 == 0 {
+			evts = []any{eventtest.Event1{Val: "val " + strconv.Itoa(i)}}
+		}
+		if err := store.Append(ctx, streamID, event.Wrap(ctx, streamID, evts)); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	}
+
+	q := event.StreamQuery{
+		RecordLimit: 2,
+		Types:       []string{event.TypeOf(eventtest.Event1{})},
+	}
+	pages := make([][]event.Envelope, 0)
+	for {
+		result, err := store.Query(ctx, streamID, q)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		pages = append(pages, result.Events)
+		if result.Cursor == nil {
+			break
+		}
+		q.Cursor = result.Cursor
+	}
+
+	if want, got := 2, len(pages); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := 2, len(pages[0]); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := 1, len(pages[1]); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	for i, env := range append(pages[0], pages[1]...) {
+		if want, got := "val "+strconv.Itoa(i*2), env.Event().(*eventtest.Event1).Val; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
+
+func TestEventStore_SubStreamWildcards(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store, _ := newTestStore(t, ctx)
+
+	globalID := event.UID().String()
+	streamID := event.NewStreamID(globalID, "a_b")
+	// would match "a_b/%" if LIKE wildcards are not escaped
+	otherID := event.NewStreamID(globalID, "axb", "c")
+
+	for _, id := range []event.StreamID{streamID, otherID} {
+		if err := store.Append(ctx, id, event.Wrap(ctx, id, eventtest.GenEvents(2))); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+	}
+
+	replayed := make([]event.Envelope, 0)
+	if err := store.Replay(ctx, streamID, event.StreamReplayQuery{}, func(ctx context.Context, data event.StreamData) error {
+		replayed = append(replayed, data.Value.(event.Envelope))
+		return nil
+	}); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 2, len(replayed); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	result, err := store.Query(ctx, streamID, event.StreamQuery{})
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 2, len(result.Events); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	for _, env := range append(replayed, result.Events...) {
+		if want, got := streamID.String(), env.StreamID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
+
+func TestEventStore_Conflict(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store, _ := newTestStore(t, ctx)
+
+	streamID := event.NewStreamID(event.UID().String(), "account", "1")
+
+	stm := sourcing.Wrap(ctx, streamID, event.VersionZero, eventtest.GenEvents(2))
+	if err := store.AppendToStream(ctx, stm); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// bypass the sequence check to make sure the unique constraint on (stream ID, version)
+	// is mapped to a conflict error.
+	stm2 := sourcing.Wrap(ctx, streamID, event.VersionZero, eventtest.GenEvents(2))
+	err := store.Append(ctx, streamID, stm2.Unwrap())
+	if want, got := event.ErrAppendEventsConflict, err; !errors.Is(got, want) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestEventStore_WithSplitUpcast(t *testing.T) {
+	eventtest.RegisterEvent("")
+
+	ctx := context.Background()
+
+	store, db := newTestStore(t, ctx)
+
+	streamID := event.NewStreamID(event.UID().String(), "service")
+	chunk := sourcing.Wrap(ctx, streamID, event.VersionZero, []any{eventtest.Event1{Val: "split"}, eventtest.Event1{Val: "keep"}})
+	if err := store.AppendToStream(ctx, chunk); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	typ := event.TypeOf(eventtest.Event1{})
+	upcasters := event.NewUpcasters().
+		Set(typ, 0, func(ctx context.Context, evt event.RawEvent) ([]event.RawEvent, error) {
+			if evt.Data["Val"] != "split" {
+				return []event.RawEvent{evt}, nil
+			}
+			return []event.RawEvent{
+				{Type: typ, Data: map[string]any{"Val": "split-1"}},
+				{Type: typ, Data: map[string]any{"Val": "split-2"}},
+			}, nil
+		})
+	store, err := NewEventStore(ctx, db, json.NewEventSerializer("", func(cfg *json.EventSerializerConfig) {
+		cfg.Upcasters = upcasters
+	}))
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	stm, err := store.LoadStream(ctx, streamID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 3, len(stm.Unwrap()); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if err := stm.Validate(); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := "split-2", stm.Unwrap()[1].Event().(*eventtest.Event1).Val; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestDialect_Rebind(t *testing.T) {
+	query := `SELECT data FROM events WHERE id = ? AND at >= ?`
+
+	if want, got := query, SQLite.rebind(query); want != got {
+		t.Fatalf("expect %s, %s be equals", want, got)
+	}
+	if want, got := `SELECT data FROM events WHERE id = $1 AND at >= $2`, Postgres.rebind(query); want != got {
+		t.Fatalf("expect %s, %s be equals", want, got)
+	}
+}
@@ -4,7 +4,7 @@ go 1.25.0
 
 require (
 	github.com/ln80/avro/v2 v2.33.1
-	github.com/ln80/event-store v0.6.0
+	github.com/ln80/event-store v0.7.0
 )
 
 require (
@@ -34,3 +34,8 @@ require (
 	golang.org/x/sys v0.45.0 // indirect
 	golang.org/x/tools v0.45.0 // indirect
 )
+
+// Requires the root module release shipped along with this module: tag the
+// root module (vX.Y.Z) before tool/vX.Y.Z. The replace directive only applies
+// when developing in this repository.
+replace github.com/ln80/event-store => ../